@@ -4,9 +4,14 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
 	textm "github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
-	"strings"
 )
 
 // A NodeType indicates what type a node belongs to.
@@ -229,10 +234,12 @@ func (n *BaseNode) RemoveChild(self, v Node) {
 
 // RemoveChildren implements Node.RemoveChildren .
 func (n *BaseNode) RemoveChildren(self Node) {
-	for c := n.firstChild; c != nil; c = c.NextSibling() {
+	for c := n.firstChild; c != nil; {
+		next := c.NextSibling()
 		c.SetParent(nil)
 		c.SetPreviousSibling(nil)
 		c.SetNextSibling(nil)
+		c = next
 	}
 	n.firstChild = nil
 	n.lastChild = nil
@@ -326,27 +333,35 @@ func (n *BaseNode) Text(source []byte) []byte {
 }
 
 // SetAttribute implements Node.SetAttribute.
+// The name '#' is an alias for 'id' and the name '.' is an alias for 'class'.
+// Classes set by '.' are joined with a space.
 func (n *BaseNode) SetAttribute(name, value []byte) {
+	join := false
+	if len(name) == 1 {
+		if name[0] == '#' {
+			name = attrNameID
+		} else if name[0] == '.' {
+			name = attrNameClass
+			join = true
+		}
+	}
 	if n.attributes == nil {
 		n.attributes = make([]Attribute, 0, 10)
 	} else {
 		for i, a := range n.attributes {
 			if bytes.Equal(a.Name, name) {
+				if join && len(a.Value) != 0 {
+					v := make([]byte, 0, len(a.Value)+len(value)+1)
+					v = append(v, a.Value...)
+					v = append(v, ' ')
+					value = append(v, value...)
+				}
 				n.attributes[i].Name = name
 				n.attributes[i].Value = value
 				return
 			}
 		}
 	}
-	if len(name) == 1 {
-		if name[0] == '#' {
-			n.attributes = append(n.attributes, Attribute{attrNameID, value})
-			return
-		} else if name[0] == '.' {
-			n.attributes = append(n.attributes, Attribute{attrNameClass, value})
-			return
-		}
-	}
 	n.attributes = append(n.attributes, Attribute{name, value})
 	return
 }
@@ -410,6 +425,102 @@ func DumpHelper(v Node, source []byte, level int, kv map[string]string, cb func(
 	fmt.Printf("%s}\n", indent)
 }
 
+// Dump prints an indented tree of the given node and its descendants to
+// the standard output. Unlike Node.Dump, Dump prints key properties of
+// well-known nodes(like heading levels and link destinations) and attributes
+// in a stable order, so it works well for debugging extensions.
+func Dump(n Node, source []byte, level int) {
+	dumpTo(os.Stdout, n, source, level)
+}
+
+// DumpTo is same as Dump, but writes a tree to the given writer.
+func DumpTo(w io.Writer, n Node, source []byte) {
+	dumpTo(w, n, source, 0)
+}
+
+func dumpTo(w io.Writer, n Node, source []byte, level int) {
+	indent := strings.Repeat("    ", level)
+	indent2 := strings.Repeat("    ", level+1)
+	fmt.Fprintf(w, "%s%s {\n", indent, n.Kind().String())
+	if n.Type() == TypeBlock {
+		var buf bytes.Buffer
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			buf.Write(line.Value(source))
+		}
+		fmt.Fprintf(w, "%sRawText: %s\n", indent2, strconv.Quote(buf.String()))
+		fmt.Fprintf(w, "%sHasBlankPreviousLines: %v\n", indent2, n.HasBlankPreviousLines())
+	}
+	kv := dumpProperties(n, source)
+	names := make([]string, 0, len(kv))
+	for name := range kv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s: %s\n", indent2, name, kv[name])
+	}
+	if attrs := n.Attributes(); len(attrs) != 0 {
+		fmt.Fprintf(w, "%sAttributes {\n", indent2)
+		for _, attr := range attrs {
+			fmt.Fprintf(w, "%s    %s: %s\n", indent2, attr.Name, strconv.Quote(string(attr.Value)))
+		}
+		fmt.Fprintf(w, "%s}\n", indent2)
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		dumpTo(w, c, source, level+1)
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+func dumpProperties(n Node, source []byte) map[string]string {
+	kv := map[string]string{}
+	switch v := n.(type) {
+	case *Heading:
+		kv["Level"] = strconv.Itoa(v.Level)
+	case *FencedCodeBlock:
+		if v.Info != nil {
+			kv["Info"] = strconv.Quote(string(v.Info.Text(source)))
+		}
+	case *List:
+		kv["Ordered"] = strconv.FormatBool(v.IsOrdered())
+		kv["Marker"] = strconv.Quote(string(v.Marker))
+		kv["Tight"] = strconv.FormatBool(v.IsTight)
+		if v.IsOrdered() {
+			kv["Start"] = strconv.Itoa(v.Start)
+		}
+	case *ListItem:
+		kv["Offset"] = strconv.Itoa(v.Offset)
+	case *Text:
+		kv["Value"] = strconv.Quote(string(v.Text(source)))
+		if v.SoftLineBreak() {
+			kv["SoftLineBreak"] = "true"
+		}
+		if v.HardLineBreak() {
+			kv["HardLineBreak"] = "true"
+		}
+	case *Emphasis:
+		kv["Level"] = strconv.Itoa(v.Level)
+	case *Link:
+		kv["Destination"] = strconv.Quote(string(v.Destination))
+		kv["Title"] = strconv.Quote(string(v.Title))
+	case *Image:
+		kv["Destination"] = strconv.Quote(string(v.Destination))
+		kv["Title"] = strconv.Quote(string(v.Title))
+	case *AutoLink:
+		kv["URL"] = strconv.Quote(string(v.URL(source)))
+	case *RawHTML:
+		var buf bytes.Buffer
+		for i := 0; i < v.Segments.Len(); i++ {
+			segment := v.Segments.At(i)
+			buf.Write(segment.Value(source))
+		}
+		kv["RawText"] = strconv.Quote(buf.String())
+	}
+	return kv
+}
+
 // WalkStatus represents a current status of the Walk function.
 type WalkStatus int
 
@@ -432,21 +543,152 @@ const (
 type Walker func(n Node, entering bool) (WalkStatus, error)
 
 // Walk walks a AST tree by the depth first search algorighm.
+// Walk stops walking the whole tree when the walker returns WalkStop and
+// skips children of the current node when the walker returns
+// WalkSkipChildren on entering.
+//
+// Walkers may modify the current node like its attributes and contents.
+// Walkers should not add or remove nodes other than children of the
+// current node while walking, because Walk follows links between siblings.
+// Collect nodes and modify the tree after Walk returns in such cases.
+// Walk does not lock the tree, so a tree that is being walked must not be
+// modified by other goroutines. Walking a tree concurrently is safe only if
+// all walkers are read-only.
 func Walk(n Node, walker Walker) error {
-	status, err := walker(n, true)
+	_, err := walkHelper(n, func(n Node, entering bool, ctx WalkContext) (WalkStatus, error) {
+		return walker(n, entering)
+	}, WalkContext{})
+	return err
+}
+
+// A WalkContext struct holds states of walking for ContextWalker.
+type WalkContext struct {
+	// Depth is a depth of the current node. The node passed to
+	// WalkWithContext has a depth 0.
+	Depth int
+}
+
+// ContextWalker is a function that will be called when WalkWithContext
+// find a new node. ContextWalker is a Walker that receives states of
+// walking.
+type ContextWalker func(n Node, entering bool, ctx WalkContext) (WalkStatus, error)
+
+// WalkWithContext is same as Walk except that the walker receives states
+// of walking like depths of nodes.
+func WalkWithContext(n Node, walker ContextWalker) error {
+	_, err := walkHelper(n, walker, WalkContext{})
+	return err
+}
+
+func walkHelper(n Node, walker ContextWalker, ctx WalkContext) (WalkStatus, error) {
+	status, err := walker(n, true, ctx)
 	if err != nil || status == WalkStop {
-		return err
+		return WalkStop, err
 	}
 	if status != WalkSkipChildren {
+		child := WalkContext{Depth: ctx.Depth + 1}
 		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
-			if err := Walk(c, walker); err != nil {
-				return err
+			if status, err := walkHelper(c, walker, child); err != nil || status == WalkStop {
+				return WalkStop, err
 			}
 		}
 	}
-	status, err = walker(n, false)
+	status, err = walker(n, false, ctx)
 	if err != nil || status == WalkStop {
-		return err
+		return WalkStop, err
+	}
+	return WalkContinue, nil
+}
+
+// A Positioner interface is implemented by nodes that hold positions where
+// they start in a source. BaseBlock implements this interface, so block
+// nodes that embed BaseBlock have positions. This is not a part of the Node
+// interface so that existing Node implementations keep working.
+type Positioner interface {
+	// Pos returns a byte offset where this node starts in a source,
+	// including markers like '#' and '>', or -1 if it is unknown.
+	Pos() int
+
+	// SetPos sets a byte offset where this node starts in a source.
+	// Block parsers set it when the node is opened.
+	SetPos(v int)
+}
+
+// StartOffset returns a byte offset of the first source text of the given node.
+// Block nodes start at the position where they are opened(see Positioner),
+// so that markers like '#' and '>' are included. Block nodes that are not
+// opened by block parsers start at their first line of contents.
+// Nodes that do not have any source text return -1.
+func StartOffset(n Node) int {
+	if p, ok := n.(Positioner); ok && p.Pos() > -1 {
+		return p.Pos()
+	}
+	switch v := n.(type) {
+	case *Text:
+		return v.Segment.Start
+	case *AutoLink:
+		return v.value.Segment.Start
+	case *RawHTML:
+		if v.Segments.Len() != 0 {
+			return v.Segments.At(0).Start
+		}
+	}
+	if n.Type() == TypeBlock && n.Lines().Len() != 0 {
+		return n.Lines().At(0).Start
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if offset := StartOffset(c); offset > -1 {
+			return offset
+		}
+	}
+	return -1
+}
+
+// Position returns (a line and a column where the given node starts, true)
+// if the node has source texts, otherwise (zero value, false).
+// See StartOffset for details.
+// Use text.LineTable with StartOffset if you need positions of many nodes.
+func Position(n Node, source []byte) (textm.Position, bool) {
+	offset := StartOffset(n)
+	if offset < 0 {
+		return textm.Position{}, false
 	}
-	return nil
+	return textm.PositionOf(source, offset), true
+}
+
+// PlainText returns a text of the given node without inline markups, like
+// 'bold and link' for '**bold** and [link](/url)'. This is useful for
+// generating ids and tables of contents from headings.
+// Unlike Node.Text, line breaks are converted into spaces, labels of
+// autolinks are included, raw HTML is excluded and escapes and character
+// references are resolved.
+func PlainText(n Node, source []byte) []byte {
+	var buf bytes.Buffer
+	_ = Walk(n, func(c Node, entering bool) (WalkStatus, error) {
+		if !entering {
+			return WalkContinue, nil
+		}
+		switch v := c.(type) {
+		case *RawHTML:
+			return WalkSkipChildren, nil
+		case *AutoLink:
+			buf.Write(v.Label(source))
+			return WalkSkipChildren, nil
+		case *Text:
+			value := v.Segment.Value(source)
+			if !v.IsRaw() {
+				value = util.UnescapePunctuations(value)
+				value = util.ResolveNumericReferences(value)
+				value = util.ResolveEntityNames(value)
+			}
+			buf.Write(value)
+			if v.SoftLineBreak() || v.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *String:
+			buf.Write(v.Value)
+		}
+		return WalkContinue, nil
+	})
+	return bytes.TrimRight(buf.Bytes(), " ")
 }
@@ -3,6 +3,7 @@ package ast
 import (
 	"fmt"
 	textm "github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"strings"
 )
 
@@ -11,6 +12,7 @@ type BaseBlock struct {
 	BaseNode
 	blankPreviousLines bool
 	lines              *textm.Segments
+	pos                int // an offset + 1, so zero values mean unknown
 }
 
 // Type implements Node.Type
@@ -33,6 +35,16 @@ func (b *BaseBlock) SetBlankPreviousLines(v bool) {
 	b.blankPreviousLines = v
 }
 
+// Pos implements Positioner.Pos.
+func (b *BaseBlock) Pos() int {
+	return b.pos - 1
+}
+
+// SetPos implements Positioner.SetPos.
+func (b *BaseBlock) SetPos(v int) {
+	b.pos = v + 1
+}
+
 // Lines implements Node.Lines
 func (b *BaseBlock) Lines() *textm.Segments {
 	if b.lines == nil {
@@ -226,25 +238,46 @@ type FencedCodeBlock struct {
 	BaseBlock
 	// Info returns a info text of this fenced code block.
 	Info *Text
-
-	language []byte
 }
 
 // Language returns an language in an info string.
 // Language returns nil if this node does not have an info string.
 func (n *FencedCodeBlock) Language(source []byte) []byte {
-	if n.language == nil && n.Info != nil {
-		segment := n.Info.Segment
-		info := segment.Value(source)
-		i := 0
-		for ; i < len(info); i++ {
-			if info[i] == ' ' {
-				break
-			}
+	language, _ := n.parseInfo(source)
+	return language
+}
+
+// Meta returns a text that follows a language in an info string like
+// '{highlight:1-3}' in '```go {highlight:1-3}'.
+// Meta returns nil if this node does not have such text.
+func (n *FencedCodeBlock) Meta(source []byte) []byte {
+	_, meta := n.parseInfo(source)
+	return meta
+}
+
+// parseInfo returns a language and a meta of the info string. parseInfo
+// does not cache results in this node because the same node may be
+// rendered concurrently.
+func (n *FencedCodeBlock) parseInfo(source []byte) ([]byte, []byte) {
+	if n.Info == nil {
+		return nil, nil
+	}
+	segment := n.Info.Segment
+	info := util.TrimLeftSpace(segment.Value(source))
+	i := 0
+	for ; i < len(info); i++ {
+		if util.IsSpace(info[i]) {
+			break
 		}
-		n.language = info[:i]
 	}
-	return n.language
+	if i == 0 {
+		return nil, nil
+	}
+	meta := util.TrimRightSpace(util.TrimLeftSpace(info[i:]))
+	if len(meta) == 0 {
+		meta = nil
+	}
+	return info[:i], meta
 }
 
 // IsRaw implements Node.IsRaw.
@@ -307,6 +340,8 @@ type List struct {
 	BaseBlock
 
 	// Marker is a markar character like '-', '+', ')' and '.'.
+	// Ordered lists have a delimiter of the source, '.' for "1." and
+	// ')' for "1)", as their Marker.
 	Marker byte
 
 	// IsTight is a true if this list is a 'tight' list.
@@ -73,7 +73,7 @@ func (n *Text) SetSoftLineBreak(v bool) {
 	if v {
 		n.flags |= textSoftLineBreak
 	} else {
-		n.flags = n.flags &^ textHardLineBreak
+		n.flags = n.flags &^ textSoftLineBreak
 	}
 }
 
@@ -114,7 +114,7 @@ func (n *Text) Merge(node Node, source []byte) bool {
 	if !ok {
 		return false
 	}
-	if n.Segment.Stop != t.Segment.Start || t.Segment.Padding != 0 || source[n.Segment.Stop-1] == '\n' || t.IsRaw() != n.IsRaw() {
+	if n.Segment.Stop != t.Segment.Start || t.Segment.Padding != 0 || source[n.Segment.Stop-1] == '\n' || source[n.Segment.Stop-1] == '\r' || t.IsRaw() != n.IsRaw() {
 		return false
 	}
 	n.Segment.Stop = t.Segment.Stop
@@ -192,6 +192,46 @@ func MergeOrReplaceTextSegment(parent Node, n Node, s textm.Segment) {
 	}
 }
 
+// A String struct is a textual content that does not exist in the source
+// like an ellipsis added by Truncate.
+type String struct {
+	BaseInline
+
+	// Value is a text of this node. Value is rendered with HTML escaping.
+	Value []byte
+}
+
+// Inline implements Inline.Inline.
+func (n *String) Inline() {
+}
+
+// Text implements Node.Text.
+func (n *String) Text(source []byte) []byte {
+	return n.Value
+}
+
+// Dump implements Node.Dump.
+func (n *String) Dump(source []byte, level int) {
+	DumpHelper(n, source, level, map[string]string{
+		"Value": string(n.Value),
+	}, nil)
+}
+
+// KindString is a NodeKind of the String node.
+var KindString = NewNodeKind("String")
+
+// Kind implements Node.Kind.
+func (n *String) Kind() NodeKind {
+	return KindString
+}
+
+// NewString returns a new String node.
+func NewString(v []byte) *String {
+	return &String{
+		Value: v,
+	}
+}
+
 // A CodeSpan struct represents a code span of Markdown text.
 type CodeSpan struct {
 	BaseInline
@@ -0,0 +1,177 @@
+package ast
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// DefaultWordsPerMinute is a reading speed used in ReadingTime when
+// the given speed is not positive.
+const DefaultWordsPerMinute = 200
+
+// CountWords returns a number of words in the given AST.
+// Only texts in Text nodes are counted, so code blocks, code spans and
+// raw HTML are ignored.
+// Each Han, Hiragana and Katakana character is counted as a word because
+// these scripts do not separate words with spaces.
+func CountWords(doc Node, source []byte) int {
+	count := 0
+	inWord := false
+	_ = Walk(doc, func(n Node, entering bool) (WalkStatus, error) {
+		if n.Type() == TypeBlock {
+			inWord = false
+			return WalkContinue, nil
+		}
+		if !entering {
+			return WalkContinue, nil
+		}
+		switch v := n.(type) {
+		case *CodeSpan, *RawHTML:
+			inWord = false
+			return WalkSkipChildren, nil
+		case *Text:
+			count += countWords(v.Segment.Value(source), &inWord)
+			if v.SoftLineBreak() || v.HardLineBreak() {
+				inWord = false
+			}
+		}
+		return WalkContinue, nil
+	})
+	return count
+}
+
+func countWords(value []byte, inWord *bool) int {
+	count := 0
+	for len(value) != 0 {
+		r, size := utf8.DecodeRune(value)
+		value = value[size:]
+		if scanWordRune(r, inWord) {
+			count++
+		}
+	}
+	return count
+}
+
+// scanWordRune updates inWord with the given rune and returns true if the
+// rune starts a new word.
+func scanWordRune(r rune, inWord *bool) bool {
+	switch {
+	case isCJK(r):
+		*inWord = false
+		return true
+	case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+		start := !*inWord
+		*inWord = true
+		return start
+	case r == '\'' || r == '-' || r == '’':
+		// keeps words like "don't" and "well-known" together.
+		return false
+	}
+	*inWord = false
+	return false
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// ReadingTime estimates a time to read the given AST at the given
+// words per minute. If wordsPerMinute is not positive,
+// DefaultWordsPerMinute is used.
+func ReadingTime(doc Node, source []byte, wordsPerMinute int) time.Duration {
+	if wordsPerMinute <= 0 {
+		wordsPerMinute = DefaultWordsPerMinute
+	}
+	words := CountWords(doc, source)
+	return time.Duration(words) * time.Minute / time.Duration(wordsPerMinute)
+}
+
+// ImagesWithoutAlt returns images that have empty or blank alt texts like
+// '![](image.png)' in the given AST. This is useful for finding images that
+// are not accessible.
+func ImagesWithoutAlt(doc Node, source []byte) []*Image {
+	images := []*Image{}
+	_ = Walk(doc, func(n Node, entering bool) (WalkStatus, error) {
+		if image, ok := n.(*Image); ok && entering {
+			if util.IsBlank(image.Text(source)) {
+				images = append(images, image)
+			}
+			return WalkSkipChildren, nil
+		}
+		return WalkContinue, nil
+	})
+	return images
+}
+
+// Truncate removes contents of the given AST after the given number of
+// words and appends the given ellipsis like '…' as a String node to the
+// last remaining text. Words are counted like CountWords. Elements that
+// contain the last word like emphases and links are kept, so the AST
+// is rendered as a valid HTML.
+// Truncate returns true if the AST has been truncated.
+func Truncate(doc Node, source []byte, words int, ellipsis []byte) bool {
+	if words < 1 {
+		return false
+	}
+	count := 0
+	inWord := false
+	var last *Text
+	lastStop := 0
+	truncated := false
+	_ = Walk(doc, func(n Node, entering bool) (WalkStatus, error) {
+		if n.Type() == TypeBlock {
+			inWord = false
+			return WalkContinue, nil
+		}
+		if !entering {
+			return WalkContinue, nil
+		}
+		switch v := n.(type) {
+		case *CodeSpan, *RawHTML:
+			inWord = false
+			return WalkSkipChildren, nil
+		case *Text:
+			value := v.Segment.Value(source)
+			for i := 0; i < len(value); {
+				r, size := utf8.DecodeRune(value[i:])
+				if scanWordRune(r, &inWord) {
+					count++
+					if count > words {
+						truncated = true
+						return WalkStop, nil
+					}
+				}
+				i += size
+				if inWord || isCJK(r) {
+					last = v
+					lastStop = v.Segment.Start + i
+				}
+			}
+			if v.SoftLineBreak() || v.HardLineBreak() {
+				inWord = false
+			}
+		}
+		return WalkContinue, nil
+	})
+	if !truncated || last == nil {
+		return false
+	}
+	segment := last.Segment
+	last.Segment = segment.WithStop(lastStop)
+	last.SetSoftLineBreak(false)
+	last.SetHardLineBreak(false)
+	var n Node = last
+	for ; n != doc && n.Parent() != nil; n = n.Parent() {
+		parent := n.Parent()
+		for c := n.NextSibling(); c != nil; {
+			next := c.NextSibling()
+			parent.RemoveChild(parent, c)
+			c = next
+		}
+	}
+	last.Parent().InsertAfter(last.Parent(), last, NewString(ellipsis))
+	return true
+}
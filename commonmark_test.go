@@ -1,11 +1,15 @@
 package goldmark
 
 import (
+	"bytes"
 	"encoding/json"
 	"io/ioutil"
 	"testing"
 
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	mdrenderer "github.com/yuin/goldmark/renderer/markdown"
+	"github.com/yuin/goldmark/util"
 )
 
 type commonmarkSpecTestCase struct {
@@ -17,7 +21,7 @@ type commonmarkSpecTestCase struct {
 	Section   string `json:"section"`
 }
 
-func TestSpec(t *testing.T) {
+func readSpecTestCases() []commonmarkSpecTestCase {
 	bs, err := ioutil.ReadFile("_test/spec.json")
 	if err != nil {
 		panic(err)
@@ -26,8 +30,12 @@ func TestSpec(t *testing.T) {
 	if err := json.Unmarshal(bs, &testCases); err != nil {
 		panic(err)
 	}
+	return testCases
+}
+
+func TestSpec(t *testing.T) {
 	cases := []MarkdownTestCase{}
-	for _, c := range testCases {
+	for _, c := range readSpecTestCases() {
 		cases = append(cases, MarkdownTestCase{
 			No:       c.Example,
 			Markdown: c.Markdown,
@@ -40,3 +48,100 @@ func TestSpec(t *testing.T) {
 	))
 	DoTestCases(markdown, cases, t)
 }
+
+func TestExtras(t *testing.T) {
+	markdown := New(WithRendererOptions(
+		html.WithXHTML(),
+		html.WithUnsafe(),
+	))
+	DoTestCaseFile(markdown, "_test/extra.txt", t)
+}
+
+func TestLists(t *testing.T) {
+	markdown := New()
+	DoTestCaseFile(markdown, "_test/lists.txt", t)
+}
+
+func TestIndentedCodeBlocks(t *testing.T) {
+	cases := []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "    a\tb\n\tc\n  \td\n     e  \n\n    \n      \n    f\n",
+			Expected: "<pre><code>a\tb\nc\nd\n e  \n\n\n  \nf\n</code></pre>",
+		},
+		{
+			No:       2,
+			Markdown: "\t\tx\n  \t y\n",
+			Expected: "<pre><code>\tx\n y\n</code></pre>",
+		},
+		{
+			No:       3,
+			Markdown: "    code\n      \n    \n\nparagraph",
+			Expected: "<pre><code>code\n</code></pre>\n<p>paragraph</p>",
+		},
+		{
+			No:       4,
+			Markdown: "- item\n\n\t\tcode\n\n\t\t\tmore\n",
+			Expected: "<ul>\n<li>\n<p>item</p>\n<pre><code>  code\n\n  \tmore\n</code></pre>\n</li>\n</ul>",
+		},
+	}
+	markdown := New()
+	for _, c := range cases {
+		var out bytes.Buffer
+		if err := markdown.Convert([]byte(c.Markdown), &out); err != nil {
+			t.Fatal(err)
+		}
+		// DoTestCases trims spaces, so compare whole outputs.
+		if out.String() != c.Expected+"\n" {
+			t.Errorf("%d: expected %q, but got %q", c.No, c.Expected+"\n", out.String())
+		}
+	}
+
+	// code contents must survive a round trip through the markdown renderer.
+	md := New(WithRenderer(renderer.NewRenderer(
+		renderer.WithNodeRenderers(util.Prioritized(mdrenderer.NewRenderer(), 1000)),
+	)))
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := md.Convert([]byte(c.Markdown), &buf); err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := markdown.Convert(buf.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != c.Expected+"\n" {
+			t.Errorf("%d: expected %q after a round trip, but got %q", c.No, c.Expected+"\n", out.String())
+		}
+	}
+}
+
+func TestSpecMarkdownRoundTrip(t *testing.T) {
+	skip := map[int]bool{
+		49:  true, // empty ATX headings at the end of lines
+		165: true, // multi-line titles can not be written in inline links
+	}
+	md := New(WithRenderer(renderer.NewRenderer(
+		renderer.WithNodeRenderers(util.Prioritized(mdrenderer.NewRenderer(), 1000)),
+	)))
+	cases := []MarkdownTestCase{}
+	for _, c := range readSpecTestCases() {
+		if skip[c.Example] {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := md.Convert([]byte(c.Markdown), &buf); err != nil {
+			t.Fatal(err)
+		}
+		cases = append(cases, MarkdownTestCase{
+			No:       c.Example,
+			Markdown: buf.String(),
+			Expected: c.HTML,
+		})
+	}
+	markdown := New(WithRendererOptions(
+		html.WithXHTML(),
+		html.WithUnsafe(),
+	))
+	DoTestCases(markdown, cases, t)
+}
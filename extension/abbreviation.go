@@ -0,0 +1,313 @@
+package extension
+
+import (
+	"bytes"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var abbreviationListKey = parser.NewContextKey()
+
+type abbreviationDefinition struct {
+	label     []byte
+	expansion []byte
+}
+
+// An AbbreviationConfig struct is a data structure that holds configuration
+// of the Abbreviation extension.
+type AbbreviationConfig struct {
+	// IgnoreCase is true if abbreviations should match texts regardless of
+	// their cases like 'html' for '*[HTML]: ...'.
+	IgnoreCase bool
+}
+
+// NewAbbreviationConfig returns a new AbbreviationConfig with defaults.
+func NewAbbreviationConfig() AbbreviationConfig {
+	return AbbreviationConfig{
+		IgnoreCase: false,
+	}
+}
+
+// An AbbreviationOption interface sets options for the Abbreviation
+// extension.
+type AbbreviationOption interface {
+	SetAbbreviationOption(*AbbreviationConfig)
+}
+
+type withAbbreviationIgnoreCase struct {
+}
+
+func (o *withAbbreviationIgnoreCase) SetAbbreviationOption(c *AbbreviationConfig) {
+	c.IgnoreCase = true
+}
+
+// WithAbbreviationIgnoreCase is a functional option that makes
+// abbreviations match texts regardless of their cases.
+func WithAbbreviationIgnoreCase() AbbreviationOption {
+	return &withAbbreviationIgnoreCase{}
+}
+
+type abbreviationParagraphTransformer struct {
+}
+
+var defaultAbbreviationParagraphTransformer = &abbreviationParagraphTransformer{}
+
+// NewAbbreviationParagraphTransformer returns a new ParagraphTransformer
+// that extracts abbreviation definitions like
+// '*[HTML]: HyperText Markup Language' from the beginning of paragraphs.
+func NewAbbreviationParagraphTransformer() parser.ParagraphTransformer {
+	return defaultAbbreviationParagraphTransformer
+}
+
+func (b *abbreviationParagraphTransformer) Transform(node *gast.Paragraph, reader text.Reader, pc parser.Context) {
+	lines := node.Lines()
+	i := 0
+	for ; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		definition := parseAbbreviationDefinition(segment.Value(reader.Source()))
+		if definition == nil {
+			break
+		}
+		var list []*abbreviationDefinition
+		if v := pc.Get(abbreviationListKey); v != nil {
+			list = v.([]*abbreviationDefinition)
+		}
+		pc.Set(abbreviationListKey, append(list, definition))
+	}
+	if i == 0 {
+		return
+	}
+	if i == lines.Len() {
+		node.Parent().RemoveChild(node.Parent(), node)
+		return
+	}
+	lines.SetSliced(i, lines.Len())
+}
+
+// parseAbbreviationDefinition parses a line like '*[ABBR]: expansion'.
+func parseAbbreviationDefinition(line []byte) *abbreviationDefinition {
+	line = util.TrimRightSpace(line)
+	if len(line) < 5 || line[0] != '*' || line[1] != '[' {
+		return nil
+	}
+	closes := bytes.IndexByte(line, ']')
+	if closes < 0 || closes+1 >= len(line) || line[closes+1] != ':' {
+		return nil
+	}
+	label := util.TrimRightSpace(util.TrimLeftSpace(line[2:closes]))
+	if len(label) == 0 {
+		return nil
+	}
+	return &abbreviationDefinition{
+		label:     label,
+		expansion: util.TrimLeftSpace(line[closes+2:]),
+	}
+}
+
+type abbreviationASTTransformer struct {
+	AbbreviationConfig
+}
+
+// NewAbbreviationASTTransformer returns a new parser.ASTTransformer that
+// converts words defined as abbreviations into Abbreviation nodes.
+// Texts in code spans, links and raw HTMLs are not converted.
+func NewAbbreviationASTTransformer(opts ...AbbreviationOption) parser.ASTTransformer {
+	t := &abbreviationASTTransformer{
+		AbbreviationConfig: NewAbbreviationConfig(),
+	}
+	for _, o := range opts {
+		o.SetAbbreviationOption(&t.AbbreviationConfig)
+	}
+	return t
+}
+
+func (a *abbreviationASTTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
+	v := pc.Get(abbreviationListKey)
+	if v == nil {
+		return
+	}
+	pc.Set(abbreviationListKey, nil)
+	// the first definition takes precedence like link reference definitions.
+	list := []*abbreviationDefinition{}
+	for _, d := range v.([]*abbreviationDefinition) {
+		defined := false
+		for _, e := range list {
+			if a.equals(d.label, e.label) {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			list = append(list, d)
+		}
+	}
+	// longer abbreviations take precedence.
+	sort.SliceStable(list, func(i, j int) bool {
+		return len(list[i].label) > len(list[j].label)
+	})
+
+	texts := []*gast.Text{}
+	gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering {
+			return gast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case gast.KindCodeSpan, gast.KindLink, gast.KindAutoLink, gast.KindImage,
+			gast.KindRawHTML:
+			return gast.WalkSkipChildren, nil
+		case gast.KindText:
+			if t := n.(*gast.Text); !t.IsRaw() {
+				texts = append(texts, t)
+			}
+		}
+		return gast.WalkContinue, nil
+	})
+	source := reader.Source()
+	for _, t := range texts {
+		a.transformText(t, list, source)
+	}
+}
+
+func (a *abbreviationASTTransformer) equals(x, y []byte) bool {
+	if a.IgnoreCase {
+		return bytes.EqualFold(x, y)
+	}
+	return bytes.Equal(x, y)
+}
+
+func (a *abbreviationASTTransformer) transformText(t *gast.Text, list []*abbreviationDefinition, source []byte) {
+	parent := t.Parent()
+	value := t.Segment.Value(source)
+	start := 0
+	for i := 0; i < len(value); {
+		if i != 0 && isAbbreviationWordRune(lastRune(value[:i])) {
+			_, size := utf8.DecodeRune(value[i:])
+			i += size
+			continue
+		}
+		var matched *abbreviationDefinition
+		for _, d := range list {
+			l := len(d.label)
+			if i+l > len(value) || !a.equals(value[i:i+l], d.label) {
+				continue
+			}
+			if i+l < len(value) {
+				if r, _ := utf8.DecodeRune(value[i+l:]); isAbbreviationWordRune(r) {
+					continue
+				}
+			}
+			matched = d
+			break
+		}
+		if matched == nil {
+			_, size := utf8.DecodeRune(value[i:])
+			i += size
+			continue
+		}
+		l := len(matched.label)
+		if i > start {
+			before := gast.NewTextSegment(text.NewSegment(t.Segment.Start+start, t.Segment.Start+i))
+			parent.InsertBefore(parent, t, before)
+		}
+		abbr := ast.NewAbbreviation(matched.expansion)
+		abbr.AppendChild(abbr, gast.NewTextSegment(text.NewSegment(t.Segment.Start+i, t.Segment.Start+i+l)))
+		parent.InsertBefore(parent, t, abbr)
+		i += l
+		start = i
+	}
+	if start == 0 {
+		return
+	}
+	t.Segment = t.Segment.WithStart(t.Segment.Start + start)
+	if t.Segment.Len() == 0 && !t.SoftLineBreak() && !t.HardLineBreak() {
+		parent.RemoveChild(parent, t)
+	}
+}
+
+func lastRune(b []byte) rune {
+	r, _ := utf8.DecodeLastRune(b)
+	return r
+}
+
+func isAbbreviationWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// AbbreviationHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders Abbreviation nodes.
+type AbbreviationHTMLRenderer struct {
+	html.Config
+}
+
+// NewAbbreviationHTMLRenderer returns a new AbbreviationHTMLRenderer.
+func NewAbbreviationHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &AbbreviationHTMLRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *AbbreviationHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindAbbreviation, r.renderAbbreviation)
+}
+
+func (r *AbbreviationHTMLRenderer) renderAbbreviation(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	n := node.(*ast.Abbreviation)
+	if entering {
+		w.WriteString("<abbr")
+		if len(n.Expansion) != 0 {
+			w.WriteString(` title="`)
+			r.Writer.Write(w, n.Expansion)
+			w.WriteByte('"')
+		}
+		html.RenderAttributes(w, n)
+		w.WriteByte('>')
+	} else {
+		w.WriteString("</abbr>")
+	}
+	return gast.WalkContinue, nil
+}
+
+type abbreviation struct {
+	options []AbbreviationOption
+}
+
+// Abbreviation is an extension that allow you to use abbreviations like
+// PHP Markdown Extra.
+var Abbreviation = &abbreviation{}
+
+// NewAbbreviation returns a new Extender that allow you to use
+// abbreviations configured by the given options.
+func NewAbbreviation(opts ...AbbreviationOption) goldmark.Extender {
+	return &abbreviation{
+		options: opts,
+	}
+}
+
+func (e *abbreviation) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithParagraphTransformers(
+			util.Prioritized(NewAbbreviationParagraphTransformer(), 150),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(NewAbbreviationASTTransformer(e.options...), 900),
+		),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewAbbreviationHTMLRenderer(), 500),
+	))
+}
@@ -0,0 +1,33 @@
+package extension
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestAbbreviation(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Abbreviation,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/abbreviation.txt", t)
+}
+
+func TestAbbreviationIgnoreCase(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewAbbreviation(
+				WithAbbreviationIgnoreCase(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "*[HTML]: HyperText Markup Language\n\nhtml and Html",
+			Expected: "<p><abbr title=\"HyperText Markup Language\">html</abbr> and <abbr title=\"HyperText Markup Language\">Html</abbr></p>",
+		},
+	}, t)
+}
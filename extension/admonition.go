@@ -0,0 +1,179 @@
+package extension
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var admonitionListKey = parser.NewContextKey()
+
+// AdmonitionTypes is a list of admonition types that are recognized by the
+// Admonition extension.
+var AdmonitionTypes = [][]byte{
+	[]byte("note"),
+	[]byte("tip"),
+	[]byte("important"),
+	[]byte("warning"),
+	[]byte("caution"),
+}
+
+type admonitionMarker struct {
+	blockquote     gast.Node
+	admonitionType []byte
+}
+
+type admonitionParagraphTransformer struct {
+}
+
+var defaultAdmonitionParagraphTransformer = &admonitionParagraphTransformer{}
+
+// NewAdmonitionParagraphTransformer returns a new ParagraphTransformer that
+// finds markers like '[!NOTE]' at the beginning of blockquotes.
+func NewAdmonitionParagraphTransformer() parser.ParagraphTransformer {
+	return defaultAdmonitionParagraphTransformer
+}
+
+func (b *admonitionParagraphTransformer) Transform(node *gast.Paragraph, reader text.Reader, pc parser.Context) {
+	parent := node.Parent()
+	if parent == nil || parent.Kind() != gast.KindBlockquote || node.PreviousSibling() != nil {
+		return
+	}
+	lines := node.Lines()
+	if lines.Len() == 0 {
+		return
+	}
+	line := lines.At(0)
+	typ := admonitionType(util.TrimRightSpace(line.Value(reader.Source())))
+	if typ == nil {
+		return
+	}
+	var markers []*admonitionMarker
+	if v := pc.Get(admonitionListKey); v != nil {
+		markers = v.([]*admonitionMarker)
+	}
+	pc.Set(admonitionListKey, append(markers, &admonitionMarker{parent, typ}))
+	if lines.Len() == 1 {
+		parent.RemoveChild(parent, node)
+		return
+	}
+	lines.SetSliced(1, lines.Len())
+	node.SetLines(lines)
+}
+
+// admonitionType returns a lower cased type of the given marker like
+// '[!NOTE]', or nil if the marker is not a known admonition.
+func admonitionType(marker []byte) []byte {
+	if len(marker) < 4 || marker[0] != '[' || marker[1] != '!' || marker[len(marker)-1] != ']' {
+		return nil
+	}
+	typ := bytes.ToLower(marker[2 : len(marker)-1])
+	for _, t := range AdmonitionTypes {
+		if bytes.Equal(t, typ) {
+			return t
+		}
+	}
+	return nil
+}
+
+type admonitionASTTransformer struct {
+}
+
+var defaultAdmonitionASTTransformer = &admonitionASTTransformer{}
+
+// NewAdmonitionASTTransformer returns a new parser.ASTTransformer that
+// replaces blockquotes that begin with markers like '[!NOTE]' with
+// Admonition nodes.
+func NewAdmonitionASTTransformer() parser.ASTTransformer {
+	return defaultAdmonitionASTTransformer
+}
+
+func (a *admonitionASTTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
+	v := pc.Get(admonitionListKey)
+	if v == nil {
+		return
+	}
+	pc.Set(admonitionListKey, nil)
+	for _, marker := range v.([]*admonitionMarker) {
+		blockquote := marker.blockquote
+		admonition := ast.NewAdmonition(marker.admonitionType)
+		if positioner, ok := blockquote.(gast.Positioner); ok {
+			admonition.SetPos(positioner.Pos())
+		}
+		for c := blockquote.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			admonition.AppendChild(admonition, c)
+			c = next
+		}
+		parent := blockquote.Parent()
+		parent.ReplaceChild(parent, blockquote, admonition)
+	}
+}
+
+// AdmonitionHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders Admonition nodes.
+type AdmonitionHTMLRenderer struct {
+	html.Config
+}
+
+// NewAdmonitionHTMLRenderer returns a new AdmonitionHTMLRenderer.
+func NewAdmonitionHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &AdmonitionHTMLRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *AdmonitionHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindAdmonition, r.renderAdmonition)
+}
+
+func (r *AdmonitionHTMLRenderer) renderAdmonition(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	n := node.(*ast.Admonition)
+	if entering {
+		w.WriteString(`<div class="admonition `)
+		w.Write(n.AdmonitionType)
+		w.WriteString("\">")
+		r.WriteNewLine(w)
+		w.WriteString(`<p class="admonition-title">`)
+		w.Write(bytes.ToUpper(n.AdmonitionType[:1]))
+		w.Write(n.AdmonitionType[1:])
+		w.WriteString("</p>")
+		r.WriteNewLine(w)
+	} else {
+		w.WriteString("</div>")
+		r.WriteNewLine(w)
+	}
+	return gast.WalkContinue, nil
+}
+
+type admonition struct {
+}
+
+// Admonition is an extension that allow you to use GitHub style alerts like
+// '> [!NOTE]'.
+var Admonition = &admonition{}
+
+func (e *admonition) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithParagraphTransformers(
+			util.Prioritized(NewAdmonitionParagraphTransformer(), 300),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(NewAdmonitionASTTransformer(), 500),
+		),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewAdmonitionHTMLRenderer(), 500),
+	))
+}
@@ -0,0 +1,15 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	"testing"
+)
+
+func TestAdmonition(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Admonition,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/admonition.txt", t)
+}
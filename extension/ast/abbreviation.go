@@ -0,0 +1,37 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// An Abbreviation struct represents an abbreviation like 'HTML' that is
+// defined by '*[HTML]: HyperText Markup Language'.
+type Abbreviation struct {
+	gast.BaseInline
+
+	// Expansion is a full text of this abbreviation.
+	Expansion []byte
+}
+
+// Dump implements Node.Dump.
+func (n *Abbreviation) Dump(source []byte, level int) {
+	m := map[string]string{
+		"Expansion": string(n.Expansion),
+	}
+	gast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindAbbreviation is a NodeKind of the Abbreviation node.
+var KindAbbreviation = gast.NewNodeKind("Abbreviation")
+
+// Kind implements Node.Kind.
+func (n *Abbreviation) Kind() gast.NodeKind {
+	return KindAbbreviation
+}
+
+// NewAbbreviation returns a new Abbreviation node.
+func NewAbbreviation(expansion []byte) *Abbreviation {
+	return &Abbreviation{
+		Expansion: expansion,
+	}
+}
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// An Admonition struct represents a GitHub style alert like '> [!NOTE]'.
+type Admonition struct {
+	gast.BaseBlock
+
+	// AdmonitionType is a lower cased type of the admonition like 'note'.
+	AdmonitionType []byte
+}
+
+// Dump implements Node.Dump.
+func (n *Admonition) Dump(source []byte, level int) {
+	m := map[string]string{
+		"AdmonitionType": string(n.AdmonitionType),
+	}
+	gast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindAdmonition is a NodeKind of the Admonition node.
+var KindAdmonition = gast.NewNodeKind("Admonition")
+
+// Kind implements Node.Kind.
+func (n *Admonition) Kind() gast.NodeKind {
+	return KindAdmonition
+}
+
+// NewAdmonition returns a new Admonition node.
+func NewAdmonition(typ []byte) *Admonition {
+	return &Admonition{
+		AdmonitionType: typ,
+	}
+}
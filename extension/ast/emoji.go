@@ -0,0 +1,41 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// An Emoji struct represents an emoji shortcode like ':smile:'.
+type Emoji struct {
+	gast.BaseInline
+
+	// ShortName is a name of the shortcode without colons.
+	ShortName []byte
+
+	// Value is an emoji the shortcode represents.
+	Value []byte
+}
+
+// Dump implements Node.Dump.
+func (n *Emoji) Dump(source []byte, level int) {
+	m := map[string]string{
+		"ShortName": string(n.ShortName),
+		"Value":     string(n.Value),
+	}
+	gast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindEmoji is a NodeKind of the Emoji node.
+var KindEmoji = gast.NewNodeKind("Emoji")
+
+// Kind implements Node.Kind.
+func (n *Emoji) Kind() gast.NodeKind {
+	return KindEmoji
+}
+
+// NewEmoji returns a new Emoji node.
+func NewEmoji(shortName, value []byte) *Emoji {
+	return &Emoji{
+		ShortName: shortName,
+		Value:     value,
+	}
+}
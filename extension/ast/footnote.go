@@ -10,12 +10,17 @@ import (
 type FootnoteLink struct {
 	gast.BaseInline
 	Index int
+
+	// RefIndex is a 1-based index of this link among the links that refer to
+	// the same footnote.
+	RefIndex int
 }
 
 // Dump implements Node.Dump.
 func (n *FootnoteLink) Dump(source []byte, level int) {
 	m := map[string]string{}
 	m["Index"] = fmt.Sprintf("%v", n.Index)
+	m["RefIndex"] = fmt.Sprintf("%v", n.RefIndex)
 	gast.DumpHelper(n, source, level, m, nil)
 }
 
@@ -30,7 +35,42 @@ func (n *FootnoteLink) Kind() gast.NodeKind {
 // NewFootnoteLink returns a new FootnoteLink node.
 func NewFootnoteLink(index int) *FootnoteLink {
 	return &FootnoteLink{
-		Index: index,
+		Index:    index,
+		RefIndex: 1,
+	}
+}
+
+// A FootnoteBackLink struct represents a link from a footnote to
+// the FootnoteLink that refers to it.
+type FootnoteBackLink struct {
+	gast.BaseInline
+	Index int
+
+	// RefIndex is a RefIndex of the FootnoteLink this link goes back to.
+	RefIndex int
+}
+
+// Dump implements Node.Dump.
+func (n *FootnoteBackLink) Dump(source []byte, level int) {
+	m := map[string]string{}
+	m["Index"] = fmt.Sprintf("%v", n.Index)
+	m["RefIndex"] = fmt.Sprintf("%v", n.RefIndex)
+	gast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindFootnoteBackLink is a NodeKind of the FootnoteBackLink node.
+var KindFootnoteBackLink = gast.NewNodeKind("FootnoteBackLink")
+
+// Kind implements Node.Kind.
+func (n *FootnoteBackLink) Kind() gast.NodeKind {
+	return KindFootnoteBackLink
+}
+
+// NewFootnoteBackLink returns a new FootnoteBackLink node.
+func NewFootnoteBackLink(index, refIndex int) *FootnoteBackLink {
+	return &FootnoteBackLink{
+		Index:    index,
+		RefIndex: refIndex,
 	}
 }
 
@@ -40,11 +80,18 @@ type Footnote struct {
 	gast.BaseBlock
 	Ref   []byte
 	Index int
+
+	// RefCount is a number of the FootnoteLinks that refer to this footnote.
+	RefCount int
 }
 
 // Dump implements Node.Dump.
 func (n *Footnote) Dump(source []byte, level int) {
-	gast.DumpHelper(n, source, level, nil, nil)
+	m := map[string]string{}
+	m["Ref"] = string(n.Ref)
+	m["Index"] = fmt.Sprintf("%v", n.Index)
+	m["RefCount"] = fmt.Sprintf("%v", n.RefCount)
+	gast.DumpHelper(n, source, level, m, nil)
 }
 
 // KindFootnote is a NodeKind of the Footnote node.
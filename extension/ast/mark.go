@@ -0,0 +1,51 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// A Mark struct represents a highlighted text like '==text=='.
+type Mark struct {
+	gast.BaseInline
+}
+
+// Dump implements Node.Dump.
+func (n *Mark) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindMark is a NodeKind of the Mark node.
+var KindMark = gast.NewNodeKind("Mark")
+
+// Kind implements Node.Kind.
+func (n *Mark) Kind() gast.NodeKind {
+	return KindMark
+}
+
+// NewMark returns a new Mark node.
+func NewMark() *Mark {
+	return &Mark{}
+}
+
+// An Insert struct represents an inserted text like '++text++'.
+type Insert struct {
+	gast.BaseInline
+}
+
+// Dump implements Node.Dump.
+func (n *Insert) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindInsert is a NodeKind of the Insert node.
+var KindInsert = gast.NewNodeKind("Insert")
+
+// Kind implements Node.Kind.
+func (n *Insert) Kind() gast.NodeKind {
+	return KindInsert
+}
+
+// NewInsert returns a new Insert node.
+func NewInsert() *Insert {
+	return &Insert{}
+}
@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"fmt"
+
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// An InlineMath struct represents an inline math like '$E=mc^2$'.
+// InlineMath has a Text node that contains the raw TeX as a child.
+type InlineMath struct {
+	gast.BaseInline
+
+	// Display is true if this math is written with '$$' like
+	// '$$\sum_{i=1}^n i$$' in a paragraph.
+	Display bool
+}
+
+// Dump implements Node.Dump.
+func (n *InlineMath) Dump(source []byte, level int) {
+	m := map[string]string{
+		"Display": fmt.Sprintf("%v", n.Display),
+	}
+	gast.DumpHelper(n, source, level, m, nil)
+}
+
+// KindInlineMath is a NodeKind of the InlineMath node.
+var KindInlineMath = gast.NewNodeKind("InlineMath")
+
+// Kind implements Node.Kind.
+func (n *InlineMath) Kind() gast.NodeKind {
+	return KindInlineMath
+}
+
+// NewInlineMath returns a new InlineMath node.
+func NewInlineMath(display bool) *InlineMath {
+	return &InlineMath{
+		Display: display,
+	}
+}
+
+// A MathBlock struct represents a display math block like
+//
+//     $$
+//     \sum_{i=1}^n i
+//     $$
+//
+// Lines of a MathBlock are the raw TeX.
+type MathBlock struct {
+	gast.BaseBlock
+}
+
+// IsRaw implements Node.IsRaw.
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements Node.Dump.
+func (n *MathBlock) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindMathBlock is a NodeKind of the MathBlock node.
+var KindMathBlock = gast.NewNodeKind("MathBlock")
+
+// Kind implements Node.Kind.
+func (n *MathBlock) Kind() gast.NodeKind {
+	return KindMathBlock
+}
+
+// NewMathBlock returns a new MathBlock node.
+func NewMathBlock() *MathBlock {
+	return &MathBlock{}
+}
@@ -0,0 +1,58 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// A QuoteFigure struct represents a blockquote with an attribution like
+//
+//     > Stay hungry, stay foolish.
+//     > — Steve Jobs
+//
+// A QuoteFigure has a Blockquote node and a QuoteCitation node as children.
+type QuoteFigure struct {
+	gast.BaseBlock
+}
+
+// Dump implements Node.Dump.
+func (n *QuoteFigure) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindQuoteFigure is a NodeKind of the QuoteFigure node.
+var KindQuoteFigure = gast.NewNodeKind("QuoteFigure")
+
+// Kind implements Node.Kind.
+func (n *QuoteFigure) Kind() gast.NodeKind {
+	return KindQuoteFigure
+}
+
+// NewQuoteFigure returns a new QuoteFigure node.
+func NewQuoteFigure() *QuoteFigure {
+	return &QuoteFigure{}
+}
+
+// A QuoteCitation struct represents an attribution of a blockquote like
+// '— Steve Jobs'. Children of a QuoteCitation are inlines that follow
+// the em dash.
+type QuoteCitation struct {
+	gast.BaseBlock
+}
+
+// Dump implements Node.Dump.
+func (n *QuoteCitation) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindQuoteCitation is a NodeKind of the QuoteCitation node.
+var KindQuoteCitation = gast.NewNodeKind("QuoteCitation")
+
+// Kind implements Node.Kind.
+func (n *QuoteCitation) Kind() gast.NodeKind {
+	return KindQuoteCitation
+}
+
+// NewQuoteCitation returns a new QuoteCitation node.
+func NewQuoteCitation() *QuoteCitation {
+	return &QuoteCitation{}
+}
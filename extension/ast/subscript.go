@@ -0,0 +1,51 @@
+package ast
+
+import (
+	gast "github.com/yuin/goldmark/ast"
+)
+
+// A Subscript struct represents a subscript text like 'H~2~O'.
+type Subscript struct {
+	gast.BaseInline
+}
+
+// Dump implements Node.Dump.
+func (n *Subscript) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindSubscript is a NodeKind of the Subscript node.
+var KindSubscript = gast.NewNodeKind("Subscript")
+
+// Kind implements Node.Kind.
+func (n *Subscript) Kind() gast.NodeKind {
+	return KindSubscript
+}
+
+// NewSubscript returns a new Subscript node.
+func NewSubscript() *Subscript {
+	return &Subscript{}
+}
+
+// A Superscript struct represents a superscript text like '2^10^'.
+type Superscript struct {
+	gast.BaseInline
+}
+
+// Dump implements Node.Dump.
+func (n *Superscript) Dump(source []byte, level int) {
+	gast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindSuperscript is a NodeKind of the Superscript node.
+var KindSuperscript = gast.NewNodeKind("Superscript")
+
+// Kind implements Node.Kind.
+func (n *Superscript) Kind() gast.NodeKind {
+	return KindSuperscript
+}
+
+// NewSuperscript returns a new Superscript node.
+func NewSuperscript() *Superscript {
+	return &Superscript{}
+}
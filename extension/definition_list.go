@@ -34,7 +34,7 @@ func (b *definitionListParser) Open(parent gast.Node, reader text.Reader, pc par
 
 	last := parent.LastChild()
 	// need 1 or more spaces after ':'
-	w, _ := util.IndentWidth(line[pos+1:], pos+1)
+	w, _ := reader.TabStop().IndentWidth(line[pos+1:], pos+1)
 	if w < 1 {
 		return nil, parser.NoChildren
 	}
@@ -70,11 +70,11 @@ func (b *definitionListParser) Continue(node gast.Node, reader text.Reader, pc p
 		return parser.Continue | parser.HasChildren
 	}
 	list, _ := node.(*ast.DefinitionList)
-	w, _ := util.IndentWidth(line, reader.LineOffset())
+	w, _ := reader.TabStop().IndentWidth(line, reader.LineOffset())
 	if w < list.Offset {
 		return parser.Close
 	}
-	pos, padding := util.IndentPosition(line, reader.LineOffset(), list.Offset)
+	pos, padding := reader.TabStop().IndentPosition(line, reader.LineOffset(), list.Offset)
 	reader.AdvanceAndSetPadding(pos, padding)
 	return parser.Continue | parser.HasChildren
 }
@@ -108,7 +108,10 @@ func (b *definitionDescriptionParser) Open(parent gast.Node, reader text.Reader,
 	if line[pos] != ':' {
 		return nil, parser.NoChildren
 	}
-	list, _ := parent.(*ast.DefinitionList)
+	list, ok := parent.(*ast.DefinitionList)
+	if !ok {
+		return nil, parser.NoChildren
+	}
 	para := list.TemporaryParagraph
 	list.TemporaryParagraph = nil
 	if para != nil {
@@ -122,7 +125,7 @@ func (b *definitionDescriptionParser) Open(parent gast.Node, reader text.Reader,
 		}
 		para.Parent().RemoveChild(para.Parent(), para)
 	}
-	cpos, padding := util.IndentPosition(line[pos+1:], pos+1, list.Offset-pos-1)
+	cpos, padding := reader.TabStop().IndentPosition(line[pos+1:], pos+1, list.Offset-pos-1)
 	reader.AdvanceAndSetPadding(cpos, padding)
 
 	return ast.NewDefinitionDescription(), parser.HasChildren
@@ -143,6 +146,7 @@ func (b *definitionDescriptionParser) Close(node gast.Node, reader text.Reader,
 			if ok {
 				textBlock := gast.NewTextBlock()
 				textBlock.SetLines(paragraph.Lines())
+				textBlock.SetPos(paragraph.Pos())
 				desc.ReplaceChild(desc, paragraph, textBlock)
 			}
 		}
@@ -183,18 +187,33 @@ func (r *DefinitionListHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFunc
 
 func (r *DefinitionListHTMLRenderer) renderDefinitionList(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<dl>\n")
+		if n.Attributes() != nil {
+			w.WriteString("<dl")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<dl>")
+		}
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</dl>\n")
+		w.WriteString("</dl>")
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
 
 func (r *DefinitionListHTMLRenderer) renderDefinitionTerm(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<dt>")
+		if n.Attributes() != nil {
+			w.WriteString("<dt")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<dt>")
+		}
 	} else {
-		w.WriteString("</dt>\n")
+		w.WriteString("</dt>")
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
@@ -202,13 +221,19 @@ func (r *DefinitionListHTMLRenderer) renderDefinitionTerm(w util.BufWriter, sour
 func (r *DefinitionListHTMLRenderer) renderDefinitionDescription(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
 		n := node.(*ast.DefinitionDescription)
-		if n.IsTight {
-			w.WriteString("<dd>")
+		if n.Attributes() != nil {
+			w.WriteString("<dd")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
 		} else {
-			w.WriteString("<dd>\n")
+			w.WriteString("<dd>")
+		}
+		if fc := n.FirstChild(); !n.IsTight || (fc != nil && fc.Kind() != gast.KindTextBlock) {
+			r.WriteNewLine(w)
 		}
 	} else {
-		w.WriteString("</dd>\n")
+		w.WriteString("</dd>")
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
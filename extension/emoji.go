@@ -0,0 +1,226 @@
+package extension
+
+import (
+	"fmt"
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"strings"
+	"unicode/utf8"
+)
+
+// EmojiRenderMethod indicates how are emojis rendered in HTML format.
+type EmojiRenderMethod int
+
+const (
+	// EmojiUnicode renders emojis as unicode characters.
+	EmojiUnicode EmojiRenderMethod = iota
+
+	// EmojiImage renders emojis as 'img' elements.
+	EmojiImage
+)
+
+// An EmojiConfig struct is a data structure that holds configuration of the
+// Emoji extension.
+type EmojiConfig struct {
+	// Emojis is a map of shortcodes(without colons) to emojis.
+	Emojis map[string]string
+
+	// RenderMethod indicates how are emojis rendered.
+	RenderMethod EmojiRenderMethod
+
+	// ImageURL returns an image url of the given emoji. ImageURL is used
+	// only if RenderMethod is EmojiImage. If ImageURL is nil, emojis are
+	// rendered as unicode characters.
+	ImageURL func(shortName, value []byte) []byte
+}
+
+// NewEmojiConfig returns a new EmojiConfig with defaults.
+func NewEmojiConfig() EmojiConfig {
+	return EmojiConfig{
+		Emojis:       DefaultEmojis,
+		RenderMethod: EmojiUnicode,
+		ImageURL:     nil,
+	}
+}
+
+// TwemojiImageURL returns a function for WithEmojiImageURL that returns
+// urls of Twemoji images under the given base url like
+// '/images/twemoji/72x72/'. Twemoji image names are hexadecimal code points
+// joined with '-' without variation selectors like '1f604.png'.
+func TwemojiImageURL(baseURL string) func(shortName, value []byte) []byte {
+	return func(shortName, value []byte) []byte {
+		var codePoints []string
+		for len(value) != 0 {
+			r, size := utf8.DecodeRune(value)
+			value = value[size:]
+			if r != 0xfe0f {
+				codePoints = append(codePoints, fmt.Sprintf("%x", r))
+			}
+		}
+		return []byte(baseURL + strings.Join(codePoints, "-") + ".png")
+	}
+}
+
+// An EmojiOption interface sets options for the Emoji extension.
+type EmojiOption interface {
+	SetEmojiOption(*EmojiConfig)
+}
+
+type withEmojis struct {
+	value map[string]string
+}
+
+func (o *withEmojis) SetEmojiOption(c *EmojiConfig) {
+	c.Emojis = o.value
+}
+
+// WithEmojis is a functional option that replaces the emoji table.
+// Keys are shortcodes without colons like 'smile'.
+func WithEmojis(emojis map[string]string) EmojiOption {
+	return &withEmojis{emojis}
+}
+
+type withEmojiRenderMethod struct {
+	value EmojiRenderMethod
+}
+
+func (o *withEmojiRenderMethod) SetEmojiOption(c *EmojiConfig) {
+	c.RenderMethod = o.value
+}
+
+// WithEmojiRenderMethod is a functional option that indicates how are
+// emojis rendered in HTML format.
+func WithEmojiRenderMethod(m EmojiRenderMethod) EmojiOption {
+	return &withEmojiRenderMethod{m}
+}
+
+type withEmojiImageURL struct {
+	value func(shortName, value []byte) []byte
+}
+
+func (o *withEmojiImageURL) SetEmojiOption(c *EmojiConfig) {
+	c.ImageURL = o.value
+}
+
+// WithEmojiImageURL is a functional option that specifies a function that
+// returns an image url of an emoji. Emojis are rendered as images only if
+// this option is given. See also TwemojiImageURL.
+func WithEmojiImageURL(f func(shortName, value []byte) []byte) EmojiOption {
+	return &withEmojiImageURL{f}
+}
+
+type emojiParser struct {
+	EmojiConfig
+}
+
+// NewEmojiParser returns a new InlineParser that parses emoji shortcodes
+// like ':smile:'.
+func NewEmojiParser(opts ...EmojiOption) parser.InlineParser {
+	p := &emojiParser{
+		EmojiConfig: NewEmojiConfig(),
+	}
+	for _, o := range opts {
+		o.SetEmojiOption(&p.EmojiConfig)
+	}
+	return p
+}
+
+func (s *emojiParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func isEmojiShortNameCharacter(c byte) bool {
+	return util.IsAlphaNumeric(c) || c == '_' || c == '-' || c == '+'
+}
+
+func (s *emojiParser) Parse(parent gast.Node, block text.Reader, pc parser.Context) gast.Node {
+	line, _ := block.PeekLine()
+	i := 1
+	for ; i < len(line) && isEmojiShortNameCharacter(line[i]); i++ {
+	}
+	if i == 1 || i >= len(line) || line[i] != ':' {
+		return nil
+	}
+	shortName := line[1:i]
+	value, ok := s.Emojis[string(shortName)]
+	if !ok {
+		return nil
+	}
+	block.Advance(i + 1)
+	return ast.NewEmoji(shortName, []byte(value))
+}
+
+// EmojiHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders Emoji nodes.
+type EmojiHTMLRenderer struct {
+	html.Config
+	EmojiConfig
+}
+
+// NewEmojiHTMLRenderer returns a new EmojiHTMLRenderer.
+func NewEmojiHTMLRenderer(opts ...EmojiOption) renderer.NodeRenderer {
+	r := &EmojiHTMLRenderer{
+		Config:      html.NewConfig(),
+		EmojiConfig: NewEmojiConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetEmojiOption(&r.EmojiConfig)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *EmojiHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindEmoji, r.renderEmoji)
+}
+
+func (r *EmojiHTMLRenderer) renderEmoji(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		return gast.WalkContinue, nil
+	}
+	n := node.(*ast.Emoji)
+	if r.RenderMethod != EmojiImage || r.ImageURL == nil {
+		w.Write(util.EscapeHTML(n.Value))
+		return gast.WalkContinue, nil
+	}
+	w.WriteString(`<img class="emoji" src="`)
+	w.Write(util.EscapeHTML(util.URLEscape(r.ImageURL(n.ShortName, n.Value), false)))
+	w.WriteString(`" alt="`)
+	w.Write(util.EscapeHTML(n.Value))
+	w.WriteString(`" title=":`)
+	w.Write(util.EscapeHTML(n.ShortName))
+	w.WriteString(`:"`)
+	r.WriteVoidElementEnd(w)
+	return gast.WalkContinue, nil
+}
+
+type emoji struct {
+	options []EmojiOption
+}
+
+// Emoji is an extension that converts emoji shortcodes like ':smile:' into
+// emojis.
+var Emoji = &emoji{}
+
+// NewEmoji returns a new Extender that converts emoji shortcodes like
+// ':smile:' into emojis.
+func NewEmoji(opts ...EmojiOption) goldmark.Extender {
+	return &emoji{
+		options: opts,
+	}
+}
+
+func (e *emoji) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewEmojiParser(e.options...), 999),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewEmojiHTMLRenderer(e.options...), 500),
+	))
+}
@@ -0,0 +1,83 @@
+package extension
+
+// DefaultEmojis is a default emoji table of the Emoji extension.
+// Shortcodes are taken from GitHub.
+var DefaultEmojis = map[string]string{
+	"+1":                    "\U0001F44D",
+	"-1":                    "\U0001F44E",
+	"100":                   "\U0001F4AF",
+	"angry":                 "\U0001F620",
+	"apple":                 "\U0001F34E",
+	"beer":                  "\U0001F37A",
+	"bell":                  "\U0001F514",
+	"blush":                 "\U0001F60A",
+	"book":                  "\U0001F4D6",
+	"broken_heart":          "\U0001F494",
+	"bug":                   "\U0001F41B",
+	"cake":                  "\U0001F370",
+	"camera":                "\U0001F4F7",
+	"cat":                   "\U0001F431",
+	"check":                 "\u2714\uFE0F",
+	"clap":                  "\U0001F44F",
+	"coffee":                "\u2615",
+	"confused":              "\U0001F615",
+	"cry":                   "\U0001F622",
+	"dog":                   "\U0001F436",
+	"eyes":                  "\U0001F440",
+	"fire":                  "\U0001F525",
+	"flushed":               "\U0001F633",
+	"gift":                  "\U0001F381",
+	"grin":                  "\U0001F601",
+	"grinning":              "\U0001F600",
+	"heart":                 "\u2764\uFE0F",
+	"heart_eyes":            "\U0001F60D",
+	"hourglass":             "\u231B",
+	"hugs":                  "\U0001F917",
+	"innocent":              "\U0001F607",
+	"joy":                   "\U0001F602",
+	"kiss":                  "\U0001F48B",
+	"laughing":              "\U0001F606",
+	"memo":                  "\U0001F4DD",
+	"moon":                  "\U0001F314",
+	"muscle":                "\U0001F4AA",
+	"neutral_face":          "\U0001F610",
+	"ok_hand":               "\U0001F44C",
+	"pencil2":               "\u270F\uFE0F",
+	"pray":                  "\U0001F64F",
+	"question":              "\u2753",
+	"rage":                  "\U0001F621",
+	"rainbow":               "\U0001F308",
+	"raised_hands":          "\U0001F64C",
+	"rocket":                "\U0001F680",
+	"rofl":                  "\U0001F923",
+	"sad":                   "\U0001F61E",
+	"scream":                "\U0001F631",
+	"see_no_evil":           "\U0001F648",
+	"shrug":                 "\U0001F937",
+	"sleeping":              "\U0001F634",
+	"slightly_smiling_face": "\U0001F642",
+	"smile":                 "\U0001F604",
+	"smiley":                "\U0001F603",
+	"smirk":                 "\U0001F60F",
+	"sob":                   "\U0001F62D",
+	"sparkles":              "\u2728",
+	"star":                  "\u2B50",
+	"stuck_out_tongue":      "\U0001F61B",
+	"sunglasses":            "\U0001F60E",
+	"sunny":                 "\u2600\uFE0F",
+	"sweat_smile":           "\U0001F605",
+	"tada":                  "\U0001F389",
+	"thinking":              "\U0001F914",
+	"thumbsdown":            "\U0001F44E",
+	"thumbsup":              "\U0001F44D",
+	"trophy":                "\U0001F3C6",
+	"unamused":              "\U0001F612",
+	"warning":               "\u26A0\uFE0F",
+	"wave":                  "\U0001F44B",
+	"white_check_mark":      "\u2705",
+	"wink":                  "\U0001F609",
+	"x":                     "\u274C",
+	"yum":                   "\U0001F60B",
+	"zap":                   "\u26A1",
+	"zzz":                   "\U0001F4A4",
+}
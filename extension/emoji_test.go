@@ -0,0 +1,73 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer/html"
+	"testing"
+)
+
+func TestEmoji(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Emoji,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/emoji.txt", t)
+}
+
+func TestEmojiOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithRendererOptions(
+			html.WithXHTML(),
+		),
+		goldmark.WithExtensions(
+			NewEmoji(
+				WithEmojiRenderMethod(EmojiImage),
+				WithEmojiImageURL(TwemojiImageURL("/twemoji/72x72/")),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: ":smile: :heart:",
+			Expected: `<p><img class="emoji" src="/twemoji/72x72/1f604.png" alt="😄" title=":smile:" /> <img class="emoji" src="/twemoji/72x72/2764.png" alt="❤️" title=":heart:" /></p>`,
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewEmoji(
+				WithEmojiRenderMethod(EmojiImage),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: ":smile:",
+			Expected: `<p>😄</p>`,
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewEmoji(
+				WithEmojis(map[string]string{
+					"gopher": "<gopher>",
+				}),
+				WithEmojiRenderMethod(EmojiImage),
+				WithEmojiImageURL(func(shortName, value []byte) []byte {
+					return append([]byte("/emojis/"), shortName...)
+				}),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       3,
+			Markdown: ":gopher: :smile:",
+			Expected: `<p><img class="emoji" src="/emojis/gopher" alt="&lt;gopher&gt;" title=":gopher:"> :smile:</p>`,
+		},
+	}, t)
+}
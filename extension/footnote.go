@@ -10,11 +10,165 @@ import (
 	"github.com/yuin/goldmark/renderer/html"
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
+	"sort"
 	"strconv"
 )
 
+// A FootnoteConfig struct is a data structure that holds configuration of
+// the Footnote extension.
+type FootnoteConfig struct {
+	// ListTag is a name of an element that wraps the footnote list.
+	// If ListTag is empty, 'section' is used, or 'div' is used for XHTML.
+	ListTag string
+
+	// ListClass is a class of the element that wraps the footnote list.
+	ListClass string
+
+	// LinkClass is a class of links to footnotes.
+	LinkClass string
+
+	// BackLinkClass is a class of links from footnotes to their references.
+	BackLinkClass string
+
+	// Unreferenced is true if footnotes that are not referenced should be
+	// rendered after referenced footnotes.
+	Unreferenced bool
+
+	// LastDefinitionWins is true if the last definition should be used when
+	// footnotes with the same label are defined. By default, the first
+	// definition is used like link reference definitions.
+	LastDefinitionWins bool
+}
+
+// NewFootnoteConfig returns a new FootnoteConfig with defaults.
+func NewFootnoteConfig() FootnoteConfig {
+	return FootnoteConfig{
+		ListTag:       "",
+		ListClass:     "footnotes",
+		LinkClass:     "footnote-ref",
+		BackLinkClass: "footnote-backref",
+
+		Unreferenced:       false,
+		LastDefinitionWins: false,
+	}
+}
+
+// A FootnoteOption interface sets options for the Footnote extension.
+type FootnoteOption interface {
+	SetFootnoteOption(*FootnoteConfig)
+}
+
+type withFootnoteHTMLOptions struct {
+	value []html.Option
+}
+
+func (o *withFootnoteHTMLOptions) SetFootnoteOption(c *FootnoteConfig) {
+}
+
+func (o *withFootnoteHTMLOptions) SetHTMLOption(c *html.Config) {
+	for _, v := range o.value {
+		v.SetHTMLOption(c)
+	}
+}
+
+// WithFootnoteHTMLOptions is a functional option that wraps options for the
+// HTML renderers like html.WithXHTML.
+func WithFootnoteHTMLOptions(opts ...html.Option) FootnoteOption {
+	return &withFootnoteHTMLOptions{opts}
+}
+
+type withFootnoteListTag struct {
+	value string
+}
+
+func (o *withFootnoteListTag) SetFootnoteOption(c *FootnoteConfig) {
+	c.ListTag = o.value
+}
+
+// WithFootnoteListTag is a functional option that specifies a name of an
+// element that wraps the footnote list like "aside".
+func WithFootnoteListTag(tag string) FootnoteOption {
+	return &withFootnoteListTag{tag}
+}
+
+type withFootnoteListClass struct {
+	value string
+}
+
+func (o *withFootnoteListClass) SetFootnoteOption(c *FootnoteConfig) {
+	c.ListClass = o.value
+}
+
+// WithFootnoteListClass is a functional option that specifies a class of
+// the element that wraps the footnote list. The default class is
+// 'footnotes'. An empty class renders no class attribute.
+func WithFootnoteListClass(class string) FootnoteOption {
+	return &withFootnoteListClass{class}
+}
+
+type withFootnoteLinkClass struct {
+	value string
+}
+
+func (o *withFootnoteLinkClass) SetFootnoteOption(c *FootnoteConfig) {
+	c.LinkClass = o.value
+}
+
+// WithFootnoteLinkClass is a functional option that specifies a class of
+// links to footnotes. The default class is 'footnote-ref'.
+func WithFootnoteLinkClass(class string) FootnoteOption {
+	return &withFootnoteLinkClass{class}
+}
+
+type withFootnoteBackLinkClass struct {
+	value string
+}
+
+func (o *withFootnoteBackLinkClass) SetFootnoteOption(c *FootnoteConfig) {
+	c.BackLinkClass = o.value
+}
+
+// WithFootnoteBackLinkClass is a functional option that specifies a class
+// of links from footnotes to their references. The default class is
+// 'footnote-backref'.
+func WithFootnoteBackLinkClass(class string) FootnoteOption {
+	return &withFootnoteBackLinkClass{class}
+}
+
+type withFootnoteUnreferenced struct {
+}
+
+func (o *withFootnoteUnreferenced) SetFootnoteOption(c *FootnoteConfig) {
+	c.Unreferenced = true
+}
+
+// WithFootnoteUnreferenced is a functional option that renders footnotes
+// that are not referenced after referenced footnotes in the order they are
+// defined. By default, such footnotes are omitted.
+func WithFootnoteUnreferenced() FootnoteOption {
+	return &withFootnoteUnreferenced{}
+}
+
+type withFootnoteLastDefinitionWins struct {
+}
+
+func (o *withFootnoteLastDefinitionWins) SetFootnoteOption(c *FootnoteConfig) {
+	c.LastDefinitionWins = true
+}
+
+// WithFootnoteLastDefinitionWins is a functional option that uses the last
+// definition when footnotes with the same label are defined. By default, the
+// first definition is used and others are omitted.
+func WithFootnoteLastDefinitionWins() FootnoteOption {
+	return &withFootnoteLastDefinitionWins{}
+}
+
 var footnoteListKey = parser.NewContextKey()
 
+// footnoteLinksKey is a key of a map from footnote links to footnotes they
+// refer to. Links are numbered by the footnoteASTTransformer.
+var footnoteLinksKey = parser.NewContextKey()
+
 type footnoteBlockParser struct {
 }
 
@@ -53,7 +207,7 @@ func (b *footnoteBlockParser) Open(parent gast.Node, reader text.Reader, pc pars
 		return nil, parser.NoChildren
 	}
 	pos = pos + 2 + closes - open + 2
-	childpos, padding := util.IndentPosition(line[pos:], pos, 1)
+	childpos, padding := reader.TabStop().IndentPosition(line[pos:], pos, 1)
 	reader.AdvanceAndSetPadding(pos+childpos, padding)
 	item := ast.NewFootnote(label)
 	return item, parser.HasChildren
@@ -64,7 +218,7 @@ func (b *footnoteBlockParser) Continue(node gast.Node, reader text.Reader, pc pa
 	if util.IsBlank(line) {
 		return parser.Continue | parser.HasChildren
 	}
-	childpos, padding := util.IndentPosition(line, reader.LineOffset(), 4)
+	childpos, padding := reader.TabStop().IndentPosition(line, reader.LineOffset(), 4)
 	if childpos < 0 {
 		return parser.Close
 	}
@@ -86,9 +240,6 @@ func (b *footnoteBlockParser) Close(node gast.Node, reader text.Reader, pc parse
 		root.AppendChild(root, list)
 	}
 	node.Parent().RemoveChild(node.Parent(), node)
-	n := node.(*ast.Footnote)
-	index := list.ChildCount() + 1
-	n.Index = index
 	list.AppendChild(list, node)
 }
 
@@ -101,14 +252,19 @@ func (b *footnoteBlockParser) CanAcceptIndentedLine() bool {
 }
 
 type footnoteParser struct {
+	FootnoteConfig
 }
 
-var defaultFootnoteParser = &footnoteParser{}
-
 // NewFootnoteParser returns a new parser.InlineParser that can parse
 // footnote links of the Markdown(PHP Markdown Extra) text.
-func NewFootnoteParser() parser.InlineParser {
-	return defaultFootnoteParser
+func NewFootnoteParser(opts ...FootnoteOption) parser.InlineParser {
+	p := &footnoteParser{
+		FootnoteConfig: NewFootnoteConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetFootnoteOption(&p.FootnoteConfig)
+	}
+	return p
 }
 
 func (s *footnoteParser) Trigger() []byte {
@@ -141,30 +297,56 @@ func (s *footnoteParser) Parse(parent gast.Node, block text.Reader, pc parser.Co
 	if list == nil {
 		return nil
 	}
-	index := 0
+	footnote := findFootnote(list, value, s.LastDefinitionWins)
+	if footnote == nil {
+		return nil
+	}
+	var links map[*ast.FootnoteLink]*ast.Footnote
+	if v := pc.Get(footnoteLinksKey); v != nil {
+		links = v.(map[*ast.FootnoteLink]*ast.Footnote)
+	} else {
+		links = map[*ast.FootnoteLink]*ast.Footnote{}
+		pc.Set(footnoteLinksKey, links)
+	}
+	link := ast.NewFootnoteLink(0)
+	links[link] = footnote
+	return link
+}
+
+// findFootnote returns a footnote that has the given label in the list, or
+// nil if the list has no such footnotes.
+func findFootnote(list *ast.FootnoteList, label []byte, last bool) *ast.Footnote {
+	var footnote *ast.Footnote
 	for def := list.FirstChild(); def != nil; def = def.NextSibling() {
 		d := def.(*ast.Footnote)
-		if bytes.Equal(d.Ref, value) {
-			index = d.Index
-			break
+		if bytes.Equal(d.Ref, label) {
+			footnote = d
+			if !last {
+				break
+			}
 		}
 	}
-	if index == 0 {
-		return nil
-	}
-
-	return ast.NewFootnoteLink(index)
+	return footnote
 }
 
 type footnoteASTTransformer struct {
+	FootnoteConfig
 }
 
-var defaultFootnoteASTTransformer = &footnoteASTTransformer{}
-
 // NewFootnoteASTTransformer returns a new parser.ASTTransformer that
 // insert a footnote list to the last of the document.
-func NewFootnoteASTTransformer() parser.ASTTransformer {
-	return defaultFootnoteASTTransformer
+// Footnotes are numbered in the order they are first referenced from the
+// document, and then from footnotes in the order of their numbers.
+// Footnotes in the list are sorted by their numbers. Footnotes that are not
+// referenced and duplicated definitions are removed.
+func NewFootnoteASTTransformer(opts ...FootnoteOption) parser.ASTTransformer {
+	t := &footnoteASTTransformer{
+		FootnoteConfig: NewFootnoteConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetFootnoteOption(&t.FootnoteConfig)
+	}
+	return t
 }
 
 func (a *footnoteASTTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
@@ -176,29 +358,128 @@ func (a *footnoteASTTransformer) Transform(node *gast.Document, reader text.Read
 		return
 	}
 	pc.Set(footnoteListKey, nil)
+	var links map[*ast.FootnoteLink]*ast.Footnote
+	if v := pc.Get(footnoteLinksKey); v != nil {
+		links = v.(map[*ast.FootnoteLink]*ast.Footnote)
+	}
+	pc.Set(footnoteLinksKey, nil)
+
+	count := 0
+	var numbered []*ast.Footnote
+	number := func(n gast.Node) {
+		_ = gast.Walk(n, func(c gast.Node, entering bool) (gast.WalkStatus, error) {
+			link, ok := c.(*ast.FootnoteLink)
+			if !entering || !ok {
+				return gast.WalkContinue, nil
+			}
+			footnote := links[link]
+			if footnote == nil {
+				return gast.WalkContinue, nil
+			}
+			if footnote.Index == 0 {
+				count++
+				footnote.Index = count
+				numbered = append(numbered, footnote)
+			}
+			footnote.RefCount++
+			link.Index = footnote.Index
+			link.RefIndex = footnote.RefCount
+			return gast.WalkContinue, nil
+		})
+	}
+	// links in footnotes are numbered only if their footnotes are rendered.
+	next := 0
+	numberFootnotes := func() {
+		for ; next < len(numbered); next++ {
+			number(numbered[next])
+		}
+	}
+	number(node)
+	numberFootnotes()
+
+	footnotes := make([]*ast.Footnote, 0, list.ChildCount())
+	for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+		footnote := c.(*ast.Footnote)
+		if findFootnote(list, footnote.Ref, a.LastDefinitionWins) != footnote {
+			// only one of definitions that have the same label is used.
+			continue
+		}
+		if footnote.Index == 0 {
+			if !a.Unreferenced {
+				continue
+			}
+			count++
+			footnote.Index = count
+			numbered = append(numbered, footnote)
+			numberFootnotes()
+		}
+		footnotes = append(footnotes, footnote)
+	}
+	list.RemoveChildren(list)
+	if len(footnotes) == 0 {
+		return
+	}
+	sort.SliceStable(footnotes, func(i, j int) bool {
+		return footnotes[i].Index < footnotes[j].Index
+	})
+	for _, footnote := range footnotes {
+		list.AppendChild(list, footnote)
+		if footnote.RefCount == 0 {
+			continue
+		}
+		var container gast.Node
+		if last := footnote.LastChild(); last != nil && last.Kind() == gast.KindParagraph {
+			container = last
+		} else {
+			container = gast.NewParagraph()
+			footnote.AppendChild(footnote, container)
+		}
+		for i := 1; i <= footnote.RefCount; i++ {
+			container.AppendChild(container, ast.NewFootnoteBackLink(footnote.Index, i))
+		}
+	}
 	node.AppendChild(node, list)
 }
 
 // FootnoteHTMLRenderer is a renderer.NodeRenderer implementation that
 // renders FootnoteLink nodes.
+// Footnotes are rendered with ARIA DPUB roles like 'doc-endnotes' and
+// 'doc-backlink' for accessibility.
 type FootnoteHTMLRenderer struct {
 	html.Config
+	FootnoteConfig
 }
 
 // NewFootnoteHTMLRenderer returns a new FootnoteHTMLRenderer.
-func NewFootnoteHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+func NewFootnoteHTMLRenderer(opts ...FootnoteOption) renderer.NodeRenderer {
 	r := &FootnoteHTMLRenderer{
-		Config: html.NewConfig(),
+		Config:         html.NewConfig(),
+		FootnoteConfig: NewFootnoteConfig(),
 	}
 	for _, opt := range opts {
-		opt.SetHTMLOption(&r.Config)
+		opt.SetFootnoteOption(&r.FootnoteConfig)
+		if ho, ok := opt.(html.Option); ok {
+			ho.SetHTMLOption(&r.Config)
+		}
 	}
 	return r
 }
 
+// writeFootnoteClass writes a class attribute if the given class is not
+// empty.
+func writeFootnoteClass(w util.BufWriter, class string) {
+	if len(class) == 0 {
+		return
+	}
+	w.WriteString(` class="`)
+	w.Write(util.EscapeHTML([]byte(class)))
+	w.WriteByte('"')
+}
+
 // RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
 func (r *FootnoteHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindFootnoteLink, r.renderFootnoteLink)
+	reg.Register(ast.KindFootnoteBackLink, r.renderFootnoteBackLink)
 	reg.Register(ast.KindFootnote, r.renderFootnote)
 	reg.Register(ast.KindFootnoteList, r.renderFootnoteList)
 }
@@ -207,17 +488,45 @@ func (r *FootnoteHTMLRenderer) renderFootnoteLink(w util.BufWriter, source []byt
 	if entering {
 		n := node.(*ast.FootnoteLink)
 		is := strconv.Itoa(n.Index)
-		w.WriteString(`<sup id="fnref:`)
-		w.WriteString(is)
+		w.WriteString(`<sup id="`)
+		w.WriteString(footnoteRefID(n.Index, n.RefIndex))
 		w.WriteString(`"><a href="#fn:`)
 		w.WriteString(is)
-		w.WriteString(`" class="footnote-ref" role="doc-noteref">`)
+		w.WriteByte('"')
+		writeFootnoteClass(w, r.LinkClass)
+		w.WriteString(` role="doc-noteref">`)
 		w.WriteString(is)
 		w.WriteString(`</a></sup>`)
 	}
 	return gast.WalkContinue, nil
 }
 
+func (r *FootnoteHTMLRenderer) renderFootnoteBackLink(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		n := node.(*ast.FootnoteBackLink)
+		if n.PreviousSibling() != nil {
+			w.WriteString(`&#160;`)
+		}
+		w.WriteString(`<a href="#`)
+		w.WriteString(footnoteRefID(n.Index, n.RefIndex))
+		w.WriteByte('"')
+		writeFootnoteClass(w, r.BackLinkClass)
+		w.WriteString(` role="doc-backlink">&#x21a9;&#xfe0e;</a>`)
+	}
+	return gast.WalkContinue, nil
+}
+
+// footnoteRefID returns an id of the FootnoteLink.
+// The first link to a footnote has an id like 'fnref:1', and following links
+// have ids like 'fnref:1:2'.
+func footnoteRefID(index, refIndex int) string {
+	id := "fnref:" + strconv.Itoa(index)
+	if refIndex > 1 {
+		id += ":" + strconv.Itoa(refIndex)
+	}
+	return id
+}
+
 func (r *FootnoteHTMLRenderer) renderFootnote(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
 	n := node.(*ast.Footnote)
 	is := strconv.Itoa(n.Index)
@@ -225,56 +534,70 @@ func (r *FootnoteHTMLRenderer) renderFootnote(w util.BufWriter, source []byte, n
 		w.WriteString(`<li id="fn:`)
 		w.WriteString(is)
 		w.WriteString(`" role="doc-endnote">`)
-		w.WriteString("\n")
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</li>\n")
+		w.WriteString("</li>")
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
 
 func (r *FootnoteHTMLRenderer) renderFootnoteList(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
-	tag := "section"
-	if r.Config.XHTML {
-		tag = "div"
+	tag := r.ListTag
+	if len(tag) == 0 {
+		tag = "section"
+		if r.Config.XHTML {
+			tag = "div"
+		}
 	}
 	if entering {
 		w.WriteString("<")
 		w.WriteString(tag)
-		w.WriteString(` class="footnotes" role="doc-endnotes">`)
-		if r.Config.XHTML {
-			w.WriteString("\n<hr />\n")
-		} else {
-			w.WriteString("\n<hr>\n")
-		}
-		w.WriteString("<ol>\n")
+		writeFootnoteClass(w, r.ListClass)
+		w.WriteString(` role="doc-endnotes">`)
+		r.WriteNewLine(w)
+		r.WriteVoidElement(w, "hr")
+		r.WriteNewLine(w)
+		w.WriteString("<ol>")
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</ol>\n")
-		w.WriteString("<")
+		w.WriteString("</ol>")
+		r.WriteNewLine(w)
+		w.WriteString("</")
 		w.WriteString(tag)
-		w.WriteString(">\n")
+		w.WriteByte('>')
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
 
 type footnote struct {
+	options []FootnoteOption
 }
 
 // Footnote is an extension that allow you to use PHP Markdown Extra Footnotes.
 var Footnote = &footnote{}
 
+// NewFootnote returns a new extension with given options.
+func NewFootnote(opts ...FootnoteOption) goldmark.Extender {
+	return &footnote{
+		options: opts,
+	}
+}
+
 func (e *footnote) Extend(m goldmark.Markdown) {
 	m.Parser().AddOptions(
 		parser.WithBlockParsers(
 			util.Prioritized(NewFootnoteBlockParser(), 999),
 		),
 		parser.WithInlineParsers(
-			util.Prioritized(NewFootnoteParser(), 101),
+			util.Prioritized(NewFootnoteParser(e.options...), 101),
 		),
 		parser.WithASTTransformers(
-			util.Prioritized(NewFootnoteASTTransformer(), 999),
+			util.Prioritized(NewFootnoteASTTransformer(e.options...), 999),
 		),
 	)
 	m.Renderer().AddOptions(renderer.WithNodeRenderers(
-		util.Prioritized(NewFootnoteHTMLRenderer(), 500),
+		util.Prioritized(NewFootnoteHTMLRenderer(e.options...), 500),
 	))
 }
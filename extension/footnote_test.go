@@ -2,7 +2,9 @@ package extension
 
 import (
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
 	"testing"
 )
 
@@ -17,3 +19,132 @@ func TestFootnote(t *testing.T) {
 	)
 	goldmark.DoTestCaseFile(markdown, "_test/footnote.txt", t)
 }
+
+func TestFootnoteOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewFootnote(
+				WithFootnoteListTag("aside"),
+				WithFootnoteListClass("notes"),
+				WithFootnoteLinkClass(""),
+				WithFootnoteBackLinkClass("back"),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "a[^1]\n\n[^1]: note",
+			Expected: `<p>a<sup id="fnref:1"><a href="#fn:1" role="doc-noteref">1</a></sup></p>
+<aside class="notes" role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn:1" role="doc-endnote">
+<p>note&#160;<a href="#fnref:1" class="back" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+</ol>
+</aside>`,
+		},
+	}, t)
+}
+
+func TestFootnoteHTMLOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Footnote,
+		),
+	)
+	markdown.SetRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(
+		util.Prioritized(html.NewRenderer(html.WithXHTML()), 1000),
+		util.Prioritized(NewFootnoteHTMLRenderer(
+			WithFootnoteHTMLOptions(html.WithXHTML()),
+			WithFootnoteListClass("notes"),
+		), 500),
+	)))
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "a[^1]\n\n[^1]: note",
+			Expected: `<p>a<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup></p>
+<div class="notes" role="doc-endnotes">
+<hr />
+<ol>
+<li id="fn:1" role="doc-endnote">
+<p>note&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+</ol>
+</div>`,
+		},
+	}, t)
+}
+
+func TestFootnoteDefinitions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewFootnote(
+				WithFootnoteUnreferenced(),
+				WithFootnoteLastDefinitionWins(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "[^unused]: Not referenced.\n\nText[^a].\n\n[^a]: First.\n\n[^a]: Last.",
+			Expected: `<p>Text<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>.</p>
+<section class="footnotes" role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn:1" role="doc-endnote">
+<p>Last.&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+<li id="fn:2" role="doc-endnote">
+<p>Not referenced.</p>
+</li>
+</ol>
+</section>`,
+		},
+	}, t)
+}
+
+func TestFootnoteReferencesInDefinitions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Footnote,
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "[^a]: See[^b].\n\n[^b]: B.\n\nText[^b].",
+			Expected: `<p>Text<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>.</p>
+<section class="footnotes" role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn:1" role="doc-endnote">
+<p>B.&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+</ol>
+</section>`,
+		},
+		{
+			No:       2,
+			Markdown: "Text[^a] and[^c].\n\n[^a]: See[^b] and[^c].\n\n[^b]: B[^a].\n\n[^c]: C.",
+			Expected: `<p>Text<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup> and<sup id="fnref:2"><a href="#fn:2" class="footnote-ref" role="doc-noteref">2</a></sup>.</p>
+<section class="footnotes" role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn:1" role="doc-endnote">
+<p>See<sup id="fnref:3"><a href="#fn:3" class="footnote-ref" role="doc-noteref">3</a></sup> and<sup id="fnref:2:2"><a href="#fn:2" class="footnote-ref" role="doc-noteref">2</a></sup>.&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a>&#160;<a href="#fnref:1:2" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+<li id="fn:2" role="doc-endnote">
+<p>C.&#160;<a href="#fnref:2" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a>&#160;<a href="#fnref:2:2" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+<li id="fn:3" role="doc-endnote">
+<p>B<sup id="fnref:1:2"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>.&#160;<a href="#fnref:3" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</li>
+</ol>
+</section>`,
+		},
+	}, t)
+}
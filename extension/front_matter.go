@@ -0,0 +1,169 @@
+package extension
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// A FrontMatterFormat represents a format of front matters.
+type FrontMatterFormat int
+
+const (
+	// FrontMatterYAML is a format of front matters delimited by '---'.
+	FrontMatterYAML FrontMatterFormat = iota + 1
+
+	// FrontMatterTOML is a format of front matters delimited by '+++'.
+	FrontMatterTOML
+)
+
+// A FrontMatterData struct represents a front matter of a document.
+type FrontMatterData struct {
+	// Format is a format of this front matter.
+	Format FrontMatterFormat
+
+	// Raw is a text between delimiters. Raw is not parsed, so you can
+	// unmarshal it with a library you like.
+	Raw []byte
+}
+
+var frontMatterKey = parser.NewContextKey()
+
+// GetFrontMatter returns a front matter of the document parsed with the
+// given context, or nil if the document has no front matter.
+func GetFrontMatter(pc parser.Context) *FrontMatterData {
+	v := pc.Get(frontMatterKey)
+	if v == nil {
+		return nil
+	}
+	return v.(*FrontMatterData)
+}
+
+var frontMatterYAMLDelimiter = []byte("---")
+var frontMatterTOMLDelimiter = []byte("+++")
+
+var frontMatterStateKey = parser.NewContextKey()
+
+type frontMatterState struct {
+	delimiter []byte
+	format    FrontMatterFormat
+}
+
+type frontMatterParser struct {
+}
+
+var defaultFrontMatterParser = &frontMatterParser{}
+
+// NewFrontMatterParser returns a new BlockParser that parses a front matter
+// like
+//
+//     ---
+//     title: Hello
+//     ---
+//
+// at the very start of documents. Front matters are removed from the AST
+// and can be retrieved with GetFrontMatter.
+func NewFrontMatterParser() parser.BlockParser {
+	return defaultFrontMatterParser
+}
+
+func (b *frontMatterParser) Open(parent gast.Node, reader text.Reader, pc parser.Context) (gast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	// front matters must be at the very start of documents, a leading BOM
+	// is allowed.
+	source := reader.Source()
+	if segment.Start != len(source)-len(util.TrimBOM(source)) ||
+		parent.Kind() != gast.KindDocument || parent.HasChildren() {
+		return nil, parser.NoChildren
+	}
+	data := &frontMatterState{}
+	delimiter := util.TrimRightSpace(line)
+	switch {
+	case bytes.Equal(delimiter, frontMatterYAMLDelimiter):
+		data.delimiter, data.format = frontMatterYAMLDelimiter, FrontMatterYAML
+	case bytes.Equal(delimiter, frontMatterTOMLDelimiter):
+		data.delimiter, data.format = frontMatterTOMLDelimiter, FrontMatterTOML
+	default:
+		return nil, parser.NoChildren
+	}
+	// an unclosed delimiter is not a front matter but a thematic break or
+	// a paragraph.
+	if !hasFrontMatterCloser(source[segment.Stop:], data.delimiter) {
+		return nil, parser.NoChildren
+	}
+	pc.Set(frontMatterStateKey, data)
+	reader.Advance(segment.Len() - 1)
+	return gast.NewTextBlock(), parser.NoChildren
+}
+
+func hasFrontMatterCloser(source, delimiter []byte) bool {
+	for len(source) != 0 {
+		i := bytes.IndexAny(source, "\r\n")
+		line := source
+		if i > -1 {
+			line, source = source[:i+1], source[i+1:]
+		} else {
+			source = nil
+		}
+		if bytes.Equal(util.TrimRightSpace(line), delimiter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *frontMatterParser) Continue(node gast.Node, reader text.Reader, pc parser.Context) parser.State {
+	data := pc.Get(frontMatterStateKey).(*frontMatterState)
+	line, segment := reader.PeekLine()
+	if bytes.Equal(util.TrimRightSpace(line), data.delimiter) {
+		newline := util.LineEndingLength(line)
+		reader.Advance(segment.Len() - newline)
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *frontMatterParser) Close(node gast.Node, reader text.Reader, pc parser.Context) {
+	data := pc.Get(frontMatterStateKey).(*frontMatterState)
+	pc.Set(frontMatterStateKey, nil)
+	var raw []byte
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		raw = append(raw, segment.Value(reader.Source())...)
+	}
+	raw = util.NormalizeLineEndings(raw)
+	pc.Set(frontMatterKey, &FrontMatterData{
+		Format: data.format,
+		Raw:    raw,
+	})
+	node.Parent().RemoveChild(node.Parent(), node)
+}
+
+func (b *frontMatterParser) CanInterruptParagraph() bool {
+	return false
+}
+
+func (b *frontMatterParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+type frontMatter struct {
+}
+
+// FrontMatter is an extension that parses YAML front matters delimited by
+// '---' and TOML front matters delimited by '+++' at the very start of
+// documents. Front matters are not rendered.
+var FrontMatter = &frontMatter{}
+
+func (e *frontMatter) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithBlockParsers(
+		util.Prioritized(NewFrontMatterParser(), 50),
+	))
+}
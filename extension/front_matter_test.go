@@ -0,0 +1,57 @@
+package extension
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+)
+
+func TestFrontMatter(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			FrontMatter,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/front_matter.txt", t)
+}
+
+func TestGetFrontMatter(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			FrontMatter,
+		),
+	)
+	cases := []struct {
+		source string
+		format FrontMatterFormat
+		raw    string
+	}{
+		{"---\ntitle: Hello\n---\ntext", FrontMatterYAML, "title: Hello\n"},
+		{"+++\ntitle = \"Hello\"\n\n[a]\nb = 1\n+++", FrontMatterTOML, "title = \"Hello\"\n\n[a]\nb = 1\n"},
+		{"---\n---\n", FrontMatterYAML, ""},
+		{"text\n\n---\na: b\n---\n", 0, ""},
+	}
+	for i, c := range cases {
+		ctx := parser.NewContext()
+		var buf bytes.Buffer
+		if err := markdown.Convert([]byte(c.source), &buf, parser.WithContext(ctx)); err != nil {
+			t.Fatal(err)
+		}
+		fm := GetFrontMatter(ctx)
+		if c.format == 0 {
+			if fm != nil {
+				t.Errorf("%d: expected no front matter, but got %v", i, fm)
+			}
+			continue
+		}
+		if fm == nil {
+			t.Errorf("%d: expected a front matter", i)
+			continue
+		}
+		if fm.Format != c.format || string(fm.Raw) != c.raw {
+			t.Errorf("%d: expected (%d, %q), but got (%d, %q)", i, c.format, c.raw, fm.Format, fm.Raw)
+		}
+	}
+}
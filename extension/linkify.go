@@ -10,9 +10,10 @@ import (
 	"regexp"
 )
 
-var wwwURLRegxp = regexp.MustCompile(`^www\.[-a-zA-Z0-9@:%._\+~#=]{2,256}\.[a-z]{2,6}\b(?:[-a-zA-Z0-9@:%_\+.~#?&//=\(\);]*)`)
+// Domains and paths may contain non-ASCII letters like 'https://пример.рф/путь'.
+var wwwURLRegxp = regexp.MustCompile(`^www\.[-\p{L}\p{N}\p{M}@:%._\+~#=]{2,256}\.(?:[a-z]{2,6}\b|\p{L}{2,63})(?:[-\p{L}\p{N}\p{M}@:%_\+.~#?&//=\(\);]*)`)
 
-var urlRegexp = regexp.MustCompile(`^(?:http|https|ftp):\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{2,256}\.[a-z]{2,6}\b([-a-zA-Z0-9@:%_\+.~#?&//=\(\);]*)`)
+var urlRegexp = regexp.MustCompile(`^(?:http|https|ftp):\/\/(?:www\.)?[-\p{L}\p{N}\p{M}@:%._\+~#=]{2,256}\.(?:[a-z]{2,6}\b|\p{L}{2,63})([-\p{L}\p{N}\p{M}@:%_\+.~#?&//=\(\);]*)`)
 
 type linkifyParser struct {
 }
@@ -58,35 +59,7 @@ func (s *linkifyParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 		protocol = []byte("http")
 	}
 	if m != nil {
-		lastChar := line[m[1]-1]
-		if lastChar == '.' {
-			m[1]--
-		} else if lastChar == ')' {
-			closing := 0
-			for i := m[1] - 1; i >= m[0]; i-- {
-				if line[i] == ')' {
-					closing++
-				} else if line[i] == '(' {
-					closing--
-				}
-			}
-			if closing > 0 {
-				m[1] -= closing
-			}
-		} else if lastChar == ';' {
-			i := m[1] - 2
-			for ; i >= m[0]; i-- {
-				if util.IsAlphaNumeric(line[i]) {
-					continue
-				}
-				break
-			}
-			if i != m[1]-2 {
-				if line[i] == '&' {
-					m[1] -= m[1] - i
-				}
-			}
-		}
+		m[1] = trimURLTrailingPunctuations(line, m[0], m[1])
 	}
 	if m == nil {
 		typ = ast.AutoLinkEmail
@@ -125,6 +98,36 @@ func (s *linkifyParser) Parse(parent ast.Node, block text.Reader, pc parser.Cont
 	return link
 }
 
+// trimURLTrailingPunctuations returns a stop position of the URL in
+// line[start:stop] without trailing punctuations. Closing parentheses are
+// trimmed only if these are not balanced, and entity references are trimmed.
+func trimURLTrailingPunctuations(line []byte, start, stop int) int {
+	for stop > start {
+		switch line[stop-1] {
+		case '?', '!', '.', ',', ':', '*', '_', '~':
+			stop--
+		case ')':
+			opened := bytes.Count(line[start:stop], []byte{'('})
+			closed := bytes.Count(line[start:stop], []byte{')'})
+			if closed <= opened {
+				return stop
+			}
+			stop--
+		case ';':
+			i := stop - 2
+			for ; i >= start && util.IsAlphaNumeric(line[i]); i-- {
+			}
+			if i < start || i == stop-2 || line[i] != '&' {
+				return stop
+			}
+			stop = i
+		default:
+			return stop
+		}
+	}
+	return stop
+}
+
 func (s *linkifyParser) CloseBlock(parent ast.Node, pc parser.Context) {
 	// nothing to do
 }
@@ -0,0 +1,209 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+type doubleDelimiterProcessor struct {
+	char    byte
+	newNode func() gast.Node
+}
+
+func (p *doubleDelimiterProcessor) IsDelimiter(b byte) bool {
+	return b == p.char
+}
+
+func (p *doubleDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	return opener.Char == closer.Char && closer.OriginalLength == opener.OriginalLength
+}
+
+func (p *doubleDelimiterProcessor) OnMatch(consumes int) gast.Node {
+	return p.newNode()
+}
+
+var defaultMarkDelimiterProcessor = &doubleDelimiterProcessor{
+	char:    '=',
+	newNode: func() gast.Node { return ast.NewMark() },
+}
+
+var defaultInsertDelimiterProcessor = &doubleDelimiterProcessor{
+	char:    '+',
+	newNode: func() gast.Node { return ast.NewInsert() },
+}
+
+type doubleDelimiterParser struct {
+	processor *doubleDelimiterProcessor
+}
+
+var defaultMarkParser = &doubleDelimiterParser{defaultMarkDelimiterProcessor}
+
+var defaultInsertParser = &doubleDelimiterParser{defaultInsertDelimiterProcessor}
+
+// NewMarkParser return a new InlineParser that parses
+// highlighted texts like '==text=='.
+func NewMarkParser() parser.InlineParser {
+	return defaultMarkParser
+}
+
+// NewInsertParser return a new InlineParser that parses
+// inserted texts like '++text++'.
+func NewInsertParser() parser.InlineParser {
+	return defaultInsertParser
+}
+
+func (s *doubleDelimiterParser) Trigger() []byte {
+	return []byte{s.processor.char}
+}
+
+func (s *doubleDelimiterParser) Parse(parent gast.Node, block text.Reader, pc parser.Context) gast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 1, s.processor)
+	if node == nil {
+		return nil
+	}
+	if node.OriginalLength != 2 {
+		// a single or three or more characters are literal texts.
+		block.Advance(node.OriginalLength)
+		return gast.NewTextSegment(segment.WithStop(segment.Start + node.OriginalLength))
+	}
+	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
+	block.Advance(node.OriginalLength)
+	pc.PushDelimiter(node)
+	return node
+}
+
+// MarkHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders Mark and Insert nodes.
+type MarkHTMLRenderer struct {
+	html.Config
+}
+
+// NewMarkHTMLRenderer returns a new MarkHTMLRenderer.
+func NewMarkHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &MarkHTMLRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *MarkHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindMark, r.renderMark)
+	reg.Register(ast.KindInsert, r.renderInsert)
+}
+
+func (r *MarkHTMLRenderer) renderMark(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		if n.Attributes() != nil {
+			w.WriteString("<mark")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<mark>")
+		}
+	} else {
+		w.WriteString("</mark>")
+	}
+	return gast.WalkContinue, nil
+}
+
+func (r *MarkHTMLRenderer) renderInsert(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		if n.Attributes() != nil {
+			w.WriteString("<ins")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<ins>")
+		}
+	} else {
+		w.WriteString("</ins>")
+	}
+	return gast.WalkContinue, nil
+}
+
+// A MarkConfig struct is a data structure that holds configuration of the
+// Mark extension.
+type MarkConfig struct {
+	// Mark is true if '==text==' should be a highlighted text.
+	Mark bool
+
+	// Insert is true if '++text++' should be an inserted text.
+	Insert bool
+}
+
+// A MarkOption interface sets options for the Mark extension.
+type MarkOption interface {
+	SetMarkOption(*MarkConfig)
+}
+
+type withMark struct {
+}
+
+func (o *withMark) SetMarkOption(c *MarkConfig) {
+	c.Mark = true
+}
+
+// WithMark is a functional option that enables highlighted texts
+// like '==text=='.
+func WithMark() MarkOption {
+	return &withMark{}
+}
+
+type withInsert struct {
+}
+
+func (o *withInsert) SetMarkOption(c *MarkConfig) {
+	c.Insert = true
+}
+
+// WithInsert is a functional option that enables inserted texts
+// like '++text++'.
+func WithInsert() MarkOption {
+	return &withInsert{}
+}
+
+type mark struct {
+	MarkConfig
+}
+
+// Mark is an extension that allow you to use highlighted texts like
+// '==text==' and inserted texts like '++text++'.
+var Mark = NewMark(WithMark(), WithInsert())
+
+// NewMark returns a new Extender that allow you to use highlighted texts
+// and inserted texts enabled by the given options.
+func NewMark(opts ...MarkOption) goldmark.Extender {
+	e := &mark{}
+	for _, opt := range opts {
+		opt.SetMarkOption(&e.MarkConfig)
+	}
+	return e
+}
+
+func (e *mark) Extend(m goldmark.Markdown) {
+	if e.Mark {
+		m.Parser().AddOptions(parser.WithInlineParsers(
+			util.Prioritized(NewMarkParser(), 500),
+		))
+	}
+	if e.Insert {
+		m.Parser().AddOptions(parser.WithInlineParsers(
+			util.Prioritized(NewInsertParser(), 500),
+		))
+	}
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewMarkHTMLRenderer(), 500),
+	))
+}
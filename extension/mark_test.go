@@ -0,0 +1,31 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	"testing"
+)
+
+func TestMark(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Mark,
+			Strikethrough,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/mark.txt", t)
+}
+
+func TestMarkOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewMark(WithInsert()),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "==highlighted== and ++inserted++",
+			Expected: "<p>==highlighted== and <ins>inserted</ins></p>",
+		},
+	}, t)
+}
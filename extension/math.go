@@ -0,0 +1,361 @@
+package extension
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// A MathRenderFunc function renders the given raw TeX. display is true if
+// the TeX is a display math. This is useful for rendering maths on the
+// server side with KaTeX and so on.
+type MathRenderFunc func(w io.Writer, tex []byte, display bool) error
+
+// A MathConfig struct is a data structure that holds configuration of the
+// Math extension.
+type MathConfig struct {
+	// Inline is true if '$...$' should be an inline math.
+	Inline bool
+
+	// Display is true if '$$...$$' should be a display math.
+	Display bool
+
+	// RenderMath renders maths instead of the default renderer that writes
+	// '<span class="math inline">' and '<div class="math display">'
+	// with the HTML escaped TeX.
+	RenderMath MathRenderFunc
+}
+
+// NewMathConfig returns a new MathConfig with defaults.
+func NewMathConfig() MathConfig {
+	return MathConfig{
+		Inline:     true,
+		Display:    true,
+		RenderMath: nil,
+	}
+}
+
+// A MathOption interface sets options for the Math extension.
+type MathOption interface {
+	SetMathOption(*MathConfig)
+}
+
+type withoutInlineMath struct {
+}
+
+func (o *withoutInlineMath) SetMathOption(c *MathConfig) {
+	c.Inline = false
+}
+
+// WithoutInlineMath is a functional option that disables inline maths
+// like '$...$'.
+func WithoutInlineMath() MathOption {
+	return &withoutInlineMath{}
+}
+
+type withoutDisplayMath struct {
+}
+
+func (o *withoutDisplayMath) SetMathOption(c *MathConfig) {
+	c.Display = false
+}
+
+// WithoutDisplayMath is a functional option that disables display maths
+// like '$$...$$'.
+func WithoutDisplayMath() MathOption {
+	return &withoutDisplayMath{}
+}
+
+type withMathRenderer struct {
+	value MathRenderFunc
+}
+
+func (o *withMathRenderer) SetMathOption(c *MathConfig) {
+	c.RenderMath = o.value
+}
+
+// WithMathRenderer is a functional option that specifies a function that
+// renders maths.
+func WithMathRenderer(f MathRenderFunc) MathOption {
+	return &withMathRenderer{f}
+}
+
+type inlineMathParser struct {
+	MathConfig
+}
+
+// NewInlineMathParser returns a new InlineParser that parses inline maths
+// like '$...$' and '$$...$$'.
+//
+// An opening '$' must be followed by a non-space character and a closing
+// '$' must be preceded by a non-space character and must not be followed
+// by a digit, so that texts like '$5 and $10' are not maths.
+func NewInlineMathParser(opts ...MathOption) parser.InlineParser {
+	p := &inlineMathParser{
+		MathConfig: NewMathConfig(),
+	}
+	for _, o := range opts {
+		o.SetMathOption(&p.MathConfig)
+	}
+	return p
+}
+
+func (s *inlineMathParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (s *inlineMathParser) Parse(parent gast.Node, block text.Reader, pc parser.Context) gast.Node {
+	line, segment := block.PeekLine()
+	opener := 1
+	if len(line) > 1 && line[1] == '$' {
+		opener = 2
+	}
+	if opener == 2 && !s.Display {
+		// '$$' is a literal text, not a pair of empty inline maths.
+		block.Advance(2)
+		return gast.NewTextSegment(segment.WithStop(segment.Start + 2))
+	}
+	if opener == 1 && (!s.Inline || len(line) < 2 || util.IsSpace(line[1])) {
+		return nil
+	}
+	closer := -1
+	for i := opener; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c != '$' || i == opener {
+			continue
+		}
+		if opener == 2 {
+			if i+1 < len(line) && line[i+1] == '$' {
+				closer = i
+				break
+			}
+			continue
+		}
+		if !util.IsSpace(line[i-1]) && (i+1 >= len(line) || !util.IsNumeric(line[i+1])) {
+			closer = i
+			break
+		}
+	}
+	if closer < 0 {
+		if opener == 2 {
+			block.Advance(2)
+			return gast.NewTextSegment(segment.WithStop(segment.Start + 2))
+		}
+		return nil
+	}
+	node := ast.NewInlineMath(opener == 2)
+	value := text.NewSegment(segment.Start+opener, segment.Start+closer)
+	node.AppendChild(node, gast.NewRawTextSegment(value))
+	block.Advance(closer + opener)
+	return node
+}
+
+type mathBlockData struct {
+	indent int
+	closed bool
+}
+
+var mathBlockInfoKey = parser.NewContextKey()
+
+type mathBlockParser struct {
+}
+
+var defaultMathBlockParser = &mathBlockParser{}
+
+// NewMathBlockParser returns a new BlockParser that parses display maths
+// like
+//
+//     $$
+//     \sum_{i=1}^n i
+//     $$
+//
+// and '$$\sum_{i=1}^n i$$' in a line.
+func NewMathBlockParser() parser.BlockParser {
+	return defaultMathBlockParser
+}
+
+var mathDelimiter = []byte("$$")
+
+func (b *mathBlockParser) Open(parent gast.Node, reader text.Reader, pc parser.Context) (gast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathDelimiter) {
+		return nil, parser.NoChildren
+	}
+	node := ast.NewMathBlock()
+	data := &mathBlockData{indent: pos}
+	start := pos + 2
+	rest := util.TrimRightSpace(line[start:])
+	if i := bytes.Index(rest, mathDelimiter); i > -1 {
+		// texts following closing '$$' like '$$a$$ and $$b$$' are
+		// paragraphs.
+		if i != len(rest)-2 {
+			return nil, parser.NoChildren
+		}
+		data.closed = true
+		rest = rest[:i]
+	}
+	if !util.IsBlank(rest) {
+		value := text.NewSegment(segment.Start+start, segment.Start+start+len(rest))
+		if !data.closed {
+			value.Stop = segment.Stop
+		}
+		node.Lines().Append(value)
+	}
+	pc.Set(mathBlockInfoKey, data)
+	return node, parser.NoChildren
+}
+
+func (b *mathBlockParser) Continue(node gast.Node, reader text.Reader, pc parser.Context) parser.State {
+	data := pc.Get(mathBlockInfoKey).(*mathBlockData)
+	if data.closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	pos, padding := util.DedentPosition(line, data.indent)
+	rest := util.TrimRightSpace(line[pos:])
+	if bytes.HasSuffix(rest, mathDelimiter) {
+		rest = rest[:len(rest)-2]
+		if !util.IsBlank(rest) {
+			node.Lines().Append(text.NewSegmentPadding(segment.Start+pos, segment.Start+pos+len(rest), padding))
+		}
+		newline := util.LineEndingLength(line)
+		reader.Advance(segment.Stop - segment.Start - newline - segment.Padding)
+		return parser.Close
+	}
+	node.Lines().Append(text.NewSegmentPadding(segment.Start+pos, segment.Stop, padding))
+	reader.AdvanceAndSetPadding(segment.Len()-pos-1, padding)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *mathBlockParser) Close(node gast.Node, reader text.Reader, pc parser.Context) {
+	pc.Set(mathBlockInfoKey, nil)
+}
+
+func (b *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// MathHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders InlineMath and MathBlock nodes.
+type MathHTMLRenderer struct {
+	html.Config
+	MathConfig
+}
+
+// NewMathHTMLRenderer returns a new MathHTMLRenderer.
+func NewMathHTMLRenderer(opts ...MathOption) renderer.NodeRenderer {
+	r := &MathHTMLRenderer{
+		Config:     html.NewConfig(),
+		MathConfig: NewMathConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetMathOption(&r.MathConfig)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *MathHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindInlineMath, r.renderInlineMath)
+	reg.Register(ast.KindMathBlock, r.renderMathBlock)
+}
+
+func (r *MathHTMLRenderer) renderInlineMath(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		return gast.WalkContinue, nil
+	}
+	n := node.(*ast.InlineMath)
+	var tex []byte
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		tex = append(tex, c.Text(source)...)
+	}
+	if r.RenderMath != nil {
+		return gast.WalkSkipChildren, r.RenderMath(w, tex, n.Display)
+	}
+	if n.Display {
+		w.WriteString(`<span class="math display">`)
+	} else {
+		w.WriteString(`<span class="math inline">`)
+	}
+	r.Writer.RawWrite(w, tex)
+	w.WriteString(`</span>`)
+	return gast.WalkSkipChildren, nil
+}
+
+func (r *MathHTMLRenderer) renderMathBlock(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if !entering {
+		return gast.WalkContinue, nil
+	}
+	var tex []byte
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		tex = append(tex, line.Value(source)...)
+	}
+	tex = util.NormalizeLineEndings(tex)
+	if r.RenderMath != nil {
+		if err := r.RenderMath(w, tex, true); err != nil {
+			return gast.WalkStop, err
+		}
+		r.WriteNewLine(w)
+		return gast.WalkContinue, nil
+	}
+	w.WriteString(`<div class="math display">`)
+	r.Writer.RawWrite(w, tex)
+	w.WriteString(`</div>`)
+	r.WriteNewLine(w)
+	return gast.WalkContinue, nil
+}
+
+type math struct {
+	options []MathOption
+}
+
+// Math is an extension that allow you to use inline maths like '$...$' and
+// display maths like '$$...$$'.
+var Math = &math{}
+
+// NewMath returns a new Extender that allow you to use maths configured by
+// the given options.
+func NewMath(opts ...MathOption) goldmark.Extender {
+	return &math{
+		options: opts,
+	}
+}
+
+func (e *math) Extend(m goldmark.Markdown) {
+	config := NewMathConfig()
+	for _, opt := range e.options {
+		opt.SetMathOption(&config)
+	}
+	if config.Display {
+		m.Parser().AddOptions(parser.WithBlockParsers(
+			util.Prioritized(NewMathBlockParser(), 750),
+		))
+	}
+	if config.Inline || config.Display {
+		m.Parser().AddOptions(parser.WithInlineParsers(
+			util.Prioritized(NewInlineMathParser(e.options...), 150),
+		))
+	}
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewMathHTMLRenderer(e.options...), 500),
+	))
+}
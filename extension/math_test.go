@@ -0,0 +1,68 @@
+package extension
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestMath(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Math,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/math.txt", t)
+}
+
+func TestMathOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewMath(
+				WithoutDisplayMath(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "$a$ $$b$$\n\n$$\nc\n$$",
+			Expected: "<p><span class=\"math inline\">a</span> $$b$$</p>\n<p>$$\nc\n$$</p>",
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewMath(
+				WithoutInlineMath(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "$a$ $$b$$",
+			Expected: "<p>$a$ <span class=\"math display\">b</span></p>",
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewMath(
+				WithMathRenderer(func(w io.Writer, tex []byte, display bool) error {
+					_, err := fmt.Fprintf(w, "<katex display=%v>%s</katex>", display, tex)
+					return err
+				}),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       3,
+			Markdown: "$a$\n\n$$\nb\n$$",
+			Expected: "<p><katex display=false>a</katex></p>\n<katex display=true>b\n</katex>",
+		},
+	}, t)
+}
@@ -0,0 +1,215 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A MentionConfig struct is a data structure that holds configuration of the
+// Mention extension.
+type MentionConfig struct {
+	// Mentions is true if '@name' should be a link.
+	Mentions bool
+
+	// Hashtags is true if '#tag' should be a link.
+	Hashtags bool
+
+	// MentionURL returns a link destination for the given user name.
+	MentionURL func(name []byte) []byte
+
+	// HashtagURL returns a link destination for the given tag.
+	HashtagURL func(tag []byte) []byte
+
+	// IsNameCharacter returns true if the given character can be a part
+	// of user names and tags.
+	IsNameCharacter func(r rune) bool
+}
+
+// NewMentionConfig returns a new MentionConfig with defaults.
+func NewMentionConfig() MentionConfig {
+	return MentionConfig{
+		Mentions:        true,
+		Hashtags:        true,
+		MentionURL:      defaultMentionURL,
+		HashtagURL:      defaultHashtagURL,
+		IsNameCharacter: isDefaultNameCharacter,
+	}
+}
+
+func defaultMentionURL(name []byte) []byte {
+	return append([]byte("/users/"), name...)
+}
+
+func defaultHashtagURL(tag []byte) []byte {
+	return append([]byte("/tags/"), tag...)
+}
+
+func isDefaultNameCharacter(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// A MentionOption interface sets options for the Mention extension.
+type MentionOption interface {
+	SetMentionOption(*MentionConfig)
+}
+
+type withMentionURL struct {
+	value func([]byte) []byte
+}
+
+func (o *withMentionURL) SetMentionOption(c *MentionConfig) {
+	c.MentionURL = o.value
+}
+
+// WithMentionURL is a functional option that specifies a function that
+// returns a link destination for a user name.
+// The default function returns '/users/name'.
+func WithMentionURL(f func(name []byte) []byte) MentionOption {
+	return &withMentionURL{f}
+}
+
+type withHashtagURL struct {
+	value func([]byte) []byte
+}
+
+func (o *withHashtagURL) SetMentionOption(c *MentionConfig) {
+	c.HashtagURL = o.value
+}
+
+// WithHashtagURL is a functional option that specifies a function that
+// returns a link destination for a tag.
+// The default function returns '/tags/tag'.
+func WithHashtagURL(f func(tag []byte) []byte) MentionOption {
+	return &withHashtagURL{f}
+}
+
+type withoutMentions struct {
+}
+
+func (o *withoutMentions) SetMentionOption(c *MentionConfig) {
+	c.Mentions = false
+}
+
+// WithoutMentions is a functional option that disables '@name' links.
+func WithoutMentions() MentionOption {
+	return &withoutMentions{}
+}
+
+type withoutHashtags struct {
+}
+
+func (o *withoutHashtags) SetMentionOption(c *MentionConfig) {
+	c.Hashtags = false
+}
+
+// WithoutHashtags is a functional option that disables '#tag' links.
+func WithoutHashtags() MentionOption {
+	return &withoutHashtags{}
+}
+
+type withNameCharacter struct {
+	value func(rune) bool
+}
+
+func (o *withNameCharacter) SetMentionOption(c *MentionConfig) {
+	c.IsNameCharacter = o.value
+}
+
+// WithNameCharacter is a functional option that specifies characters that
+// can be a part of user names and tags. By default, letters, digits
+// and '_' are allowed.
+func WithNameCharacter(f func(r rune) bool) MentionOption {
+	return &withNameCharacter{f}
+}
+
+type mentionParser struct {
+	MentionConfig
+}
+
+// NewMentionParser returns a new InlineParser that parses '@name' and
+// '#tag' as links.
+func NewMentionParser(opts ...MentionOption) parser.InlineParser {
+	p := &mentionParser{
+		MentionConfig: NewMentionConfig(),
+	}
+	for _, o := range opts {
+		o.SetMentionOption(&p.MentionConfig)
+	}
+	return p
+}
+
+func (s *mentionParser) Trigger() []byte {
+	var trigger []byte
+	if s.Mentions {
+		trigger = append(trigger, '@')
+	}
+	if s.Hashtags {
+		trigger = append(trigger, '#')
+	}
+	return trigger
+}
+
+func (s *mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	// links can not contain other links.
+	if parser.IsInLinkLabel(pc) {
+		return nil
+	}
+	before := block.PrecendingCharacter()
+	// avoids emails, entity references like '&#35;' and urls like '/#anchor'.
+	if before == '&' || before == '/' || before == '@' || before == '#' ||
+		unicode.IsLetter(before) || unicode.IsDigit(before) || s.IsNameCharacter(before) {
+		return nil
+	}
+	line, segment := block.PeekLine()
+	c := line[0]
+	if (c == '@' && !s.Mentions) || (c == '#' && !s.Hashtags) {
+		return nil
+	}
+	i := 1
+	for i < len(line) {
+		r, size := utf8.DecodeRune(line[i:])
+		if !s.IsNameCharacter(r) {
+			break
+		}
+		i += size
+	}
+	if i == 1 {
+		return nil
+	}
+	name := line[1:i]
+	block.Advance(i)
+	link := ast.NewLink()
+	if c == '@' {
+		link.Destination = s.MentionURL(name)
+	} else {
+		link.Destination = s.HashtagURL(name)
+	}
+	link.AppendChild(link, ast.NewTextSegment(segment.WithStop(segment.Start+i)))
+	return link
+}
+
+type mention struct {
+	options []MentionOption
+}
+
+// Mention is an extension that converts '@name' and '#tag' into links.
+var Mention = &mention{}
+
+// NewMention returns a new Extender that converts '@name' and '#tag' into
+// links.
+func NewMention(opts ...MentionOption) goldmark.Extender {
+	return &mention{
+		options: opts,
+	}
+}
+
+func (e *mention) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewMentionParser(e.options...), 500),
+	))
+}
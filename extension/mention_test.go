@@ -0,0 +1,53 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	"testing"
+)
+
+func TestMention(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Mention,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/mention.txt", t)
+}
+
+func TestMentionOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewMention(
+				WithMentionURL(func(name []byte) []byte {
+					return append([]byte("https://example.com/@"), name...)
+				}),
+				WithNameCharacter(func(r rune) bool {
+					return r == '-' || isDefaultNameCharacter(r)
+				}),
+				WithoutHashtags(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "@foo-bar #tag",
+			Expected: `<p><a href="https://example.com/@foo-bar">@foo-bar</a> #tag</p>`,
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewMention(
+				WithoutMentions(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "@foo #tag",
+			Expected: `<p>@foo <a href="/tags/tag">#tag</a></p>`,
+		},
+	}, t)
+}
@@ -0,0 +1,170 @@
+package extension
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var emDash = []byte("—")
+
+type quoteFigureASTTransformer struct {
+}
+
+var defaultQuoteFigureASTTransformer = &quoteFigureASTTransformer{}
+
+// NewQuoteFigureASTTransformer returns a new parser.ASTTransformer that
+// replaces blockquotes that end with a line like '— Steve Jobs' with
+// QuoteFigure nodes.
+func NewQuoteFigureASTTransformer() parser.ASTTransformer {
+	return defaultQuoteFigureASTTransformer
+}
+
+func (a *quoteFigureASTTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
+	blockquotes := []gast.Node{}
+	gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if entering && n.Kind() == gast.KindBlockquote {
+			blockquotes = append(blockquotes, n)
+		}
+		return gast.WalkContinue, nil
+	})
+	source := reader.Source()
+	for _, blockquote := range blockquotes {
+		citation := liftQuoteCitation(blockquote, source)
+		if citation == nil {
+			continue
+		}
+		figure := ast.NewQuoteFigure()
+		if positioner, ok := blockquote.(gast.Positioner); ok {
+			figure.SetPos(positioner.Pos())
+		}
+		parent := blockquote.Parent()
+		parent.ReplaceChild(parent, blockquote, figure)
+		figure.AppendChild(figure, blockquote)
+		figure.AppendChild(figure, citation)
+	}
+}
+
+// liftQuoteCitation removes the last line of the given blockquote if the
+// line begins with an em dash and returns the rest of the line as a
+// QuoteCitation node. liftQuoteCitation returns nil if the blockquote does
+// not have such a line.
+func liftQuoteCitation(blockquote gast.Node, source []byte) *ast.QuoteCitation {
+	paragraph, ok := blockquote.LastChild().(*gast.Paragraph)
+	if !ok {
+		return nil
+	}
+	// inlines of the last line follow the last line break. Line breaks in
+	// nested inlines like emphases are not considered.
+	var lineBreak *gast.Text
+	for c := paragraph.LastChild(); c != nil; c = c.PreviousSibling() {
+		if t, ok := c.(*gast.Text); ok && (t.SoftLineBreak() || t.HardLineBreak()) {
+			lineBreak = t
+			break
+		}
+	}
+	var first gast.Node
+	if lineBreak != nil {
+		first = lineBreak.NextSibling()
+	} else {
+		first = paragraph.FirstChild()
+	}
+	t, ok := first.(*gast.Text)
+	if !ok || !bytes.HasPrefix(t.Segment.Value(source), emDash) {
+		return nil
+	}
+	segment := t.Segment.WithStart(t.Segment.Start + len(emDash))
+	segment = segment.TrimLeftSpace(source)
+	if segment.Len() == 0 && t.NextSibling() == nil {
+		return nil
+	}
+	t.Segment = segment
+	citation := ast.NewQuoteCitation()
+	for c := gast.Node(t); c != nil; {
+		next := c.NextSibling()
+		citation.AppendChild(citation, c)
+		c = next
+	}
+	if lineBreak != nil {
+		lineBreak.SetSoftLineBreak(false)
+		lineBreak.SetHardLineBreak(false)
+		lineBreak.Segment = lineBreak.Segment.TrimRightSpace(source)
+	} else {
+		blockquote.RemoveChild(blockquote, paragraph)
+	}
+	return citation
+}
+
+// QuoteFigureHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders QuoteFigure and QuoteCitation nodes.
+type QuoteFigureHTMLRenderer struct {
+	html.Config
+}
+
+// NewQuoteFigureHTMLRenderer returns a new QuoteFigureHTMLRenderer.
+func NewQuoteFigureHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &QuoteFigureHTMLRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *QuoteFigureHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindQuoteFigure, r.renderQuoteFigure)
+	reg.Register(ast.KindQuoteCitation, r.renderQuoteCitation)
+}
+
+func (r *QuoteFigureHTMLRenderer) renderQuoteFigure(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		if node.Attributes() != nil {
+			w.WriteString("<figure")
+			html.RenderAttributes(w, node)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<figure>")
+		}
+	} else {
+		w.WriteString("</figure>")
+	}
+	r.WriteNewLine(w)
+	return gast.WalkContinue, nil
+}
+
+func (r *QuoteFigureHTMLRenderer) renderQuoteCitation(w util.BufWriter, source []byte, node gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		w.WriteString("<figcaption>&mdash; <cite>")
+	} else {
+		w.WriteString("</cite></figcaption>")
+		r.WriteNewLine(w)
+	}
+	return gast.WalkContinue, nil
+}
+
+type quoteFigure struct {
+}
+
+// QuoteFigure is an extension that renders blockquotes that end with an
+// attribution like '— Steve Jobs' as '<figure>' with a '<figcaption>'.
+var QuoteFigure = &quoteFigure{}
+
+func (e *quoteFigure) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(NewQuoteFigureASTTransformer(), 600),
+		),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewQuoteFigureHTMLRenderer(), 500),
+	))
+}
@@ -0,0 +1,16 @@
+package extension
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestQuoteFigure(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			QuoteFigure,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/quote_figure.txt", t)
+}
@@ -19,7 +19,8 @@ func (p *strikethroughDelimiterProcessor) IsDelimiter(b byte) bool {
 }
 
 func (p *strikethroughDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
-	return opener.Char == closer.Char
+	// a single '~' is not a strikethrough but a subscript.
+	return opener.Char == closer.Char && closer.OriginalLength == opener.OriginalLength
 }
 
 func (p *strikethroughDelimiterProcessor) OnMatch(consumes int) gast.Node {
@@ -50,6 +51,11 @@ func (s *strikethroughParser) Parse(parent gast.Node, block text.Reader, pc pars
 	if node == nil {
 		return nil
 	}
+	if node.OriginalLength > 2 {
+		// three or more tildes do not create a strikethrough.
+		block.Advance(node.OriginalLength)
+		return gast.NewTextSegment(segment.WithStop(segment.Start + node.OriginalLength))
+	}
 	node.Segment = segment.WithStop(segment.Start + node.OriginalLength)
 	block.Advance(node.OriginalLength)
 	pc.PushDelimiter(node)
@@ -84,7 +90,13 @@ func (r *StrikethroughHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncR
 
 func (r *StrikethroughHTMLRenderer) renderStrikethrough(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<del>")
+		if n.Attributes() != nil {
+			w.WriteString("<del")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<del>")
+		}
 	} else {
 		w.WriteString("</del>")
 	}
@@ -0,0 +1,221 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"unicode"
+)
+
+type scriptDelimiterProcessor struct {
+	char    byte
+	newNode func() gast.Node
+}
+
+func (p *scriptDelimiterProcessor) IsDelimiter(b byte) bool {
+	return b == p.char
+}
+
+func (p *scriptDelimiterProcessor) CanOpenCloser(opener, closer *parser.Delimiter) bool {
+	// single '~'s are subscripts and double '~~'s are strikethroughs.
+	return opener.Char == closer.Char && closer.OriginalLength == 1
+}
+
+func (p *scriptDelimiterProcessor) OnMatch(consumes int) gast.Node {
+	return p.newNode()
+}
+
+var defaultSubscriptDelimiterProcessor = &scriptDelimiterProcessor{
+	char:    '~',
+	newNode: func() gast.Node { return ast.NewSubscript() },
+}
+
+var defaultSuperscriptDelimiterProcessor = &scriptDelimiterProcessor{
+	char:    '^',
+	newNode: func() gast.Node { return ast.NewSuperscript() },
+}
+
+type scriptParser struct {
+	processor *scriptDelimiterProcessor
+	kind      gast.NodeKind
+}
+
+var defaultSubscriptParser = &scriptParser{defaultSubscriptDelimiterProcessor, ast.KindSubscript}
+
+var defaultSuperscriptParser = &scriptParser{defaultSuperscriptDelimiterProcessor, ast.KindSuperscript}
+
+// NewSubscriptParser return a new InlineParser that parses
+// subscript expressions like 'H~2~O'.
+func NewSubscriptParser() parser.InlineParser {
+	return defaultSubscriptParser
+}
+
+// NewSuperscriptParser return a new InlineParser that parses
+// superscript expressions like '2^10^'.
+func NewSuperscriptParser() parser.InlineParser {
+	return defaultSuperscriptParser
+}
+
+func (s *scriptParser) Trigger() []byte {
+	return []byte{s.processor.char}
+}
+
+func (s *scriptParser) Parse(parent gast.Node, block text.Reader, pc parser.Context) gast.Node {
+	before := block.PrecendingCharacter()
+	line, segment := block.PeekLine()
+	node := parser.ScanDelimiter(line, before, 1, s.processor)
+	// a part of runs like '^^' is not a delimiter.
+	if node == nil || node.OriginalLength != 1 || before == rune(s.processor.char) {
+		return nil
+	}
+	// unlike emphasis, subscripts and superscripts can be opened anywhere
+	// if there is a closer on the same line. These can not contain spaces
+	// unless these are escaped.
+	node.CanOpen = hasScriptCloser(line, s.processor.char)
+	node.CanClose = !unicode.IsSpace(before)
+	node.Segment = segment.WithStop(segment.Start + 1)
+	block.Advance(1)
+	pc.PushDelimiter(node)
+	return node
+}
+
+// CloseBlock implements parser.CloseBlocker.
+// Escaped spaces like 'a\ b' in subscripts and superscripts are rendered as
+// spaces.
+func (s *scriptParser) CloseBlock(parent gast.Node, block text.Reader, pc parser.Context) {
+	var texts []*gast.Text
+	_ = gast.Walk(parent, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering || n.Kind() != s.kind {
+			return gast.WalkContinue, nil
+		}
+		_ = gast.Walk(n, func(c gast.Node, entering bool) (gast.WalkStatus, error) {
+			if t, ok := c.(*gast.Text); ok && entering && !t.IsRaw() {
+				texts = append(texts, t)
+			}
+			return gast.WalkContinue, nil
+		})
+		return gast.WalkSkipChildren, nil
+	})
+	source := block.Source()
+	for _, t := range texts {
+		unescapeScriptSpaces(t, source)
+	}
+}
+
+// unescapeScriptSpaces removes backslashes before spaces by splitting the
+// given text.
+func unescapeScriptSpaces(t *gast.Text, source []byte) {
+	for i := t.Segment.Start; i < t.Segment.Stop-1; i++ {
+		if source[i] != '\\' {
+			continue
+		}
+		if source[i+1] != ' ' {
+			i++ // skips escaped characters like '\\'
+			continue
+		}
+		t.Parent().InsertBefore(t.Parent(), t, gast.NewTextSegment(t.Segment.WithStop(i)))
+		t.Segment = t.Segment.WithStart(i + 1)
+	}
+}
+
+func hasScriptCloser(line []byte, char byte) bool {
+	for i := 1; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\':
+			i++
+		case c == char:
+			return i > 1
+		case util.IsSpace(c):
+			return false
+		}
+	}
+	return false
+}
+
+// SubscriptHTMLRenderer is a renderer.NodeRenderer implementation that
+// renders Subscript and Superscript nodes.
+type SubscriptHTMLRenderer struct {
+	html.Config
+}
+
+// NewSubscriptHTMLRenderer returns a new SubscriptHTMLRenderer.
+func NewSubscriptHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+	r := &SubscriptHTMLRenderer{
+		Config: html.NewConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetHTMLOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs.
+func (r *SubscriptHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindSubscript, r.renderSubscript)
+	reg.Register(ast.KindSuperscript, r.renderSuperscript)
+}
+
+func (r *SubscriptHTMLRenderer) renderSubscript(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		if n.Attributes() != nil {
+			w.WriteString("<sub")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<sub>")
+		}
+	} else {
+		w.WriteString("</sub>")
+	}
+	return gast.WalkContinue, nil
+}
+
+func (r *SubscriptHTMLRenderer) renderSuperscript(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
+	if entering {
+		if n.Attributes() != nil {
+			w.WriteString("<sup")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<sup>")
+		}
+	} else {
+		w.WriteString("</sup>")
+	}
+	return gast.WalkContinue, nil
+}
+
+type subscript struct {
+}
+
+// Subscript is an extension that allow you to use subscript expression like 'H~2~O' .
+var Subscript = &subscript{}
+
+func (e *subscript) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewSubscriptParser(), 600),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewSubscriptHTMLRenderer(), 500),
+	))
+}
+
+type superscript struct {
+}
+
+// Superscript is an extension that allow you to use superscript expression like '2^10^' .
+var Superscript = &superscript{}
+
+func (e *superscript) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewSuperscriptParser(), 600),
+	))
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(NewSubscriptHTMLRenderer(), 500),
+	))
+}
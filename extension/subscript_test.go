@@ -0,0 +1,32 @@
+package extension
+
+import (
+	"github.com/yuin/goldmark"
+	"testing"
+)
+
+func TestSubscript(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Strikethrough,
+			Subscript,
+			Superscript,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/subscript.txt", t)
+}
+
+func TestSubscriptOnly(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			Subscript,
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "H~2~O ~~not deleted~~ 2^10^",
+			Expected: "<p>H<sub>2</sub>O ~~not deleted~~ 2^10^</p>",
+		},
+	}, t)
+}
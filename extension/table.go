@@ -47,6 +47,7 @@ func (b *tableParagraphTransformer) Transform(node *gast.Paragraph, reader text.
 	}
 	table := ast.NewTable()
 	table.Alignments = alignments
+	table.SetPos(node.Pos())
 	table.AppendChild(table, ast.NewTableHeader(header))
 	if lines.Len() > 2 {
 		for i := 2; i < lines.Len(); i++ {
@@ -142,19 +143,204 @@ func (b *tableParagraphTransformer) parseDelimiter(segment text.Segment, reader
 	return alignments
 }
 
+// TableCellAlignMethod indicates how are table cells aligned in HTML format.
+type TableCellAlignMethod int
+
+const (
+	// TableCellAlignAttribute renders alignments of the table cells as 'align'
+	// attributes.
+	TableCellAlignAttribute TableCellAlignMethod = iota
+
+	// TableCellAlignStyle renders alignments of the table cells as 'style'
+	// attributes.
+	TableCellAlignStyle
+)
+
+// A TableConfig struct is a data structure that holds configuration of the
+// Table extension.
+type TableConfig struct {
+	html.Config
+
+	// TableCellAlignMethod indicates how are table cells aligned.
+	TableCellAlignMethod TableCellAlignMethod
+
+	// CellLineBreaks indicates whether literal '\n' sequences in table cells
+	// are converted into line breaks.
+	CellLineBreaks bool
+}
+
+// NewTableConfig returns a new TableConfig with defaults.
+func NewTableConfig() TableConfig {
+	return TableConfig{
+		Config:               html.NewConfig(),
+		TableCellAlignMethod: TableCellAlignAttribute,
+		CellLineBreaks:       false,
+	}
+}
+
+// SetOption implements renderer.SetOptioner.
+func (c *TableConfig) SetOption(name renderer.OptionName, value interface{}) {
+	switch name {
+	case optTableCellAlignMethod:
+		c.TableCellAlignMethod = value.(TableCellAlignMethod)
+	default:
+		c.Config.SetOption(name, value)
+	}
+}
+
+// A TableOption interface sets options for the Table extension.
+type TableOption interface {
+	SetTableOption(*TableConfig)
+}
+
+type withTableHTMLOptions struct {
+	value []html.Option
+}
+
+func (o *withTableHTMLOptions) SetConfig(c *renderer.Config) {
+	for _, v := range o.value {
+		if ro, ok := v.(renderer.Option); ok {
+			ro.SetConfig(c)
+		}
+	}
+}
+
+func (o *withTableHTMLOptions) SetTableOption(c *TableConfig) {
+	for _, v := range o.value {
+		v.SetHTMLOption(&c.Config)
+	}
+}
+
+// WithTableHTMLOptions is a functional option that wraps options for the
+// HTML renderers.
+func WithTableHTMLOptions(opts ...html.Option) interface {
+	renderer.Option
+	TableOption
+} {
+	return &withTableHTMLOptions{opts}
+}
+
+// TableCellAlignMethod is an option name used in WithTableCellAlignMethod.
+const optTableCellAlignMethod renderer.OptionName = "TableCellAlignMethod"
+
+type withTableCellAlignMethod struct {
+	value TableCellAlignMethod
+}
+
+func (o *withTableCellAlignMethod) SetConfig(c *renderer.Config) {
+	c.Options[optTableCellAlignMethod] = o.value
+}
+
+func (o *withTableCellAlignMethod) SetTableOption(c *TableConfig) {
+	c.TableCellAlignMethod = o.value
+}
+
+// WithTableCellAlignMethod is a functional option that indicates how are table
+// cells aligned in HTML format.
+func WithTableCellAlignMethod(a TableCellAlignMethod) interface {
+	renderer.Option
+	TableOption
+} {
+	return &withTableCellAlignMethod{a}
+}
+
+type withTableCellLineBreaks struct {
+}
+
+func (o *withTableCellLineBreaks) SetTableOption(c *TableConfig) {
+	c.CellLineBreaks = true
+}
+
+// WithTableCellLineBreaks is a functional option that converts literal '\n'
+// sequences in table cells into line breaks like '<br>', because table cells
+// can not contain new lines. '\n' in code spans and escaped '\\n' are not
+// converted.
+// This option is available only for NewTable, because it adds a
+// transformer to the parser.
+func WithTableCellLineBreaks() TableOption {
+	return &withTableCellLineBreaks{}
+}
+
+type tableCellLineBreakTransformer struct {
+}
+
+var defaultTableCellLineBreakTransformer = &tableCellLineBreakTransformer{}
+
+// NewTableCellLineBreakTransformer returns a new parser.ASTTransformer that
+// converts literal '\n' sequences in table cells into hard line breaks.
+func NewTableCellLineBreakTransformer() parser.ASTTransformer {
+	return defaultTableCellLineBreakTransformer
+}
+
+func (a *tableCellLineBreakTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var texts []*gast.Text
+	_ = gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering {
+			return gast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case gast.KindCodeSpan, gast.KindRawHTML:
+			return gast.WalkSkipChildren, nil
+		case gast.KindText:
+			if t := n.(*gast.Text); !t.IsRaw() && isInTableCell(t) {
+				texts = append(texts, t)
+			}
+		}
+		return gast.WalkContinue, nil
+	})
+	for _, t := range texts {
+		splitTableCellLineBreaks(t, source)
+	}
+}
+
+func isInTableCell(n gast.Node) bool {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindTableCell {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTableCellLineBreaks splits the given text at literal '\n' sequences
+// into texts that end with hard line breaks.
+func splitTableCellLineBreaks(t *gast.Text, source []byte) {
+	segment := t.Segment
+	for i := segment.Start; i < segment.Stop-1; i++ {
+		if source[i] != '\\' {
+			continue
+		}
+		backslashes := 0
+		for j := i - 1; j >= 0 && source[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 != 0 || source[i+1] != 'n' {
+			i++
+			continue
+		}
+		line := gast.NewTextSegment(segment.WithStop(i))
+		line.SetHardLineBreak(true)
+		t.Parent().InsertBefore(t.Parent(), t, line)
+		segment = segment.WithStart(i + 2)
+		i++
+	}
+	t.Segment = segment
+}
+
 // TableHTMLRenderer is a renderer.NodeRenderer implementation that
 // renders Table nodes.
 type TableHTMLRenderer struct {
-	html.Config
+	TableConfig
 }
 
 // NewTableHTMLRenderer returns a new TableHTMLRenderer.
-func NewTableHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+func NewTableHTMLRenderer(opts ...TableOption) renderer.NodeRenderer {
 	r := &TableHTMLRenderer{
-		Config: html.NewConfig(),
+		TableConfig: NewTableConfig(),
 	}
 	for _, opt := range opts {
-		opt.SetHTMLOption(&r.Config)
+		opt.SetTableOption(&r.TableConfig)
 	}
 	return r
 }
@@ -169,22 +355,41 @@ func (r *TableHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegistere
 
 func (r *TableHTMLRenderer) renderTable(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<table>\n")
+		if n.Attributes() != nil {
+			w.WriteString("<table")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<table>")
+		}
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</table>\n")
+		w.WriteString("</table>")
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
 
 func (r *TableHTMLRenderer) renderTableHeader(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<thead>\n")
-		w.WriteString("<tr>\n")
+		w.WriteString("<thead>")
+		r.WriteNewLine(w)
+		if n.Attributes() != nil {
+			w.WriteString("<tr")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<tr>")
+		}
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</tr>\n")
-		w.WriteString("</thead>\n")
+		w.WriteString("</tr>")
+		r.WriteNewLine(w)
+		w.WriteString("</thead>")
+		r.WriteNewLine(w)
 		if n.NextSibling() != nil {
-			w.WriteString("<tbody>\n")
+			w.WriteString("<tbody>")
+			r.WriteNewLine(w)
 		}
 	}
 	return gast.WalkContinue, nil
@@ -192,11 +397,20 @@ func (r *TableHTMLRenderer) renderTableHeader(w util.BufWriter, source []byte, n
 
 func (r *TableHTMLRenderer) renderTableRow(w util.BufWriter, source []byte, n gast.Node, entering bool) (gast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<tr>\n")
+		if n.Attributes() != nil {
+			w.WriteString("<tr")
+			html.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<tr>")
+		}
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</tr>\n")
+		w.WriteString("</tr>")
+		r.WriteNewLine(w)
 		if n.Parent().LastChild() == n {
-			w.WriteString("</tbody>\n")
+			w.WriteString("</tbody>")
+			r.WriteNewLine(w)
 		}
 	}
 	return gast.WalkContinue, nil
@@ -211,26 +425,51 @@ func (r *TableHTMLRenderer) renderTableCell(w util.BufWriter, source []byte, nod
 	if entering {
 		align := ""
 		if n.Alignment != ast.AlignNone {
-			align = fmt.Sprintf(` align="%s"`, n.Alignment.String())
+			switch r.TableCellAlignMethod {
+			case TableCellAlignStyle:
+				align = fmt.Sprintf(` style="text-align:%s"`, n.Alignment.String())
+			default:
+				align = fmt.Sprintf(` align="%s"`, n.Alignment.String())
+			}
 		}
-		fmt.Fprintf(w, "<%s%s>", tag, align)
+		fmt.Fprintf(w, "<%s%s", tag, align)
+		html.RenderAttributes(w, n)
+		w.WriteByte('>')
 	} else {
-		fmt.Fprintf(w, "</%s>\n", tag)
+		fmt.Fprintf(w, "</%s>", tag)
+		r.WriteNewLine(w)
 	}
 	return gast.WalkContinue, nil
 }
 
 type table struct {
+	options []TableOption
 }
 
 // Table is an extension that allow you to use GFM tables .
 var Table = &table{}
 
+// NewTable returns a new extension with given options.
+func NewTable(opts ...TableOption) goldmark.Extender {
+	return &table{
+		options: opts,
+	}
+}
+
 func (e *table) Extend(m goldmark.Markdown) {
 	m.Parser().AddOptions(parser.WithParagraphTransformers(
 		util.Prioritized(NewTableParagraphTransformer(), 200),
 	))
+	config := NewTableConfig()
+	for _, opt := range e.options {
+		opt.SetTableOption(&config)
+	}
+	if config.CellLineBreaks {
+		m.Parser().AddOptions(parser.WithASTTransformers(
+			util.Prioritized(NewTableCellLineBreakTransformer(), 200),
+		))
+	}
 	m.Renderer().AddOptions(renderer.WithNodeRenderers(
-		util.Prioritized(NewTableHTMLRenderer(), 500),
+		util.Prioritized(NewTableHTMLRenderer(e.options...), 500),
 	))
 }
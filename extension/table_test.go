@@ -2,7 +2,13 @@ package extension
 
 import (
 	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"testing"
 )
 
@@ -17,3 +23,140 @@ func TestTable(t *testing.T) {
 	)
 	goldmark.DoTestCaseFile(markdown, "_test/table.txt", t)
 }
+
+func TestTableWithAlignStyle(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithRendererOptions(
+			html.WithUnsafe(),
+		),
+		goldmark.WithExtensions(
+			NewTable(
+				WithTableCellAlignMethod(TableCellAlignStyle),
+			),
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/table_align_style.txt", t)
+}
+
+func TestTableCompactOutput(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithRendererOptions(
+			html.WithCompactOutput(),
+		),
+		goldmark.WithExtensions(
+			Table,
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "| a | b |\n|---|---|\n| 1 | 2 |\n",
+			Expected: "<table><thead><tr><th>a</th><th>b</th></tr></thead><tbody><tr><td>1</td><td>2</td></tr></tbody></table>",
+		},
+	}, t)
+}
+
+func TestTableCellLineBreaks(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewTable(
+				WithTableCellLineBreaks(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "| a\\nb | c |\n| --- | --- |\n| *d\\ne* | `f\\ng` \\\\n |\n\nh\\ni",
+			Expected: `<table>
+<thead>
+<tr>
+<th>a<br>
+b</th>
+<th>c</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td><em>d<br>
+e</em></td>
+<td><code>f\ng</code> \n</td>
+</tr>
+</tbody>
+</table>
+<p>h\ni</p>`,
+		},
+		{
+			No:       2,
+			Markdown: "| a<br>b |\n| --- |\n| c\\nd |",
+			Expected: `<table>
+<thead>
+<tr>
+<th>a<!-- raw HTML omitted -->b</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td>c<br>
+d</td>
+</tr>
+</tbody>
+</table>`,
+		},
+	}, t)
+
+	// WithTableCellLineBreaks adds a transformer to the parser, so it can
+	// not be used as a renderer option.
+	var opt interface{} = WithTableCellLineBreaks()
+	if _, ok := opt.(renderer.Option); ok {
+		t.Error("WithTableCellLineBreaks should not be a renderer.Option")
+	}
+}
+
+type tableAttributesTransformer struct {
+}
+
+func (a *tableAttributesTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
+	_ = gast.Walk(node, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if entering {
+			switch n.Kind() {
+			case ast.KindTable:
+				n.SetAttribute([]byte("class"), []byte("data"))
+			case ast.KindTableCell:
+				n.SetAttribute([]byte("data-col"), []byte("1"))
+			}
+		}
+		return gast.WalkContinue, nil
+	})
+}
+
+func TestTableAttributes(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithASTTransformers(
+				util.Prioritized(&tableAttributesTransformer{}, 999),
+			),
+		),
+		goldmark.WithExtensions(
+			Table,
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "| a |\n| :-: |\n| b |",
+			Expected: `<table class="data">
+<thead>
+<tr>
+<th align="center" data-col="1">a</th>
+</tr>
+</thead>
+<tbody>
+<tr>
+<td align="center" data-col="1">b</td>
+</tr>
+</tbody>
+</table>`,
+		},
+	}, t)
+}
@@ -1,6 +1,8 @@
 package extension
 
 import (
+	"bytes"
+
 	"github.com/yuin/goldmark"
 	gast "github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension/ast"
@@ -12,7 +14,80 @@ import (
 	"regexp"
 )
 
-var taskListRegexp = regexp.MustCompile(`^\[([\sxX])\]\s*`)
+// A TaskListConfig struct is a data structure that holds configuration of
+// the TaskList extension.
+type TaskListConfig struct {
+	// AriaLabel is true if checkboxes should have 'aria-label' attributes
+	// that are texts of the list items for screen readers.
+	AriaLabel bool
+
+	// Disabled is true if checkboxes should have 'disabled' attributes.
+	Disabled bool
+}
+
+// NewTaskListConfig returns a new TaskListConfig with defaults.
+func NewTaskListConfig() TaskListConfig {
+	return TaskListConfig{
+		AriaLabel: false,
+		Disabled:  true,
+	}
+}
+
+// A TaskListOption interface sets options for the TaskList extension.
+type TaskListOption interface {
+	SetTaskListOption(*TaskListConfig)
+}
+
+type withTaskListHTMLOptions struct {
+	value []html.Option
+}
+
+func (o *withTaskListHTMLOptions) SetTaskListOption(c *TaskListConfig) {
+}
+
+func (o *withTaskListHTMLOptions) SetHTMLOption(c *html.Config) {
+	for _, v := range o.value {
+		v.SetHTMLOption(c)
+	}
+}
+
+// WithTaskListHTMLOptions is a functional option that wraps options for the
+// HTML renderers like html.WithXHTML.
+func WithTaskListHTMLOptions(opts ...html.Option) TaskListOption {
+	return &withTaskListHTMLOptions{opts}
+}
+
+type withTaskCheckBoxAriaLabels struct {
+}
+
+func (o *withTaskCheckBoxAriaLabels) SetTaskListOption(c *TaskListConfig) {
+	c.AriaLabel = true
+}
+
+// WithTaskCheckBoxAriaLabels is a functional option that renders checkboxes
+// with 'aria-label' attributes like
+// '<input aria-label="foo" disabled="" type="checkbox">' for '- [ ] foo'.
+func WithTaskCheckBoxAriaLabels() TaskListOption {
+	return &withTaskCheckBoxAriaLabels{}
+}
+
+type withEnabledTaskCheckBoxes struct {
+}
+
+func (o *withEnabledTaskCheckBoxes) SetTaskListOption(c *TaskListConfig) {
+	c.Disabled = false
+}
+
+// WithEnabledTaskCheckBoxes is a functional option that renders checkboxes
+// without 'disabled' attributes, so users can check them.
+func WithEnabledTaskCheckBoxes() TaskListOption {
+	return &withEnabledTaskCheckBoxes{}
+}
+
+var taskListRegexp = regexp.MustCompile(`^\[([\sxX])\]\s+`)
+
+var attrNameClass = []byte("class")
+var taskListItemClass = []byte("task-list-item")
 
 type taskCheckBoxParser struct {
 }
@@ -43,6 +118,10 @@ func (s *taskCheckBoxParser) Parse(parent gast.Node, block text.Reader, pc parse
 	if _, ok := parent.Parent().(*gast.ListItem); !ok {
 		return nil
 	}
+	// checkboxes must be placed at the beginning of the list item.
+	if parent.HasChildren() {
+		return nil
+	}
 	line, _ := block.PeekLine()
 	m := taskListRegexp.FindSubmatchIndex(line)
 	if m == nil {
@@ -51,6 +130,7 @@ func (s *taskCheckBoxParser) Parse(parent gast.Node, block text.Reader, pc parse
 	value := line[m[2]:m[3]][0]
 	block.Advance(m[1])
 	checked := value == 'x' || value == 'X'
+	parent.Parent().SetAttribute(attrNameClass, taskListItemClass)
 	return ast.NewTaskCheckBox(checked)
 }
 
@@ -62,15 +142,20 @@ func (s *taskCheckBoxParser) CloseBlock(parent gast.Node, pc parser.Context) {
 // renders checkboxes in list items.
 type TaskCheckBoxHTMLRenderer struct {
 	html.Config
+	TaskListConfig
 }
 
 // NewTaskCheckBoxHTMLRenderer returns a new TaskCheckBoxHTMLRenderer.
-func NewTaskCheckBoxHTMLRenderer(opts ...html.Option) renderer.NodeRenderer {
+func NewTaskCheckBoxHTMLRenderer(opts ...TaskListOption) renderer.NodeRenderer {
 	r := &TaskCheckBoxHTMLRenderer{
-		Config: html.NewConfig(),
+		Config:         html.NewConfig(),
+		TaskListConfig: NewTaskListConfig(),
 	}
 	for _, opt := range opts {
-		opt.SetHTMLOption(&r.Config)
+		opt.SetTaskListOption(&r.TaskListConfig)
+		if ho, ok := opt.(html.Option); ok {
+			ho.SetHTMLOption(&r.Config)
+		}
 	}
 	return r
 }
@@ -86,30 +171,57 @@ func (r *TaskCheckBoxHTMLRenderer) renderTaskCheckBox(w util.BufWriter, source [
 	}
 	n := node.(*ast.TaskCheckBox)
 
+	w.WriteString("<input")
+	if r.AriaLabel {
+		if label := taskCheckBoxLabel(n, source); len(label) != 0 {
+			w.WriteString(` aria-label="`)
+			w.Write(util.EscapeHTML(label))
+			w.WriteByte('"')
+		}
+	}
 	if n.IsChecked {
-		w.WriteString(`<input checked="" disabled="" type="checkbox"`)
-	} else {
-		w.WriteString(`<input disabled="" type="checkbox"`)
+		w.WriteString(` checked=""`)
 	}
-	if r.XHTML {
-		w.WriteString(" />")
-	} else {
-		w.WriteString(">")
+	if r.Disabled {
+		w.WriteString(` disabled=""`)
 	}
+	w.WriteString(` type="checkbox"`)
+	r.WriteVoidElementEnd(w)
 	return gast.WalkContinue, nil
 }
 
+// taskCheckBoxLabel returns a text that follows the given checkbox in the
+// same line.
+func taskCheckBoxLabel(n gast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	for c := n.NextSibling(); c != nil; c = c.NextSibling() {
+		buf.Write(c.Text(source))
+		if t, ok := c.(*gast.Text); ok && (t.SoftLineBreak() || t.HardLineBreak()) {
+			break
+		}
+	}
+	return util.TrimRightSpace(util.TrimLeftSpace(buf.Bytes()))
+}
+
 type taskList struct {
+	options []TaskListOption
 }
 
 // TaskList is an extension that allow you to use GFM task lists.
 var TaskList = &taskList{}
 
+// NewTaskList returns a new extension with given options.
+func NewTaskList(opts ...TaskListOption) goldmark.Extender {
+	return &taskList{
+		options: opts,
+	}
+}
+
 func (e *taskList) Extend(m goldmark.Markdown) {
 	m.Parser().AddOptions(parser.WithInlineParsers(
 		util.Prioritized(NewTaskCheckBoxParser(), 0),
 	))
 	m.Renderer().AddOptions(renderer.WithNodeRenderers(
-		util.Prioritized(NewTaskCheckBoxHTMLRenderer(), 500),
+		util.Prioritized(NewTaskCheckBoxHTMLRenderer(e.options...), 500),
 	))
 }
@@ -2,7 +2,9 @@ package extension
 
 import (
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
 	"testing"
 )
 
@@ -17,3 +19,56 @@ func TestTaskList(t *testing.T) {
 	)
 	goldmark.DoTestCaseFile(markdown, "_test/tasklist.txt", t)
 }
+
+func TestTaskListOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewTaskList(
+				WithTaskCheckBoxAriaLabels(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "- [ ] buy *milk* & \"eggs\"\n  later\n- [x] \n  done",
+			Expected: "<ul>\n<li class=\"task-list-item\"><input aria-label=\"buy milk &amp; &quot;eggs&quot;\" disabled=\"\" type=\"checkbox\">buy <em>milk</em> &amp; &quot;eggs&quot;\nlater</li>\n<li class=\"task-list-item\"><input aria-label=\"done\" checked=\"\" disabled=\"\" type=\"checkbox\">done</li>\n</ul>",
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewTaskList(
+				WithEnabledTaskCheckBoxes(),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "- [ ] foo\n- [x] bar",
+			Expected: "<ul>\n<li class=\"task-list-item\"><input type=\"checkbox\">foo</li>\n<li class=\"task-list-item\"><input checked=\"\" type=\"checkbox\">bar</li>\n</ul>",
+		},
+	}, t)
+}
+
+func TestTaskListHTMLOptions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			TaskList,
+		),
+	)
+	markdown.SetRenderer(renderer.NewRenderer(renderer.WithNodeRenderers(
+		util.Prioritized(html.NewRenderer(), 1000),
+		util.Prioritized(NewTaskCheckBoxHTMLRenderer(
+			WithTaskListHTMLOptions(html.WithXHTML()),
+		), 500),
+	)))
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "- [x] foo",
+			Expected: "<ul>\n<li class=\"task-list-item\"><input checked=\"\" disabled=\"\" type=\"checkbox\" />foo</li>\n</ul>",
+		},
+	}, t)
+}
@@ -0,0 +1,197 @@
+package extension
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	gast "github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var tocMarker = []byte("[TOC]")
+
+// A TOCConfig struct is a data structure that holds configuration of the
+// TOC extension.
+type TOCConfig struct {
+	// MinLevel is a minimum heading level included in a table of contents.
+	MinLevel int
+
+	// MaxLevel is a maximum heading level included in a table of contents.
+	MaxLevel int
+}
+
+// NewTOCConfig returns a new TOCConfig with defaults.
+func NewTOCConfig() TOCConfig {
+	return TOCConfig{
+		MinLevel: 1,
+		MaxLevel: 6,
+	}
+}
+
+// A TOCOption interface sets options for the TOC extension.
+type TOCOption interface {
+	SetTOCOption(*TOCConfig)
+}
+
+type withTOCMinLevel struct {
+	value int
+}
+
+func (o *withTOCMinLevel) SetTOCOption(c *TOCConfig) {
+	c.MinLevel = o.value
+}
+
+// WithTOCMinLevel is a functional option that excludes headings whose level
+// is less than the given level from a table of contents.
+func WithTOCMinLevel(level int) TOCOption {
+	return &withTOCMinLevel{level}
+}
+
+type withTOCMaxLevel struct {
+	value int
+}
+
+func (o *withTOCMaxLevel) SetTOCOption(c *TOCConfig) {
+	c.MaxLevel = o.value
+}
+
+// WithTOCMaxLevel is a functional option that excludes headings whose level
+// is greater than the given level from a table of contents.
+func WithTOCMaxLevel(level int) TOCOption {
+	return &withTOCMaxLevel{level}
+}
+
+// NewTOCList returns a nested list that represents a table of contents of
+// the given document. Each item links to an id of the heading, so headings
+// should have ids(see parser.WithAutoHeadingID). Headings without ids are
+// listed as plain texts.
+// NewTOCList returns nil if the document has no headings.
+func NewTOCList(doc gast.Node, source []byte, opts ...TOCOption) *gast.List {
+	config := NewTOCConfig()
+	for _, opt := range opts {
+		opt.SetTOCOption(&config)
+	}
+	return newTOCList(doc, source, &config)
+}
+
+type tocLevel struct {
+	list  *gast.List
+	level int
+}
+
+func newTOCList(doc gast.Node, source []byte, config *TOCConfig) *gast.List {
+	var stack []tocLevel
+	_ = gast.Walk(doc, func(n gast.Node, entering bool) (gast.WalkStatus, error) {
+		if !entering {
+			return gast.WalkContinue, nil
+		}
+		heading, ok := n.(*gast.Heading)
+		if !ok {
+			return gast.WalkContinue, nil
+		}
+		if heading.Level < config.MinLevel || heading.Level > config.MaxLevel {
+			return gast.WalkSkipChildren, nil
+		}
+		if len(stack) == 0 {
+			stack = append(stack, tocLevel{gast.NewList('-'), heading.Level})
+		}
+		for len(stack) > 1 && stack[len(stack)-1].level > heading.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if top := stack[len(stack)-1]; top.level < heading.Level {
+			parent := top.list.LastChild()
+			if parent == nil {
+				parent = gast.NewListItem(2)
+				top.list.AppendChild(top.list, parent)
+			}
+			list := gast.NewList('-')
+			parent.AppendChild(parent, list)
+			stack = append(stack, tocLevel{list, heading.Level})
+		}
+		list := stack[len(stack)-1].list
+		list.AppendChild(list, newTOCListItem(heading, source))
+		return gast.WalkSkipChildren, nil
+	})
+	if len(stack) == 0 {
+		return nil
+	}
+	return stack[0].list
+}
+
+func newTOCListItem(heading *gast.Heading, source []byte) *gast.ListItem {
+	item := gast.NewListItem(2)
+	textBlock := gast.NewTextBlock()
+	item.AppendChild(item, textBlock)
+	var container gast.Node = textBlock
+	if id, ok := heading.AttributeString("id"); ok {
+		link := gast.NewLink()
+		link.Destination = append([]byte("#"), id...)
+		textBlock.AppendChild(textBlock, link)
+		container = link
+	}
+	container.AppendChild(container, gast.NewString(gast.PlainText(heading, source)))
+	return item
+}
+
+type tocASTTransformer struct {
+	TOCConfig
+}
+
+// NewTOCASTTransformer returns a new parser.ASTTransformer that replaces
+// paragraphs that consist of only '[TOC]' with a table of contents.
+func NewTOCASTTransformer(opts ...TOCOption) parser.ASTTransformer {
+	t := &tocASTTransformer{
+		TOCConfig: NewTOCConfig(),
+	}
+	for _, opt := range opts {
+		opt.SetTOCOption(&t.TOCConfig)
+	}
+	return t
+}
+
+func (a *tocASTTransformer) Transform(node *gast.Document, reader text.Reader, pc parser.Context) {
+	var markers []gast.Node
+	source := reader.Source()
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.Kind() != gast.KindParagraph || c.Lines().Len() != 1 {
+			continue
+		}
+		line := c.Lines().At(0)
+		if bytes.Equal(util.TrimRightSpace(line.Value(source)), tocMarker) {
+			markers = append(markers, c)
+		}
+	}
+	if len(markers) == 0 {
+		return
+	}
+	for _, marker := range markers {
+		list := newTOCList(node, source, &a.TOCConfig)
+		if list == nil {
+			node.RemoveChild(node, marker)
+			continue
+		}
+		node.ReplaceChild(node, marker, list)
+	}
+}
+
+type toc struct {
+	options []TOCOption
+}
+
+// TOC is an extension that replaces a '[TOC]' marker with a table of contents.
+var TOC = &toc{}
+
+// NewTOC returns a new Extender that replaces a '[TOC]' marker with a table
+// of contents.
+func NewTOC(opts ...TOCOption) goldmark.Extender {
+	return &toc{
+		options: opts,
+	}
+}
+
+func (e *toc) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithASTTransformers(
+		util.Prioritized(NewTOCASTTransformer(e.options...), 100),
+	))
+}
@@ -0,0 +1,81 @@
+package extension
+
+import (
+	"bytes"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"testing"
+)
+
+func TestTOC(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+		goldmark.WithExtensions(
+			TOC,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/toc.txt", t)
+}
+
+func TestTOCLevels(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+		goldmark.WithExtensions(
+			NewTOC(
+				WithTOCMinLevel(2),
+				WithTOCMaxLevel(3),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "# Title\n[TOC]\n## A\n### B\n#### C",
+			Expected: `<h1 id="title">Title</h1>
+<ul>
+<li><a href="#a">A</a>
+<ul>
+<li><a href="#b">B</a></li>
+</ul>
+</li>
+</ul>
+<h2 id="a">A</h2>
+<h3 id="b">B</h3>
+<h4 id="c">C</h4>`,
+		},
+	}, t)
+}
+
+func TestNewTOCList(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithRendererOptions(
+			html.WithXHTML(),
+		),
+	)
+	source := []byte("# Title\n\n## *Emphasis* and `code`\n\n### Sub")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	list := NewTOCList(doc, source, WithTOCMinLevel(2))
+	if list == nil {
+		t.Fatal("NewTOCList returned nil")
+	}
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, list); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<ul>\n<li>Emphasis and code\n<ul>\n<li>Sub</li>\n</ul>\n</li>\n</ul>\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, but got %q", expected, buf.String())
+	}
+
+	source = []byte("no headings")
+	doc = markdown.Parser().Parse(text.NewReader(source))
+	if list := NewTOCList(doc, source); list != nil {
+		t.Error("NewTOCList should return nil for a document without headings")
+	}
+}
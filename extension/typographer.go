@@ -1,6 +1,9 @@
 package extension
 
 import (
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/yuin/goldmark"
 	gast "github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension/ast"
@@ -34,6 +37,8 @@ const (
 	LeftAngleQuote
 	// RightAngleQuote is >>
 	RightAngleQuote
+	// Apostrophe is ' in contractions like don't
+	Apostrophe
 
 	typographicPunctuationMax
 )
@@ -55,6 +60,7 @@ func newDefaultSubstitutions() [][]byte {
 	replacements[Ellipsis] = []byte("&hellip;")
 	replacements[LeftAngleQuote] = []byte("&laquo;")
 	replacements[RightAngleQuote] = []byte("&raquo;")
+	replacements[Apostrophe] = []byte("&rsquo;")
 
 	return replacements
 }
@@ -187,6 +193,15 @@ func (s *typographerParser) Parse(parent gast.Node, block text.Reader, pc parser
 			return nil
 		}
 		if c == '\'' {
+			if s.Substitutions[Apostrophe] != nil && d.CanOpen && d.CanClose &&
+				isTypographerAlphaNumeric(before) && len(line) > 1 {
+				after, _ := utf8.DecodeRune(line[1:])
+				if isTypographerAlphaNumeric(after) {
+					node := ast.NewTypographicText(s.Substitutions[Apostrophe])
+					block.Advance(1)
+					return node
+				}
+			}
 			if s.Substitutions[LeftSingleQuote] != nil && d.CanOpen && !d.CanClose {
 				node := ast.NewTypographicText(s.Substitutions[LeftSingleQuote])
 				block.Advance(1)
@@ -214,6 +229,10 @@ func (s *typographerParser) Parse(parent gast.Node, block text.Reader, pc parser
 	return nil
 }
 
+func isTypographerAlphaNumeric(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
 func (s *typographerParser) CloseBlock(parent gast.Node, pc parser.Context) {
 	// nothing to do
 }
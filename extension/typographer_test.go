@@ -17,3 +17,24 @@ func TestTypographer(t *testing.T) {
 	)
 	goldmark.DoTestCaseFile(markdown, "_test/typographer.txt", t)
 }
+
+func TestTypographerSubstitutions(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewTypographer(
+				WithTypographicSubstitutions(TypographicSubstitutions{
+					LeftDoubleQuote:  []byte("&bdquo;"),
+					RightDoubleQuote: []byte("&ldquo;"),
+					Apostrophe:       nil,
+				}),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: `Er sagt "Hallo" und geht's`,
+			Expected: `<p>Er sagt &bdquo;Hallo&ldquo; und geht's</p>`,
+		},
+	}, t)
+}
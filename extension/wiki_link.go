@@ -0,0 +1,171 @@
+package extension
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// A WikiLinkResolver function returns a link destination for the given
+// target of '[[Target]]' and whether the target exists.
+// If the destination is nil, the wiki link is rendered as a plain text.
+type WikiLinkResolver func(target []byte) (destination []byte, exists bool)
+
+// A WikiLinkConfig struct is a data structure that holds configuration of the
+// WikiLink extension.
+type WikiLinkConfig struct {
+	// Resolver resolves targets into link destinations.
+	Resolver WikiLinkResolver
+
+	// NewLinkClass is a class of links to targets that do not exist.
+	// If NewLinkClass is empty, no classes are added.
+	NewLinkClass []byte
+}
+
+// NewWikiLinkConfig returns a new WikiLinkConfig with defaults.
+func NewWikiLinkConfig() WikiLinkConfig {
+	return WikiLinkConfig{
+		Resolver:     defaultWikiLinkResolver,
+		NewLinkClass: []byte("new"),
+	}
+}
+
+// defaultWikiLinkResolver resolves 'Page Name' into 'Page_Name'.
+func defaultWikiLinkResolver(target []byte) ([]byte, bool) {
+	return bytes.Replace(target, []byte{' '}, []byte{'_'}, -1), true
+}
+
+// A WikiLinkOption interface sets options for the WikiLink extension.
+type WikiLinkOption interface {
+	SetWikiLinkOption(*WikiLinkConfig)
+}
+
+type withWikiLinks struct {
+	value WikiLinkResolver
+}
+
+func (o *withWikiLinks) SetWikiLinkOption(c *WikiLinkConfig) {
+	c.Resolver = o.value
+}
+
+// WithWikiLinks is a functional option that specifies a function that
+// resolves targets of wiki links into link destinations.
+// The default resolver replaces spaces with '_' and treats all targets
+// as existing pages.
+func WithWikiLinks(resolver WikiLinkResolver) WikiLinkOption {
+	return &withWikiLinks{resolver}
+}
+
+type withWikiLinkNewClass struct {
+	value []byte
+}
+
+func (o *withWikiLinkNewClass) SetWikiLinkOption(c *WikiLinkConfig) {
+	c.NewLinkClass = o.value
+}
+
+// WithWikiLinkNewClass is a functional option that specifies a class of
+// links to targets that do not exist. The default class is 'new'.
+func WithWikiLinkNewClass(class string) WikiLinkOption {
+	return &withWikiLinkNewClass{[]byte(class)}
+}
+
+type wikiLinkParser struct {
+	WikiLinkConfig
+}
+
+// NewWikiLinkParser returns a new InlineParser that parses
+// '[[Target]]' and '[[Target|Label]]' as links.
+func NewWikiLinkParser(opts ...WikiLinkOption) parser.InlineParser {
+	p := &wikiLinkParser{
+		WikiLinkConfig: NewWikiLinkConfig(),
+	}
+	for _, o := range opts {
+		o.SetWikiLinkOption(&p.WikiLinkConfig)
+	}
+	return p
+}
+
+func (s *wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (s *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if len(line) < 4 || line[1] != '[' {
+		return nil
+	}
+	pipe := -1
+	stop := -1
+	for i := 2; i < len(line)-1; i++ {
+		c := line[i]
+		if c == '\\' && util.IsPunct(line[i+1]) {
+			i++
+			continue
+		}
+		if c == '|' && pipe < 0 {
+			pipe = i
+		} else if c == ']' && line[i+1] == ']' {
+			stop = i
+			break
+		} else if c == '[' || c == ']' || c == '\n' || c == '\r' {
+			return nil
+		}
+	}
+	if stop < 0 {
+		return nil
+	}
+	targetStop := stop
+	if pipe > -1 {
+		targetStop = pipe
+	}
+	target := util.TrimRightSpace(util.TrimLeftSpace(line[2:targetStop]))
+	if len(target) == 0 {
+		return nil
+	}
+	destination, exists := s.Resolver(util.UnescapePunctuations(target))
+	if destination == nil {
+		return nil
+	}
+	labelStart, labelStop := 2, targetStop
+	if pipe > -1 && !util.IsBlank(line[pipe+1:stop]) {
+		labelStart, labelStop = pipe+1, stop
+	}
+	label := text.NewSegment(segment.Start+labelStart, segment.Start+labelStop)
+	label = label.TrimLeftSpace(block.Source())
+	label = label.TrimRightSpace(block.Source())
+	block.Advance(stop + 2)
+	link := ast.NewLink()
+	link.Destination = destination
+	if !exists && len(s.NewLinkClass) != 0 {
+		link.SetAttribute([]byte("class"), s.NewLinkClass)
+	}
+	link.AppendChild(link, ast.NewTextSegment(label))
+	return link
+}
+
+type wikiLink struct {
+	options []WikiLinkOption
+}
+
+// WikiLink is an extension that converts '[[Target]]' and
+// '[[Target|Label]]' into links.
+var WikiLink = &wikiLink{}
+
+// NewWikiLink returns a new Extender that converts '[[Target]]' and
+// '[[Target|Label]]' into links.
+func NewWikiLink(opts ...WikiLinkOption) goldmark.Extender {
+	return &wikiLink{
+		options: opts,
+	}
+}
+
+func (e *wikiLink) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(parser.WithInlineParsers(
+		util.Prioritized(NewWikiLinkParser(e.options...), 102),
+	))
+}
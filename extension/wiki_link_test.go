@@ -0,0 +1,56 @@
+package extension
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestWikiLink(t *testing.T) {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			WikiLink,
+		),
+	)
+	goldmark.DoTestCaseFile(markdown, "_test/wiki_link.txt", t)
+}
+
+func TestWikiLinkOptions(t *testing.T) {
+	pages := map[string]bool{"Home": true}
+	resolver := func(target []byte) ([]byte, bool) {
+		if string(target) == "Private" {
+			return nil, false
+		}
+		return append([]byte("/wiki/"), target...), pages[string(target)]
+	}
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			NewWikiLink(
+				WithWikiLinks(resolver),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "[[Home]] [[Missing|missing page]] [[Private]]",
+			Expected: `<p><a href="/wiki/Home">Home</a> <a href="/wiki/Missing" class="new">missing page</a> [[Private]]</p>`,
+		},
+	}, t)
+
+	markdown = goldmark.New(
+		goldmark.WithExtensions(
+			NewWikiLink(
+				WithWikiLinks(resolver),
+				WithWikiLinkNewClass(""),
+			),
+		),
+	)
+	goldmark.DoTestCases(markdown, []goldmark.MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "[[Missing]]",
+			Expected: `<p><a href="/wiki/Missing">Missing</a></p>`,
+		},
+	}, t)
+}
@@ -2,6 +2,8 @@
 package goldmark
 
 import (
+	"bytes"
+
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/renderer/html"
@@ -27,28 +29,58 @@ var defaultMarkdown = New()
 
 // Convert interprets a UTF-8 bytes source in Markdown and
 // write rendered contents to a writer w.
+// w does not need to be buffered. Rendered contents are buffered and flushed
+// before Convert returns.
 func Convert(source []byte, w io.Writer, opts ...parser.ParseOption) error {
 	return defaultMarkdown.Convert(source, w, opts...)
 }
 
+// ConvertString interprets a UTF-8 string source in Markdown and returns
+// rendered contents.
+func ConvertString(source string, opts ...parser.ParseOption) (string, error) {
+	return defaultMarkdown.ConvertString(source, opts...)
+}
+
 // A Markdown interface offers functions to convert Markdown text to
 // a desired format.
 type Markdown interface {
 	// Convert interprets a UTF-8 bytes source in Markdown and write rendered
 	// contents to a writer w.
+	// Convert is safe for concurrent use by multiple goroutines. Each call
+	// has its own parser context and writer, and the parser and the renderer
+	// are configured only once.
+	// A leading UTF-8 BOM is skipped and '\r\n' and '\r' are treated as
+	// '\n'. The source is not modified, so that source positions in the AST
+	// are positions in the given source.
 	Convert(source []byte, writer io.Writer, opts ...parser.ParseOption) error
 
+	// ConvertString is same as Convert except that it takes a string and
+	// returns rendered contents as a string.
+	ConvertString(source string, opts ...parser.ParseOption) (string, error)
+
 	// Parser returns a Parser that will be used for conversion.
+	// You can use the Parser to get an AST without rendering it.
 	Parser() parser.Parser
 
 	// SetParser sets a Parser to this object.
 	SetParser(parser.Parser)
 
-	// Parser returns a Renderer that will be used for conversion.
+	// Renderer returns a Renderer that will be used for conversion.
+	// You can use the Renderer to render an AST that is parsed by the
+	// Parser. An AST can be rendered multiple times.
 	Renderer() renderer.Renderer
 
 	// SetRenderer sets a Renderer to this object.
 	SetRenderer(renderer.Renderer)
+
+	// AddOptions applies the given options to this object after it is
+	// created by New. Added extensions are applied to the current parser
+	// and renderer. The parser and the renderer are reconfigured on the
+	// next conversion.
+	//
+	// AddOptions must not be called concurrently with Convert. Convert can
+	// be called concurrently once all options are added.
+	AddOptions(...Option)
 }
 
 // Option is a functional option type for Markdown objects.
@@ -111,12 +143,30 @@ func New(options ...Option) Markdown {
 	return md
 }
 
+func (m *markdown) AddOptions(opts ...Option) {
+	l := len(m.extensions)
+	for _, opt := range opts {
+		opt(m)
+	}
+	for _, e := range m.extensions[l:] {
+		e.Extend(m)
+	}
+}
+
 func (m *markdown) Convert(source []byte, writer io.Writer, opts ...parser.ParseOption) error {
 	reader := text.NewReader(source)
 	doc := m.parser.Parse(reader, opts...)
 	return m.renderer.Render(writer, source, doc)
 }
 
+func (m *markdown) ConvertString(source string, opts ...parser.ParseOption) (string, error) {
+	var buf bytes.Buffer
+	if err := m.Convert([]byte(source), &buf, opts...); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func (m *markdown) Parser() parser.Parser {
 	return m.parser
 }
@@ -1,8 +1,23 @@
 package goldmark
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/renderer/json"
+	mdrenderer "github.com/yuin/goldmark/renderer/markdown"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"testing"
+	"time"
 )
 
 func TestAttributeAndAutoHeadingID(t *testing.T) {
@@ -14,3 +29,2082 @@ func TestAttributeAndAutoHeadingID(t *testing.T) {
 	)
 	DoTestCaseFile(markdown, "_test/options.txt", t)
 }
+
+func TestHeadingAnchors(t *testing.T) {
+	markdown := New(
+		WithParserOptions(
+			parser.WithAttribute(),
+			parser.WithAutoHeadingID(),
+		),
+		WithRendererOptions(
+			html.WithHeadingAnchors("&para;"),
+		),
+	)
+	DoTestCaseFile(markdown, "_test/heading_anchors.txt", t)
+}
+
+func TestSafeByDefault(t *testing.T) {
+	markdown := New()
+	DoTestCaseFile(markdown, "_test/safe.txt", t)
+}
+
+type testIDs struct {
+	count int
+}
+
+func (s *testIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	s.count++
+	return []byte(fmt.Sprintf("%s-%d", strings.ToLower(kind.String()), s.count))
+}
+
+func (s *testIDs) Put(value []byte) {
+}
+
+func TestCustomIDs(t *testing.T) {
+	markdown := New(
+		WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+	)
+	var buf bytes.Buffer
+	ctx := parser.NewContext(parser.WithIDs(&testIDs{}))
+	if err := markdown.Convert([]byte("# Foo\n\n## Bar\n"), &buf, parser.WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<h1 id=\"heading-1\">Foo</h1>\n<h2 id=\"heading-2\">Bar</h2>\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, but got %q", expected, buf.String())
+	}
+}
+
+func TestExternalLinkTarget(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithExternalLinkTarget(),
+		),
+	)
+	DoTestCaseFile(markdown, "_test/external_links.txt", t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithExternalLinkTarget(),
+			html.WithExternalLinkRel("nofollow"),
+			html.WithExternalLinkMatcher(func(destination []byte) bool {
+				return html.IsExternalURL(destination) &&
+					!bytes.HasPrefix(destination, []byte("https://example.com/"))
+			}),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "[a](https://example.com/foo) [b](https://example.org/)",
+			Expected: `<p><a href="https://example.com/foo">a</a> <a href="https://example.org/" target="_blank" rel="nofollow">b</a></p>`,
+		},
+	}, t)
+}
+
+func TestImageLoadingLazy(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithImageLoadingLazy(),
+			html.WithImageDimensions(),
+		),
+	)
+	DoTestCaseFile(markdown, "_test/images.txt", t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithXHTML(),
+			html.WithImageDimensions(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: `![foo](/a.png "=200x100")`,
+			Expected: `<p><img src="/a.png" alt="foo" width="200" height="100" /></p>`,
+		},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: `![foo](/a.png "title =200x100") ![bar](</b.png =64x64>)`,
+			Expected: `<p><img src="/a.png" alt="foo" title="title =200x100"> <img src="/b.png%20=64x64" alt="bar"></p>`,
+		},
+	}, t)
+}
+
+func TestJSONRenderer(t *testing.T) {
+	markdown := New(
+		WithRenderer(renderer.NewRenderer(
+			renderer.WithNodeRenderers(util.Prioritized(json.NewRenderer(), 1000)),
+		)),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "# Foo\n\n[a](/b)",
+			Expected: `{"kind":"Document","type":"document","children":[{"kind":"Heading","type":"block","properties":{"level":1},"children":[{"kind":"Text","type":"inline","text":"Foo"}]},{"kind":"Paragraph","type":"block","children":[{"kind":"Link","type":"inline","properties":{"destination":"/b"},"children":[{"kind":"Text","type":"inline","text":"a"}]}]}]}`,
+		},
+	}, t)
+
+	markdown = New(
+		WithRenderer(renderer.NewRenderer(
+			renderer.WithNodeRenderers(util.Prioritized(json.NewRenderer(), 1000)),
+		)),
+		WithRendererOptions(json.WithIndent("  ")),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "    <code>\n",
+			Expected: `{
+  "kind": "Document",
+  "type": "document",
+  "children": [
+    {
+      "kind": "CodeBlock",
+      "type": "block",
+      "text": "<code>\n"
+    }
+  ]
+}`,
+		},
+	}, t)
+}
+
+func TestMarkdownRenderer(t *testing.T) {
+	markdown := New(
+		WithRenderer(renderer.NewRenderer(
+			renderer.WithNodeRenderers(util.Prioritized(mdrenderer.NewRenderer(), 1000)),
+		)),
+		WithRendererOptions(
+			mdrenderer.WithEmphasisMarker('_'),
+			mdrenderer.WithBulletMarker('*'),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "Foo\n===\n\n*a* foo*bar* **b**\n\n[c][d]\n\n[d]: /url 'title'\n",
+			Expected: "# Foo\n\n_a_ foo*bar* __b__\n\n[c](/url \"title\")",
+		},
+		{
+			No:       2,
+			Markdown: "- a\n- b\n+ c\n\n      code\n1) d\n",
+			Expected: "* a\n* b\n\n- c\n\n  ```\n  code\n  ```\n\n1) d",
+		},
+		{
+			No:       3,
+			Markdown: "> foo\nbar\n> # baz\n",
+			Expected: "> foo\n> bar\n>\n> # baz",
+		},
+		{
+			No:       4,
+			Markdown: "[![alt](img.png)](page.html)\n",
+			Expected: "[![alt](img.png)](page.html)",
+		},
+	}, t)
+}
+
+func TestFencedCodeBlockInfo(t *testing.T) {
+	cases := []struct {
+		source   string
+		language string
+		meta     string
+	}{
+		{"```go {highlight:1-3}  \nx\n```\n", "go", "{highlight:1-3}"},
+		{"```  go\nx\n```\n", "go", ""},
+		{"```\t\nx\n```\n", "", ""},
+		{"```\nx\n```\n", "", ""},
+	}
+	markdown := New()
+	for i, c := range cases {
+		source := []byte(c.source)
+		doc := markdown.Parser().Parse(text.NewReader(source))
+		n := doc.FirstChild().(*ast.FencedCodeBlock)
+		if language := string(n.Language(source)); language != c.language {
+			t.Errorf("%d: expected language %q, but got %q", i, c.language, language)
+		}
+		if meta := string(n.Meta(source)); meta != c.meta {
+			t.Errorf("%d: expected meta %q, but got %q", i, c.meta, meta)
+		}
+	}
+}
+
+func TestHeadingLevelOffset(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithHeadingLevelOffset(1),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "# a\n\n###### b\n",
+			Expected: "<h2>a</h2>\n<h6>b</h6>",
+		},
+	}, t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithHeadingLevelOffset(-2),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "# a\n\n### b\n",
+			Expected: "<h1>a</h1>\n<h1>b</h1>",
+		},
+	}, t)
+}
+
+func TestFigures(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithFigures(),
+		),
+	)
+	DoTestCaseFile(markdown, "_test/figures.txt", t)
+}
+
+func TestBlockAttributes(t *testing.T) {
+	markdown := New(
+		WithParserOptions(
+			parser.WithAttribute(),
+		),
+	)
+	DoTestCaseFile(markdown, "_test/block_attributes.txt", t)
+}
+
+func TestWithoutHardLineBreaks(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithoutHardLineBreaks(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "foo  \nbar\\\nbaz\nqux",
+			Expected: "<p>foo\nbar\nbaz\nqux</p>",
+		},
+	}, t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithoutHardLineBreaks(),
+			html.WithHardWraps(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "foo  \nbar",
+			Expected: "<p>foo<br>\nbar</p>",
+		},
+	}, t)
+}
+
+func TestURLSanitizer(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithURLSanitizer(func(url []byte, isImage bool) []byte {
+				if html.IsDangerousURL(url) {
+					return nil
+				}
+				if isImage && bytes.HasPrefix(url, []byte("http://")) {
+					return append([]byte("https://"), url[7:]...)
+				}
+				return url
+			}),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "[a](http://example.com/) ![b](http://example.com/b.png)",
+			Expected: `<p><a href="http://example.com/">a</a> <img src="https://example.com/b.png" alt="b"></p>`,
+		},
+		{
+			No:       2,
+			Markdown: "[a](javascript:alert(1))",
+			Expected: `<p><a href="">a</a></p>`,
+		},
+	}, t)
+}
+
+func TestCountWords(t *testing.T) {
+	markdown := New()
+	cases := []struct {
+		source   string
+		expected int
+	}{
+		{"Hello, world!", 2},
+		{"foo*bar* don't well-known\nnext  \nline", 5},
+		{"# Title\n\nsome `code span` here\n\n```\nfenced code\n```\n\n<div>raw</div>", 3},
+		{"これは日本語です", 8},
+		{"한국어 문장 abc", 3},
+	}
+	for i, c := range cases {
+		source := []byte(c.source)
+		doc := markdown.Parser().Parse(text.NewReader(source))
+		if actual := ast.CountWords(doc, source); actual != c.expected {
+			t.Errorf("%d: expected %d words, but got %d", i+1, c.expected, actual)
+		}
+	}
+
+	source := []byte(strings.Repeat("word ", 400))
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	if actual := ast.ReadingTime(doc, source, 0); actual != 2*time.Minute {
+		t.Errorf("expected 2m0s, but got %s", actual)
+	}
+}
+
+type countingWriter struct {
+	writes  int
+	size    int
+	offsets map[int]bool
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	w.size += len(p)
+	if w.offsets != nil {
+		w.offsets[w.size] = true
+	}
+	return len(p), nil
+}
+
+func TestFlushEachBlock(t *testing.T) {
+	blocks := 20
+	source := []byte(strings.Repeat("paragraph\n\n- item\n\n", blocks/2))
+	markdown := New()
+	var w countingWriter
+	if err := markdown.Convert(source, &w); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes >= blocks {
+		t.Errorf("expected fewer writes than blocks without flushing, but got %d", w.writes)
+	}
+	expectedSize := w.size
+
+	markdown = New(
+		WithRendererOptions(
+			renderer.WithFlushEachBlock(),
+		),
+	)
+	w = countingWriter{offsets: map[int]bool{}}
+	if err := markdown.Convert(source, &w); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes < blocks {
+		t.Errorf("expected at least %d writes, but got %d", blocks, w.writes)
+	}
+	if w.size != expectedSize {
+		t.Errorf("expected %d bytes, but got %d", expectedSize, w.size)
+	}
+	// outputs of each block are written before the next block is rendered.
+	offset := 0
+	for i := 0; i < blocks/2; i++ {
+		for _, output := range []string{"<p>paragraph</p>\n", "<ul>\n<li>item</li>\n</ul>\n"} {
+			offset += len(output)
+			if !w.offsets[offset] {
+				t.Errorf("expected a write that ends at %d", offset)
+			}
+		}
+	}
+}
+
+func TestFlushEachBlockAllocations(t *testing.T) {
+	block := "# heading\n\nparagraph with *emphasis* and `code`.\n\n- item 1\n- item 2\n\n"
+	markdown := New(
+		WithRendererOptions(
+			renderer.WithFlushEachBlock(),
+		),
+	)
+	allocs := func(blocks int) float64 {
+		source := []byte(strings.Repeat(block, blocks))
+		doc := markdown.Parser().Parse(text.NewReader(source))
+		return testing.AllocsPerRun(10, func() {
+			if err := markdown.Renderer().Render(ioutil.Discard, source, doc); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	// rendering does not hold outputs, but parsing needs the whole source.
+	small, large := allocs(10), allocs(10000)
+	if large > small {
+		t.Errorf("expected constant allocations, but got %v for 10 blocks and %v for 10000 blocks", small, large)
+	}
+}
+
+// BenchmarkFlushEachBlock benchmarks rendering a 50MB document. Parsing is
+// not included because the parser needs the whole source in memory.
+func BenchmarkFlushEachBlock(b *testing.B) {
+	block := "# heading\n\nparagraph with *emphasis* and `code`.\n\n- item 1\n- item 2\n\n"
+	source := []byte(strings.Repeat(block, 50*1024*1024/len(block)))
+	markdown := New(
+		WithRendererOptions(
+			renderer.WithFlushEachBlock(),
+		),
+	)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := markdown.Renderer().Render(ioutil.Discard, source, doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRenderProse(b *testing.B) {
+	paragraph := "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor " +
+		"incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud " +
+		"exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat.\n" +
+		"Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat " +
+		"nulla pariatur. Excepteur sint occaecat cupidatat non proident, sunt in culpa qui " +
+		"officia deserunt mollit anim id est laborum.\n\n"
+	source := []byte(strings.Repeat(paragraph, 100))
+	markdown := New()
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := markdown.Renderer().Render(ioutil.Discard, source, doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDefaultWriter(t *testing.T) {
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "plain text without special characters",
+			Expected: "<p>plain text without special characters</p>",
+		},
+		{
+			No:       2,
+			Markdown: `&#x26; &#38; &amp; \* "<>"`,
+			Expected: "<p>&amp; &amp; &amp; * &quot;&lt;&gt;&quot;</p>",
+		},
+		{
+			No:       3,
+			Markdown: "foo &#",
+			Expected: "<p>foo &amp;#</p>",
+		},
+	}, t)
+}
+
+func TestEscapeHTML(t *testing.T) {
+	all := make([]byte, 0, 256*2)
+	for i := 0; i < 256; i++ {
+		all = append(all, 'a', byte(i))
+	}
+	expected := []byte{}
+	for _, c := range all {
+		if escaped := util.EscapeHTMLByte(c); escaped != nil {
+			expected = append(expected, escaped...)
+		} else {
+			expected = append(expected, c)
+		}
+	}
+	if v := util.EscapeHTML(all); !bytes.Equal(v, expected) {
+		t.Errorf("expected %q, but got %q", expected, v)
+	}
+	for i := 0; i < 256; i++ {
+		v := util.EscapeHTML([]byte{byte(i)})
+		if e := util.EscapeHTMLByte(byte(i)); e != nil && !bytes.Equal(v, e) {
+			t.Errorf("%d: expected %q, but got %q", i, e, v)
+		} else if e == nil && !bytes.Equal(v, []byte{byte(i)}) {
+			t.Errorf("%d: expected %q, but got %q", i, []byte{byte(i)}, v)
+		}
+	}
+	for _, v := range []string{"", "plain", "<<", "a&b\"c>"} {
+		expected := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace(v)
+		if r := string(util.EscapeHTML([]byte(v))); r != expected {
+			t.Errorf("expected %q, but got %q", expected, r)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	html.DefaultWriter.RawWrite(w, all)
+	w.Flush()
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("expected %q, but got %q", expected, buf.Bytes())
+	}
+}
+
+func BenchmarkEscapeHTML(b *testing.B) {
+	source := []byte(strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor. ", 1000))
+	b.SetBytes(int64(len(source)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		util.EscapeHTML(source)
+	}
+}
+
+func TestCodeBlockWrapper(t *testing.T) {
+	markdown := New(
+		WithParserOptions(
+			parser.WithAttribute(),
+		),
+		WithRendererOptions(
+			html.WithCodeBlockWrapper(html.CodeBlockWrapper{
+				LanguageClassOnPre: true,
+				Tag:                "div",
+				Class:              "highlight",
+			}),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "```go\nfunc main() {}\n```",
+			Expected: "<div class=\"highlight\">\n<pre class=\"language-go\"><code>func main() {}\n</code></pre>\n</div>",
+		},
+		{
+			No:       2,
+			Markdown: "```go\nfunc main() {}\n```\n{#main .numbered data-line=1}",
+			Expected: "<div class=\"highlight\">\n<pre id=\"main\" class=\"language-go numbered\" data-line=\"1\"><code>func main() {}\n</code></pre>\n</div>",
+		},
+		{
+			No:       3,
+			Markdown: "```\nplain\n```",
+			Expected: "<div class=\"highlight\">\n<pre><code>plain\n</code></pre>\n</div>",
+		},
+	}, t)
+}
+
+func TestCodeLineNumbers(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithCodeLineNumbers(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "    a < b\n\n    c\n",
+			Expected: "<pre><code><span class=\"line\" data-line=\"1\">a &lt; b</span>\n<span class=\"line\" data-line=\"2\"></span>\n<span class=\"line\" data-line=\"3\">c</span>\n</code></pre>",
+		},
+		{
+			No:       2,
+			Markdown: "```go {start:10}\nif a && b {\n}\n```",
+			Expected: "<pre><code class=\"language-go\"><span class=\"line\" data-line=\"10\">if a &amp;&amp; b {</span>\n<span class=\"line\" data-line=\"11\">}</span>\n</code></pre>",
+		},
+		{
+			No:       3,
+			Markdown: "```go {restart:10}\nx\n```",
+			Expected: "<pre><code class=\"language-go\"><span class=\"line\" data-line=\"1\">x</span>\n</code></pre>",
+		},
+		{
+			No:       4,
+			Markdown: "```\nunclosed",
+			Expected: "<pre><code><span class=\"line\" data-line=\"1\">unclosed</span></code></pre>",
+		},
+	}, t)
+}
+
+func TestCodeBlockMetaAttributes(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithCodeBlockMetaAttributes(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "```go {data-foo=\"bar\" title='x.go' data-flag=on}\nx\n```",
+			Expected: "<pre><code class=\"language-go\" data-foo=\"bar\" title=\"x.go\" data-flag=\"on\">x\n</code></pre>",
+		},
+		{
+			No:       2,
+			Markdown: "```go {start:10, onclick=\"alert(1)\" style=x data-=y data-a=\"<&>\"}\nx\n```",
+			Expected: "<pre><code class=\"language-go\" data-a=\"&lt;&amp;&gt;\">x\n</code></pre>",
+		},
+		{
+			No:       3,
+			Markdown: "```go\nx\n```",
+			Expected: "<pre><code class=\"language-go\">x\n</code></pre>",
+		},
+	}, t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithCodeBlockMetaAttributes(),
+			html.WithCodeBlockWrapper(html.CodeBlockWrapper{
+				LanguageClassOnPre: true,
+			}),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       4,
+			Markdown: "```go {title=\"x.go\"}\nx\n```",
+			Expected: "<pre class=\"language-go\" title=\"x.go\"><code>x\n</code></pre>",
+		},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       5,
+			Markdown: "```go {title=\"x.go\"}\nx\n```",
+			Expected: "<pre><code class=\"language-go\">x\n</code></pre>",
+		},
+	}, t)
+}
+
+type testLinkRenderer struct {
+}
+
+func (r *testLinkRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindLink, r.renderLink)
+}
+
+func (r *testLinkRenderer) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Link)
+	if entering {
+		w.WriteString(`<a class="custom" href="`)
+		w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
+		w.WriteString(`">`)
+	} else {
+		w.WriteString("</a>")
+	}
+	return ast.WalkContinue, nil
+}
+
+func TestOverrideNodeRenderer(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			renderer.WithNodeRenderers(
+				util.Prioritized(&testLinkRenderer{}, 500),
+			),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "[**link**](/url) and ![image](/img.png)",
+			Expected: `<p><a class="custom" href="/url"><strong>link</strong></a> and <img src="/img.png" alt="image"></p>`,
+		},
+	}, t)
+}
+
+func TestCodeLanguagePrefix(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithCodeLanguagePrefix(""),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "```go {start:10}\nx\n```",
+			Expected: "<pre><code class=\"go\">x\n</code></pre>",
+		},
+	}, t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithCodeLanguagePrefix("lang-"),
+			html.WithCodeBlockWrapper(html.CodeBlockWrapper{
+				LanguageClassOnPre: true,
+			}),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       2,
+			Markdown: "```go\nx\n```",
+			Expected: "<pre class=\"lang-go\"><code>x\n</code></pre>",
+		},
+	}, t)
+}
+
+func TestEmailObfuscation(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithEmailObfuscation(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{
+			No:       1,
+			Markdown: "<a@b.c> <https://example.com>",
+			Expected: `<p><a href="&#109;&#97;&#105;&#108;&#116;&#111;&#58;&#97;&#64;&#98;&#46;&#99;">&#97;&#64;&#98;&#46;&#99;</a> <a href="https://example.com">https://example.com</a></p>`,
+		},
+	}, t)
+}
+
+func TestUnresolvedReferences(t *testing.T) {
+	source := []byte("[a][foo] [b][] [c] [d][bar]\n\n[bar]: /url\n")
+	var buf bytes.Buffer
+	ctx := parser.NewContext()
+	if err := New().Convert(source, &buf, parser.WithContext(ctx)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "<p>[a][foo] [b][] [c] <a href=\"/url\">d</a></p>\n" {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	refs := ctx.UnresolvedReferences()
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 unresolved references, but got %d", len(refs))
+	}
+	expected := []string{"[a][foo]:foo", "[b][]:b"}
+	for i, ref := range refs {
+		actual := fmt.Sprintf("%s:%s", ref.Segment.Value(source), ref.Label)
+		if actual != expected[i] {
+			t.Errorf("expected %s, but got %s", expected[i], actual)
+		}
+	}
+}
+
+func TestNodePosition(t *testing.T) {
+	source := []byte("# Title\n\n> 日本語 *emph*\n\n- a\n-   `code` <b>\n\n***\n")
+	doc := New().Parser().Parse(text.NewReader(source))
+	lines := text.NewLineTable(source)
+	var actual []string
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if offset := ast.StartOffset(n); offset > -1 {
+			actual = append(actual, fmt.Sprintf("%s %s", n.Kind(), lines.Position(offset)))
+		} else {
+			actual = append(actual, fmt.Sprintf("%s -", n.Kind()))
+		}
+		return ast.WalkContinue, nil
+	})
+	expected := []string{
+		"Document 1:1",
+		"Heading 1:1",
+		"Text 1:3",
+		"Blockquote 3:1",
+		"Paragraph 3:3",
+		"Text 3:3",
+		"Emphasis 3:8",
+		"Text 3:8",
+		"List 5:1",
+		"ListItem 5:1",
+		"TextBlock 5:3",
+		"Text 5:3",
+		"ListItem 6:1",
+		"TextBlock 6:5",
+		"CodeSpan 6:6",
+		"Text 6:6",
+		"Text 6:11",
+		"RawHTML 6:12",
+		"ThemanticBreak 8:1",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected positions:\n%s", strings.Join(actual, "\n"))
+	}
+	if p, ok := ast.Position(doc.FirstChild(), source); !ok || p.Line != 1 || p.Column != 1 {
+		t.Errorf("unexpected position: %s", p)
+	}
+
+	// blocks without any contents and blocks replaced by others
+	source = []byte("```\n```\n\n  Title\n  ===\n\n[a]: /url\nb\n\n    code\n")
+	doc = New().Parser().Parse(text.NewReader(source))
+	lines = text.NewLineTable(source)
+	actual = actual[:0]
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		actual = append(actual, fmt.Sprintf("%s %s", c.Kind(), lines.Position(ast.StartOffset(c))))
+	}
+	expected = []string{
+		"FencedCodeBlock 1:1",
+		"Heading 4:3",
+		"Paragraph 8:1",
+		"CodeBlock 10:5",
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected positions:\n%s", strings.Join(actual, "\n"))
+	}
+
+	// only blocks hold positions where they are opened.
+	heading := doc.FirstChild().NextSibling()
+	if _, ok := heading.(ast.Positioner); !ok {
+		t.Error("blocks should implement ast.Positioner")
+	}
+	if _, ok := heading.FirstChild().(ast.Positioner); ok {
+		t.Error("inline nodes should not implement ast.Positioner")
+	}
+}
+
+func TestVoidElements(t *testing.T) {
+	source := []byte("a  \nb ![img](/a.png)\n\n***\n")
+	for _, c := range []struct {
+		options  []renderer.Option
+		expected string
+	}{
+		{nil, "<p>a<br>\nb <img src=\"/a.png\" alt=\"img\"></p>\n<hr>\n"},
+		{[]renderer.Option{html.WithXHTML()}, "<p>a<br />\nb <img src=\"/a.png\" alt=\"img\" /></p>\n<hr />\n"},
+	} {
+		var buf bytes.Buffer
+		markdown := New(WithRendererOptions(c.options...))
+		if err := markdown.Convert(source, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != c.expected {
+			t.Errorf("expected %q, but got %q", c.expected, buf.String())
+		}
+	}
+}
+
+func TestCompactOutput(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithCompactOutput()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "# Title\n\nfoo\nbar  \nbaz\n\n***\n", "<h1>Title</h1><p>foo\nbar<br>baz</p><hr>"},
+		{2, "> - a\n>   - b\n> - c\n", "<blockquote><ul><li>a<ul><li>b</li></ul></li><li>c</li></ul></blockquote>"},
+		{3, "```go\nfunc()\n\n```\n\n    code\n", "<pre><code class=\"language-go\">func()\n\n</code></pre><pre><code>code\n</code></pre>"},
+		{4, "1. a\n\n2. b\n", "<ol><li><p>a</p></li><li><p>b</p></li></ol>"},
+	}, t)
+}
+
+func TestCodeSpanNewLines(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithCodeSpanNewLines()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "`foo\nbar\nbaz`", "<p><code>foo\nbar\nbaz</code></p>"},
+		{2, "`` foo\nbar ``", "<p><code>foo\nbar</code></p>"},
+	}, t)
+}
+
+func TestTabWidth(t *testing.T) {
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "\tfoo\n", "<pre><code>foo\n</code></pre>"},
+		{2, "  \tfoo\n", "<pre><code>foo\n</code></pre>"},
+		{3, "1. a\n\n\tb\n", "<ol>\n<li>\n<p>a</p>\n<p>b</p>\n</li>\n</ol>"},
+		{4, "- a\n\n  \tb\n", "<ul>\n<li>\n<p>a</p>\n<p>b</p>\n</li>\n</ul>"},
+		{5, "> \tfoo\n", "<blockquote>\n<p>foo</p>\n</blockquote>"},
+	}, t)
+
+	markdown = New(WithParserOptions(parser.WithTabWidth(2)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "\tfoo\n", "<p>foo</p>"},
+		{2, "\t\tfoo\n", "<pre><code>foo\n</code></pre>"},
+		{3, " \t foo\n", "<p>foo</p>"},
+		{4, " \t  foo\n", "<pre><code>foo\n</code></pre>"},
+		{5, "1. a\n\n\tb\n", "<ol>\n<li>a</li>\n</ol>\n<p>b</p>"},
+		{6, "- a\n\t- b\n", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>"},
+		{7, "\t***\n", "<hr>"},
+	}, t)
+
+	markdown = New(WithParserOptions(parser.WithTabWidth(8)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "\tfoo\n", "<pre><code>    foo\n</code></pre>"},
+		{2, "  \tfoo\n", "<pre><code>    foo\n</code></pre>"},
+		{3, "1. a\n\n\tb\n", "<ol>\n<li>\n<p>a</p>\n<pre><code> b\n</code></pre>\n</li>\n</ol>"},
+		{4, "- a\n\n  \tb\n", "<ul>\n<li>\n<p>a</p>\n<pre><code>  b\n</code></pre>\n</li>\n</ul>"},
+		{5, "> \tfoo\n", "<blockquote>\n<pre><code>  foo\n</code></pre>\n</blockquote>"},
+		{6, ">\tfoo\n", "<blockquote>\n<pre><code>  foo\n</code></pre>\n</blockquote>"},
+	}, t)
+}
+
+func TestWithoutIndentedCodeBlocks(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithoutIndentedCodeBlocks()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "    foo\n    bar\n", "<p>foo\nbar</p>"},
+		{2, "text\n    continued\n", "<p>text\ncontinued</p>"},
+		{3, "\tfoo\n", "<p>foo</p>"},
+		{4, "    # not a heading\n    - not a list\n", "<p># not a heading\n- not a list</p>"},
+		{5, "- a\n\n      b\n", "<ul>\n<li>\n<p>a</p>\n<p>b</p>\n</li>\n</ul>"},
+		{6, "- a\n  - b\n\n    c\n", "<ul>\n<li>a\n<ul>\n<li>\n<p>b</p>\n<p>c</p>\n</li>\n</ul>\n</li>\n</ul>"},
+		{7, "> a\n>\n>     b\n", "<blockquote>\n<p>a</p>\n<p>b</p>\n</blockquote>"},
+		{8, "```\n    code\n```\n", "<pre><code>    code\n</code></pre>"},
+	}, t)
+}
+
+func TestMaxNestingDepth(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithMaxNestingDepth(3)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, ">>>> a\n", "<blockquote>\n<blockquote>\n<blockquote>\n<p>&gt; a</p>\n</blockquote>\n</blockquote>\n</blockquote>"},
+		{2, "> - > - a\n", "<blockquote>\n<ul>\n<li>&gt; - a</li>\n</ul>\n</blockquote>"},
+		{3, "- a\n  - b\n    - c\n", "<ul>\n<li>a\n<ul>\n<li>b\n- c</li>\n</ul>\n</li>\n</ul>"},
+	}, t)
+
+	sources := []string{
+		strings.Repeat(">", 100000) + " a\n",
+		strings.Repeat("> ", 50000) + "- a\n",
+		strings.Repeat("- ", 50000) + "a\n",
+		strings.Repeat(">", 1000) + "\n" + strings.Repeat("> ", 1000) + "a\n",
+	}
+	markdown = New(WithParserOptions(parser.WithMaxNestingDepth(100)))
+	for i, source := range sources {
+		doc := markdown.Parser().Parse(text.NewReader([]byte(source)))
+		maxDepth := 0
+		depth := 0
+		_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if n.Type() != ast.TypeBlock {
+				return ast.WalkContinue, nil
+			}
+			if entering {
+				depth++
+				if depth > maxDepth {
+					maxDepth = depth
+				}
+			} else {
+				depth--
+			}
+			return ast.WalkContinue, nil
+		})
+		// the document and list items in the deepest lists
+		if maxDepth > 102 {
+			t.Errorf("%d: nesting depth should be limited, but got %d", i, maxDepth)
+		}
+		var buf bytes.Buffer
+		if err := markdown.Renderer().Render(&buf, []byte(source), doc); err != nil {
+			t.Errorf("%d: %s", i, err)
+		}
+	}
+}
+
+func TestExtraEntities(t *testing.T) {
+	writer := html.NewWriter(html.WithExtraEntities(map[string][]byte{
+		"ImaginaryI": []byte("ⅈ"),
+		"company":    []byte("Foo & Co."),
+		"amp":        []byte("and"),
+	}))
+	markdown := New(WithRendererOptions(html.WithWriter(writer)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "&ImaginaryI; &company; &amp; &copy;", "<p>ⅈ Foo &amp; Co. and ©</p>"},
+		{2, "&unknown; \\&company; `&company;`", "<p>&amp;unknown; &amp;company; <code>&amp;company;</code></p>"},
+	}, t)
+}
+
+func TestWithoutUnescaping(t *testing.T) {
+	writer := html.NewWriter(html.WithoutUnescaping())
+	markdown := New(WithRendererOptions(html.WithWriter(writer)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "\\*not emphasis\\* \\\\ \\[x\\] \\&amp; &amp;", "<p>\\*not emphasis\\* \\\\ \\[x\\] \\&amp;amp; &amp;</p>"},
+		{2, "`\\*code\\*` [\\*link](/url)", "<p><code>\\*code\\*</code> <a href=\"/url\">\\*link</a></p>"},
+		{3, "foo\\\nbar", "<p>foo<br>\nbar</p>"},
+	}, t)
+}
+
+func TestCollapseWhitespace(t *testing.T) {
+	writer := html.NewWriter(html.WithCollapseWhitespace())
+	markdown := New(WithRendererOptions(html.WithWriter(writer)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "a  b\t\tc \t d\te", "<p>a b c d e</p>"},
+		{2, "*a   b*  &amp;   c", "<p><em>a b</em> &amp; c</p>"},
+		{3, "`a   b`\n\n    a  \t b\n", "<p><code>a   b</code></p>\n<pre><code>a  \t b\n</code></pre>"},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "a  b\t\tc", "<p>a  b\t\tc</p>"},
+	}, t)
+}
+
+func TestEscapeNonASCII(t *testing.T) {
+	writer := html.NewWriter(html.WithEscapeNonASCII())
+	markdown := New(WithRendererOptions(html.WithWriter(writer)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "日本語 😀 *é*", "<p>&#x65E5;&#x672C;&#x8A9E; &#x1F600; <em>&#xE9;</em></p>"},
+		{2, "&copy; &#x1F600; &#55296; &amp; &lt;", "<p>&#xA9; &#x1F600; &#xFFFD; &amp; &lt;</p>"},
+		{3, "`日本` \\* a\xffb\n\n    😀\n", "<p><code>&#x65E5;&#x672C;</code> * a&#xFFFD;b</p>\n<pre><code>&#x1F600;\n</code></pre>"},
+	}, t)
+
+	markdown = New(
+		WithParserOptions(parser.WithAttribute()),
+		WithRendererOptions(html.WithWriter(writer), html.WithAutoIDs(), html.WithHeadingAnchors("#")),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{5, "![日本](a.png \"題\")", "<p><img src=\"a.png\" alt=\"&#x65E5;&#x672C;\" title=\"&#x984C;\"></p>"},
+		{6, "# 日本\n\n# a {title=\"題\"}", "<h1 id=\"&#x65E5;&#x672C;\"><a class=\"anchor\" href=\"#&#x65E5;&#x672C;\">#</a>&#x65E5;&#x672C;</h1>\n<h1 id=\"a\" title=\"&#x984C;\"><a class=\"anchor\" href=\"#a\">#</a>a</h1>"},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "日本語 😀 &copy;", "<p>日本語 😀 ©</p>"},
+		{7, "![a\"b &amp; \\* *c*](x)", "<p><img src=\"x\" alt=\"a&quot;b &amp; * c\"></p>"},
+	}, t)
+}
+
+func TestTightListParagraphs(t *testing.T) {
+	markdown := New()
+	source := []byte("- a\n- b\n\n  > c\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	list := doc.FirstChild().(*ast.List)
+	if list.IsTight {
+		t.Fatal("list should be loose")
+	}
+	list.IsTight = true
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<ul>\n<li>a</li>\n<li>b\n<blockquote>\n<p>c</p>\n</blockquote>\n</li>\n</ul>\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, but got %q", expected, buf.String())
+	}
+}
+
+func TestListMarkers(t *testing.T) {
+	markdown := New()
+	source := []byte("1. a\n2. b\n3) c\n\n- d\n+ e\n* f\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	markers := []byte{}
+	starts := []int{}
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		list := c.(*ast.List)
+		markers = append(markers, list.Marker)
+		starts = append(starts, list.Start)
+	}
+	if string(markers) != ".)-+*" {
+		t.Errorf("expected markers %q, but got %q", ".)-+*", markers)
+	}
+	if fmt.Sprint(starts) != "[1 3 0 0 0]" {
+		t.Errorf("expected starts %v, but got %v", "[1 3 0 0 0]", starts)
+	}
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "1) a\n2) b\n", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>"},
+		{2, "1. a\n2) b\n", "<ol>\n<li>a</li>\n</ol>\n<ol start=\"2\">\n<li>b</li>\n</ol>"},
+	}, t)
+}
+
+func TestThematicBreakAttributes(t *testing.T) {
+	markdown := New(
+		WithParserOptions(parser.WithAttribute()),
+		WithRendererOptions(html.WithThematicBreakAttributes(map[string]string{
+			"class":       "section-break",
+			"aria-hidden": "true",
+		})),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "***\n", "<hr class=\"section-break\" aria-hidden=\"true\">"},
+		{2, "***\n{#hr1 .star}\n", "<hr id=\"hr1\" class=\"star\" aria-hidden=\"true\">"},
+	}, t)
+
+	markdown = New(
+		WithParserOptions(parser.WithAttribute()),
+		WithRendererOptions(html.WithXHTML()),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{3, "***\n", "<hr />"},
+		{4, "***\n{.star}\n", "<hr class=\"star\" />"},
+	}, t)
+}
+
+func TestCodeSpanAttributes(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithAttribute()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "a `x := 1`{.language-go} b", "<p>a <code class=\"language-go\">x := 1</code> b</p>"},
+		{2, "`a`{#c1 .x .y} `b` {.z} c", "<p><code id=\"c1\" class=\"x y\">a</code> <code>b</code> {.z} c</p>"},
+		{3, "`a`{.x", "<p><code>a</code>{.x</p>"},
+		{4, "text `a`{.x}\n\ntext {.y}", "<p>text <code class=\"x\">a</code></p>\n<p class=\"y\">text</p>"},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{5, "`a`{.x}", "<p><code>a</code>{.x}</p>"},
+	}, t)
+}
+
+func TestHeadingNumbers(t *testing.T) {
+	source := "# A\n## B\n### C\n## D\n# E\n### F\n> # quoted\n"
+	markdown := New(WithRendererOptions(html.WithHeadingNumbers(html.HeadingNumbers{
+		Separator: ".",
+	})))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, source, `<h1>1 A</h1>
+<h2>1.1 B</h2>
+<h3>1.1.1 C</h3>
+<h2>1.2 D</h2>
+<h1>2 E</h1>
+<h3>2.0.1 F</h3>
+<blockquote>
+<h1>quoted</h1>
+</blockquote>`},
+	}, t)
+
+	markdown = New(
+		WithParserOptions(parser.WithAutoHeadingID()),
+		WithRendererOptions(
+			html.WithHeadingNumbers(html.HeadingNumbers{
+				Separator:   "-",
+				TrailingDot: true,
+			}),
+			html.WithHeadingAnchors("#"),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "## A\n### B\n## C\n", `<h2 id="a"><a class="anchor" href="#a">#</a>1. A</h2>
+<h3 id="b"><a class="anchor" href="#b">#</a>1-1. B</h3>
+<h2 id="c"><a class="anchor" href="#c">#</a>2. C</h2>`},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithHeadingNumbers(html.HeadingNumbers{
+		Separator: ".",
+	})))
+	source = "# A\n## B\n"
+	doc := markdown.Parser().Parse(text.NewReader([]byte(source)))
+	render := func(n ast.Node) string {
+		var buf bytes.Buffer
+		if err := markdown.Renderer().Render(&buf, []byte(source), n); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	if s := render(doc); s != "<h1>1 A</h1>\n<h2>1.1 B</h2>\n" {
+		t.Errorf("unexpected output: %q", s)
+	}
+	if s := render(doc.LastChild()); s != "<h2>1.1 B</h2>\n" {
+		t.Errorf("unexpected output of a heading: %q", s)
+	}
+	doc.InsertBefore(doc, doc.FirstChild(), ast.NewHeading(1))
+	if s := render(doc); s != "<h1>1 </h1>\n<h1>2 A</h1>\n<h2>2.1 B</h2>\n" {
+		t.Errorf("unexpected output of a modified document: %q", s)
+	}
+}
+
+func TestDocumentStatesAfterErrors(t *testing.T) {
+	// zero values of HeadingNumbers use '.' as a separator.
+	markdown := New(
+		WithRendererOptions(
+			html.WithHeadingNumbers(html.HeadingNumbers{}),
+			html.WithAutoIDs(),
+			renderer.WithUnknownNodeRenderer(renderer.FailOnUnknownNodes),
+		),
+	)
+	source := []byte("# A\n## B\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	unknown := &unknownTestNode{}
+	doc.LastChild().AppendChild(doc.LastChild(), unknown)
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// states of failed renderings must not be reused.
+	doc.LastChild().RemoveChild(doc.LastChild(), unknown)
+	heading := ast.NewHeading(1)
+	heading.AppendChild(heading, ast.NewString([]byte("New")))
+	doc.InsertBefore(doc, doc.FirstChild(), heading)
+	buf.Reset()
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<h1 id=\"new\">1 New</h1>\n<h1 id=\"a\">2 A</h1>\n<h2 id=\"b\">2.1 B</h2>\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func BenchmarkHeadingNumbers(b *testing.B) {
+	source := []byte(strings.Repeat("# a\n## b\n", 10000))
+	markdown := New(WithRendererOptions(html.WithHeadingNumbers(html.HeadingNumbers{
+		Separator: ".",
+	})))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := markdown.Convert(source, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseAndRenderSeparately(t *testing.T) {
+	markdown := New()
+	source := []byte("# title\n\n- a\n- b\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering {
+			heading.Level++
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<h2>title</h2>\n<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != expected {
+			t.Errorf("expected %q, but got %q", expected, buf.String())
+		}
+	}
+}
+
+func TestAddOptions(t *testing.T) {
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "# title\n\n***", "<h1>title</h1>\n<hr>"},
+	}, t)
+	markdown.AddOptions(
+		WithParserOptions(parser.WithAutoHeadingID()),
+		WithRendererOptions(html.WithXHTML()),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "# title\n\n***", "<h1 id=\"title\">title</h1>\n<hr />"},
+	}, t)
+	markdown.AddOptions(WithParserOptions(parser.WithoutIndentedCodeBlocks()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{3, "    code\n\n# title", "<p>code</p>\n<h1 id=\"title\">title</h1>"},
+	}, t)
+}
+
+func TestParseContextIsolation(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithAutoHeadingID()))
+	key := parser.NewContextKey()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx := parser.NewContext()
+			ctx.Set(key, i)
+			source := []byte(fmt.Sprintf("# foo\n\n[x][ref%d]\n", i))
+			var buf bytes.Buffer
+			if err := markdown.Convert(source, &buf, parser.WithContext(ctx)); err != nil {
+				errs <- err
+				return
+			}
+			if !strings.HasPrefix(buf.String(), `<h1 id="foo">`) {
+				errs <- fmt.Errorf("heading ids leaked between conversions: %q", buf.String())
+			}
+			refs := ctx.UnresolvedReferences()
+			if len(refs) != 1 || string(refs[0].Label) != fmt.Sprintf("ref%d", i) {
+				errs <- fmt.Errorf("unexpected unresolved references: %v", refs)
+			}
+			if ctx.Get(key) != i {
+				errs <- fmt.Errorf("expected %d, but got %v", i, ctx.Get(key))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestConcurrentConvert(t *testing.T) {
+	markdown := New(
+		WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithAttribute(),
+		),
+		WithRendererOptions(
+			html.WithHeadingNumbers(html.HeadingNumbers{Separator: "."}),
+			html.WithCodeLineNumbers(),
+			html.WithCodeBlockMetaAttributes(),
+		),
+	)
+	source := []byte("# a\n\n## b {.c}\n\n```go {title=x.go}\nx\n```\n\n[link][ref] *em* <https://example.com>\n\n[ref]: /url\n")
+	expected := "<h1 id=\"a\">1 a</h1>\n<h2 id=\"b\" class=\"c\">1.1 b</h2>\n<pre><code class=\"language-go\" title=\"x.go\"><span class=\"line\" data-line=\"1\">x</span>\n</code></pre>\n<p><a href=\"/url\">link</a> <em>em</em> <a href=\"https://example.com\">https://example.com</a></p>\n"
+	doc := New().Parser().Parse(text.NewReader(source))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 16; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			if err := markdown.Convert(source, &buf); err != nil {
+				errs <- err
+				return
+			}
+			if buf.String() != expected {
+				errs <- fmt.Errorf("expected %q, but got %q", expected, buf.String())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// renders the same AST concurrently.
+			var buf bytes.Buffer
+			if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDefaultStylesheet(t *testing.T) {
+	css := html.DefaultStylesheet(nil)
+	if !strings.Contains(css, "pre {") {
+		t.Errorf("expected %q in %q", "pre {", css)
+	}
+	for _, v := range []string{".anchor", ".line", "figure", "div.highlight", ".task-list-item", ".footnotes", ".admonition", ".math", "img.emoji", "a.new"} {
+		if strings.Contains(css, v) {
+			t.Errorf("unexpected %q in %q", v, css)
+		}
+	}
+
+	css = html.DefaultStylesheet(nil,
+		html.WithHeadingAnchors("#"),
+		html.WithCodeLineNumbers(),
+		html.WithFigures(),
+		html.WithCodeBlockWrapper(html.CodeBlockWrapper{Tag: "div", Class: "highlight code"}),
+	)
+	for _, v := range []string{".anchor {", ".line::before {", "figure {", "div.highlight.code > pre {"} {
+		if !strings.Contains(css, v) {
+			t.Errorf("expected %q in %q", v, css)
+		}
+	}
+
+	css = html.DefaultStylesheet([]html.StylesheetFeature{html.StylesheetTaskLists, html.StylesheetFootnotes, html.StylesheetTaskLists})
+	for _, v := range []string{".task-list-item {", ".footnotes {"} {
+		if strings.Count(css, v) != 1 {
+			t.Errorf("expected one %q in %q", v, css)
+		}
+	}
+	for _, v := range []string{".admonition", ".math", "img.emoji", "a.new"} {
+		if strings.Contains(css, v) {
+			t.Errorf("unexpected %q in %q", v, css)
+		}
+	}
+}
+
+func TestLinkReferences(t *testing.T) {
+	markdown := New(
+		WithParserOptions(
+			parser.WithLinkReferences(map[string]parser.LinkReference{
+				"Go":      {Destination: "https://go.dev/", Title: "The Go Programming Language"},
+				"local":   {Destination: "/external"},
+				"Foo Bar": {Destination: "/foo bar"},
+			}),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "[go] and [Go][]", "<p><a href=\"https://go.dev/\" title=\"The Go Programming Language\">go</a> and <a href=\"https://go.dev/\" title=\"The Go Programming Language\">Go</a></p>"},
+		{2, "[text][local]\n\n[local]: /document", "<p><a href=\"/document\">text</a></p>"},
+		{3, "[foo  bar] [unknown]", "<p><a href=\"/foo%20bar\">foo  bar</a> [unknown]</p>"},
+	}, t)
+}
+
+func TestLooseLists(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithLooseLists(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "- a\n- b", "<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>"},
+		{2, "- a\n\n- b", "<ul>\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ul>"},
+		{3, "1. a\n   - b\n2. c", "<ol>\n<li>\n<p>a</p>\n<ul>\n<li>\n<p>b</p>\n</li>\n</ul>\n</li>\n<li>\n<p>c</p>\n</li>\n</ol>"},
+		{4, "- # a\n-\n", "<ul>\n<li>\n<h1>a</h1>\n</li>\n<li></li>\n</ul>"},
+		{5, "> a", "<blockquote>\n<p>a</p>\n</blockquote>"},
+	}, t)
+}
+
+func TestHeadingLineBreaks(t *testing.T) {
+	markdown := New(
+		WithRendererOptions(
+			html.WithHardWraps(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "foo  \n*bar\\\nbaz*\nqux\n---", "<h2>foo\n<em>bar\nbaz</em>\nqux</h2>"},
+		{2, "foo  \nbar\n\nbaz\nqux", "<p>foo<br>\nbar</p>\n<p>baz<br>\nqux</p>"},
+		{3, "foo\\\nbar\n===", "<h1>foo\nbar</h1>"},
+	}, t)
+
+	markdown = New(
+		WithRendererOptions(
+			html.WithHeadingHardLineBreaks(),
+		),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "foo  \nbar\nbaz\n---", "<h2>foo<br>\nbar\nbaz</h2>"},
+	}, t)
+}
+
+func TestEmphasisTags(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithEmphasisTags("i", "b")))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "*a* **b** ***c***", "<p><i>a</i> <b>b</b> <i><b>c</b></i></p>"},
+	}, t)
+
+	// invalid names are replaced with the defaults.
+	for i, c := range []struct {
+		emphasis, strong string
+		expected         string
+	}{
+		{"", "b", "<p><em>a</em> <b>b</b></p>\n"},
+		{"x-1", "", "<p><x-1>a</x-1> <strong>b</strong></p>\n"},
+		{"i onclick=alert(1)", "b><script", "<p><em>a</em> <strong>b</strong></p>\n"},
+		{"1i", "b\"", "<p><em>a</em> <strong>b</strong></p>\n"},
+	} {
+		markdown := New(WithRendererOptions(html.WithEmphasisTags(c.emphasis, c.strong)))
+		actual, err := markdown.ConvertString("*a* **b**")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != c.expected {
+			t.Errorf("%d: unexpected output: %q", i+1, actual)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	markdown := New()
+	source := []byte("# a *b*\n\n- c\n- d [e](/f)\n\ng\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+
+	var kinds []string
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering {
+			kinds = append(kinds, n.Kind().String())
+		} else {
+			kinds = append(kinds, "/"+n.Kind().String())
+		}
+		if n.Kind() == ast.KindHeading {
+			return ast.WalkSkipChildren, nil
+		}
+		if n.Kind() == ast.KindLink {
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Document Heading /Heading List ListItem TextBlock Text /Text /TextBlock /ListItem ListItem TextBlock Text /Text Link"
+	if s := strings.Join(kinds, " "); s != expected {
+		t.Errorf("expected %q, but got %q", expected, s)
+	}
+
+	var depths []string
+	err = ast.WalkWithContext(doc.FirstChild().NextSibling(), func(n ast.Node, entering bool, ctx ast.WalkContext) (ast.WalkStatus, error) {
+		if entering {
+			depths = append(depths, fmt.Sprintf("%s:%d", n.Kind(), ctx.Depth))
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "List:0 ListItem:1 TextBlock:2 Text:3 ListItem:1 TextBlock:2 Text:3 Link:3 Text:4"
+	if s := strings.Join(depths, " "); s != expected {
+		t.Errorf("expected %q, but got %q", expected, s)
+	}
+}
+
+type imagePathTransformer struct {
+	prefix string
+}
+
+func (t *imagePathTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if image, ok := n.(*ast.Image); ok && entering {
+			image.Destination = append([]byte(t.prefix), image.Destination...)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func TestASTTransformers(t *testing.T) {
+	markdown := New(WithParserOptions(
+		parser.WithASTTransformers(
+			util.Prioritized(&imagePathTransformer{"/static"}, 200),
+			util.Prioritized(&imagePathTransformer{"/images"}, 100),
+		),
+	))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "![a](/a.png) *![b](/b.png)*", "<p><img src=\"/static/images/a.png\" alt=\"a\"> <em><img src=\"/static/images/b.png\" alt=\"b\"></em></p>"},
+	}, t)
+}
+
+func TestBaseURL(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithBaseURL("https://example.com/docs/")))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "[a](b.html) ![c](../img/d.png) [e](/f)", "<p><a href=\"https://example.com/docs/b.html\">a</a> <img src=\"https://example.com/img/d.png\" alt=\"c\"> <a href=\"https://example.com/f\">e</a></p>"},
+		{2, "[a](#g) [b](http://example.org/h) [c](//example.org/i) [d](mailto:j@example.com) <https://example.org/k>", "<p><a href=\"#g\">a</a> <a href=\"http://example.org/h\">b</a> <a href=\"//example.org/i\">c</a> <a href=\"mailto:j@example.com\">d</a> <a href=\"https://example.org/k\">https://example.org/k</a></p>"},
+		{3, "[a](l?m=1#n) [b]()", "<p><a href=\"https://example.com/docs/l?m=1#n\">a</a> <a href=\"\">b</a></p>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithBaseURL("sub/")))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "[a](b.md) ![c](d%20e.png)", "<p><a href=\"sub/b.md\">a</a> <img src=\"sub/d%20e.png\" alt=\"c\"></p>"},
+	}, t)
+}
+
+func TestEmptyAltAttributes(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithEmptyAltAttributes(map[string]string{
+		"role": "presentation",
+	})))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "![](a.png) ![ ](b.png) ![c](c.png)", "<p><img src=\"a.png\" alt=\"\" role=\"presentation\"> <img src=\"b.png\" alt=\" \" role=\"presentation\"> <img src=\"c.png\" alt=\"c\"></p>"},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "![](a.png)", "<p><img src=\"a.png\" alt=\"\"></p>"},
+	}, t)
+
+	source := []byte("![](a.png) ![b](b.png)\n\n- [![](c.png)](/c)\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	images := ast.ImagesWithoutAlt(doc, source)
+	if len(images) != 2 || string(images[0].Destination) != "a.png" || string(images[1].Destination) != "c.png" {
+		t.Errorf("unexpected images without alt: %v", images)
+	}
+}
+
+func TestComments(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithStripComments()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "a <!-- note --> <b>b</b>\n\n<!-- block\nnote -->\n\nc\n\n<!-- note --> <div>", "<p>a  <!-- raw HTML omitted -->b<!-- raw HTML omitted --></p>\n<p>c</p>\n<!-- raw HTML omitted -->"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithPreserveComments()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "a <!-- note --> <b>b</b>\n\n<!-- block\nnote -->\n\nc", "<p>a <!-- note --> <!-- raw HTML omitted -->b<!-- raw HTML omitted --></p>\n<!-- block\nnote -->\n<p>c</p>"},
+		{4, "<!--><script>alert(1)</script>-->", "<!-- raw HTML omitted -->"},
+		{5, "<!-- x --!><script>alert(1)</script>-->", "<!-- raw HTML omitted -->"},
+		{6, "a <!--><script>alert(1)</script>--> <!-- x --!><script>alert(1)</script>--> <!--->x-->", "<p>a &lt;!--&gt;<!-- raw HTML omitted -->alert(1)<!-- raw HTML omitted -->--&gt; &lt;!-- x --!&gt;<!-- raw HTML omitted -->alert(1)<!-- raw HTML omitted -->--&gt; &lt;!---&gt;x--&gt;</p>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithPreserveComments(), html.WithUnsafe()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{7, "<!-- x --!><b>b</b>-->", "<!-- x --!><b>b</b>-->"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithStripComments(), html.WithUnsafe()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{3, "a <!-- note --> <b>b</b>\n\n<!-- note -->\n<div>\n\n<!-- note --> <div>", "<p>a  <b>b</b></p>\n<div>\n<!-- note --> <div>"},
+	}, t)
+}
+
+func TestHTMLAllowlist(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithHTMLAllowlist(map[string][]string{
+		"a":   {"href", "title"},
+		"b":   nil,
+		"div": {"class"},
+	})))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "a <b onclick=\"x()\">b</b> <script>alert(1)</script> <!-- c -->", "<p>a <b>b</b> &lt;script&gt;alert(1)&lt;/script&gt; &lt;!-- c --&gt;</p>"},
+		{2, "<a href='javascript:x()' title='t\"q'>a</a> <a HREF=\"/x?a&amp;b\">b</a>", "<p><a title=\"t&quot;q\">a</a> <a HREF=\"/x?a&amp;b\">b</a></p>"},
+		{3, "<script>\nalert(1)\n</script>\n\nc", "&lt;script&gt;\nalert(1)\n&lt;/script&gt;\n<p>c</p>"},
+		{4, "<div class=\"c\" onmouseover=\"x()\">\n<img src=x onerror=alert(1)>\n</div>", "<div class=\"c\">\n&lt;img src=x onerror=alert(1)&gt;\n</div>"},
+		{5, "<a href=\"java&#9;script:alert(1)\">a</a> <a href=\"&#1;javascript:alert(1)\">b</a>", "<p><a>a</a> <a>b</a></p>"},
+		{6, "<div>\n<a href=\"java\tscript:alert(1)\">a</a> <a href=\" \x01javascript:alert(1)\">b</a>\n</div>", "<div>\n<a>a</a> <a>b</a>\n</div>"},
+		{7, "<a href=\"javascript&colon;alert(1)\">a</a> <a href=\"javascript&#58alert(1)\">b</a> <a href=\"vbscript:x\">c</a>", "<p><a>a</a> <a>b</a> <a>c</a></p>"},
+		{8, "<a href=\"https://example.com/?a=1&amp;b=2\">a</a> <a href=\"MAILTO:a@example.com\">b</a> <a href=\"../a:b\">c</a> <a href=\"#x\">d</a>", "<p><a href=\"https://example.com/?a=1&amp;b=2\">a</a> <a href=\"MAILTO:a@example.com\">b</a> <a href=\"../a:b\">c</a> <a href=\"#x\">d</a></p>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithHTMLAllowlist(map[string][]string{
+		"img": {"src", "srcset"},
+	})))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{9, "<img src=\"a.png\" srcset=\"a.png 1x, b.png 2x\"> <img srcset=\"a.png 1x, javascript:x 2x\">", "<p><img src=\"a.png\" srcset=\"a.png 1x, b.png 2x\"> <img></p>"},
+	}, t)
+}
+
+func TestTruncate(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithTruncate(3)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "one two three four", "<p>one two three…</p>"},
+		{2, "one *two [three](/url) four* five\n\nsix", "<p>one <em>two <a href=\"/url\">three…</a></em></p>"},
+		{3, "- one `code`\n- two\n\n  three\n\n  four", "<ul>\n<li>\n<p>one <code>code</code></p>\n</li>\n<li>\n<p>two</p>\n<p>three…</p>\n</li>\n</ul>"},
+		{4, "# one two\n\nthree\nfour", "<h1>one two</h1>\n<p>three…</p>"},
+		{5, "one two three", "<p>one two three</p>"},
+		{6, "one two three, four", "<p>one two three…</p>"},
+		{7, "one\ntwo\nthree <b>four</b>", "<p>one\ntwo\nthree…</p>"},
+	}, t)
+
+	markdown = New()
+	source := []byte("日本語")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	if !ast.Truncate(doc, source, 2, []byte("...")) {
+		t.Error("document should be truncated")
+	}
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "<p>日本...</p>\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestLinkRel(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithLinkRel("nofollow ugc")))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "[a](https://example.com/) [b](/c) [d](#e) <https://example.org/> <f@example.com>", "<p><a href=\"https://example.com/\" rel=\"nofollow ugc\">a</a> <a href=\"/c\" rel=\"nofollow ugc\">b</a> <a href=\"#e\">d</a> <a href=\"https://example.org/\" rel=\"nofollow ugc\">https://example.org/</a> <a href=\"mailto:f@example.com\">f@example.com</a></p>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(
+		html.WithExternalLinkTarget(),
+		html.WithExternalLinkRel("noopener nofollow"),
+		html.WithLinkRel("nofollow ugc"),
+	))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "[a](https://example.com/) [b](/c)", "<p><a href=\"https://example.com/\" target=\"_blank\" rel=\"noopener nofollow ugc\">a</a> <a href=\"/c\" rel=\"nofollow ugc\">b</a></p>"},
+	}, t)
+}
+
+func TestURLEscape(t *testing.T) {
+	cases := []struct {
+		url      string
+		expected string
+	}{
+		{"a%20b", "a%20b"},
+		{"a%2Fb%2f", "a%2Fb%2f"},
+		{"a b", "a%20b"},
+		{"über/日本", "%C3%BCber/%E6%97%A5%E6%9C%AC"},
+		{"100%", "100%25"},
+		{"%2G%%20", "%252G%25%20"},
+		{"a\xe3\x81", "a%E3%81"},
+		{"/a?b=c&d#e", "/a?b=c&d#e"},
+		{"https://пример.рф/путь", "https://пример.рф/%D0%BF%D1%83%D1%82%D1%8C"},
+		{"http://例え.jp?q=日本#本", "http://例え.jp?q=%E6%97%A5%E6%9C%AC#%E6%9C%AC"},
+		{"/例え.jp/a", "/%E4%BE%8B%E3%81%88.jp/a"},
+	}
+	for i, c := range cases {
+		if v := string(util.URLEscape([]byte(c.url), false)); v != c.expected {
+			t.Errorf("%d: expected %q, but got %q", i, c.expected, v)
+		}
+	}
+
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "[a](/a%20b) [c](</c d>) ![e](/%E6%97%A5/本.png)", "<p><a href=\"/a%20b\">a</a> <a href=\"/c%20d\">c</a> <img src=\"/%E6%97%A5/%E6%9C%AC.png\" alt=\"e\"></p>"},
+		{2, "<https://example.com/a%2Fb?q=%20x>", "<p><a href=\"https://example.com/a%2Fb?q=%20x\">https://example.com/a%2Fb?q=%20x</a></p>"},
+		{3, "<https://例え.jp/パス>", "<p><a href=\"https://例え.jp/%E3%83%91%E3%82%B9\">https://例え.jp/パス</a></p>"},
+	}, t)
+}
+
+var kindUnknownTestNode = ast.NewNodeKind("UnknownTestNode")
+
+type unknownTestNode struct {
+	ast.BaseInline
+}
+
+func (n *unknownTestNode) Kind() ast.NodeKind {
+	return kindUnknownTestNode
+}
+
+func (n *unknownTestNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+type unknownTestNodeTransformer struct {
+}
+
+func (a *unknownTestNodeTransformer) Transform(node *ast.Document, reader text.Reader, pc parser.Context) {
+	p := node.FirstChild()
+	n := &unknownTestNode{}
+	n.AppendChild(n, ast.NewString([]byte("b")))
+	p.AppendChild(p, n)
+}
+
+func TestUnknownNodeRenderer(t *testing.T) {
+	source := []byte("a")
+	for _, c := range []struct {
+		opts     []renderer.Option
+		expected string
+		err      bool
+	}{
+		{nil, "<p>ab</p>\n", false},
+		{[]renderer.Option{renderer.WithUnknownNodeRenderer(renderer.SkipUnknownNodes)}, "<p>a</p>\n", false},
+		{[]renderer.Option{renderer.WithUnknownNodeRenderer(renderer.FailOnUnknownNodes)}, "", true},
+		{[]renderer.Option{renderer.WithUnknownNodeRenderer(func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if entering {
+				w.WriteString("[")
+			} else {
+				w.WriteString("]")
+			}
+			return ast.WalkContinue, nil
+		})}, "<p>a[b]</p>\n", false},
+	} {
+		markdown := New(
+			WithParserOptions(parser.WithASTTransformers(util.Prioritized(&unknownTestNodeTransformer{}, 999))),
+			WithRendererOptions(c.opts...),
+		)
+		var buf bytes.Buffer
+		err := markdown.Convert(source, &buf)
+		if c.err {
+			if _, ok := err.(*renderer.UnknownNodeError); !ok {
+				t.Errorf("expected an UnknownNodeError, but got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != c.expected {
+			t.Errorf("expected %q, but got %q", c.expected, buf.String())
+		}
+	}
+}
+
+func TestAutoIDs(t *testing.T) {
+	markdown := New(
+		WithParserOptions(parser.WithAttribute()),
+		WithRendererOptions(html.WithAutoIDs()),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "# Hello *World*\n\n## Hello World\n\n> # Hello World\n\n# Intro {#hello-world}", "<h1 id=\"hello-world-1\">Hello <em>World</em></h1>\n<h2 id=\"hello-world-2\">Hello World</h2>\n<blockquote>\n<h1 id=\"hello-world-3\">Hello World</h1>\n</blockquote>\n<h1 id=\"hello-world\">Intro</h1>"},
+	}, t)
+
+	markdown = New(
+		WithParserOptions(parser.WithAutoHeadingID()),
+		WithRendererOptions(html.WithAutoIDs(), html.WithHeadingAnchors("#")),
+	)
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "# A\n\n# A", "<h1 id=\"a\"><a class=\"anchor\" href=\"#a\">#</a>A</h1>\n<h1 id=\"a-1\"><a class=\"anchor\" href=\"#a-1\">#</a>A</h1>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithHeadingAnchors("#")))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{3, "# A", "<h1>A</h1>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithAutoIDs()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "# !!\n# A\n# A", "<h1 id=\"heading\">!!</h1>\n<h1 id=\"a\">A</h1>\n<h1 id=\"a-1\">A</h1>"},
+		// same ids as parser.WithAutoHeadingID
+		{5, "# **Bold** [link](/url)\n\n## a &amp; b", "<h1 id=\"bold-link\"><strong>Bold</strong> <a href=\"/url\">link</a></h1>\n<h2 id=\"a--b\">a &amp; b</h2>"},
+	}, t)
+	// headings inserted after failed renderings must have ids.
+	markdown = New(WithRendererOptions(
+		html.WithAutoIDs(),
+		html.WithHeadingAnchors("#"),
+		renderer.WithUnknownNodeRenderer(renderer.FailOnUnknownNodes),
+	))
+	source := []byte("# A\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	unknown := &unknownTestNode{}
+	doc.AppendChild(doc, unknown)
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err == nil {
+		t.Fatal("expected an error")
+	}
+	doc.RemoveChild(doc, unknown)
+	heading := ast.NewHeading(1)
+	heading.AppendChild(heading, ast.NewString([]byte("New")))
+	doc.InsertBefore(doc, doc.FirstChild(), heading)
+	buf.Reset()
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := "<h1 id=\"new\"><a class=\"anchor\" href=\"#new\">#</a>New</h1>\n<h1 id=\"a\"><a class=\"anchor\" href=\"#a\">#</a>A</h1>\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func BenchmarkAutoIDs(b *testing.B) {
+	source := []byte(strings.Repeat("# a\n## b\n", 2500))
+	markdown := New(WithRendererOptions(html.WithAutoIDs()))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := markdown.Convert(source, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	markdown := New()
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{"# **Bold** and [link](/url) `code`", "Bold and link code"},
+		{"# a \\* b &amp; <span>c</span> <https://example.com>", "a * b & c https://example.com"},
+		{"line 1\nline 2\n===", "line 1 line 2"},
+		{"# ![alt *text*](a.png)", "alt text"},
+	}
+	for i, c := range cases {
+		source := []byte(c.source)
+		doc := markdown.Parser().Parse(text.NewReader(source))
+		if actual := string(ast.PlainText(doc.FirstChild(), source)); actual != c.expected {
+			t.Errorf("%d: expected %q, but got %q", i+1, c.expected, actual)
+		}
+	}
+
+	markdown = New(WithRendererOptions(html.WithAutoIDs()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "# **Bold** [link](/url)", "<h1 id=\"bold-link\"><strong>Bold</strong> <a href=\"/url\">link</a></h1>"},
+	}, t)
+}
+
+func TestLineEndings(t *testing.T) {
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "\xef\xbb\xbf# Title\r\n\r\npara\r\nline  \r\nline\\\r\nend\r\n", "<h1>Title</h1>\n<p>para\nline<br>\nline<br>\nend</p>"},
+		{2, "```go\r\ncode\r\n\r\nmore\r\n```\r\n\r\n    indented\r\n    code\r\n\r\n`a\r\nb`", "<pre><code class=\"language-go\">code\n\nmore\n</code></pre>\n<pre><code>indented\ncode\n</code></pre>\n<p><code>a b</code></p>"},
+		{3, "a\rb\r\rc\n- d\r\n- e\rsetext\r\n---\r", "<p>a\nb</p>\n<p>c</p>\n<ul>\n<li>d</li>\n<li>e\nsetext</li>\n</ul>\n<hr>"},
+		{4, "\xef\xbb\xbf", ""},
+		{5, "- \r\n-\r\n  a\r\n\r\n[foo]: /url '\r\ntitle\r\n'\r\n\r\n[foo] `\r\nb\r\n`", "<ul>\n<li></li>\n<li>a</li>\n</ul>\n<p><a href=\"/url\" title=\"\ntitle\n\">foo</a> <code>b</code></p>"},
+		{6, "\xef\xbb\xbf---\r\ntitle\r\n---\r\n\r\n<div>\r\n</div>\r\n", "<hr>\n<h2>title</h2>\n<!-- raw HTML omitted -->"},
+	}, t)
+
+	// the source is not modified, so that positions are positions in it.
+	source := []byte("\xef\xbb\xbf# Title\r\n\r\npara\rtext\r\n")
+	original := string(source)
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	if string(source) != original {
+		t.Errorf("source should not be modified: %q", source)
+	}
+	lines := text.NewLineTable(source)
+	var actual []string
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() != ast.KindDocument {
+			actual = append(actual, fmt.Sprintf("%s %s %q", n.Kind(), lines.Position(ast.StartOffset(n)), n.Text(source)))
+		}
+		return ast.WalkContinue, nil
+	})
+	expected := []string{
+		`Heading 1:1 "Title"`,
+		`Text 1:3 "Title"`,
+		`Paragraph 3:1 "paratext"`,
+		`Text 3:1 "para"`,
+		`Text 4:1 "text"`,
+	}
+	if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected nodes:\n%s", strings.Join(actual, "\n"))
+	}
+
+	source = []byte("a\nb")
+	if actual := util.NormalizeLineEndings(source); &actual[0] != &source[0] {
+		t.Error("NormalizeLineEndings should return the given bytes if it has no '\\r'")
+	}
+}
+
+type unbufferedWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *unbufferedWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func TestConvertString(t *testing.T) {
+	actual, err := ConvertString("# Title\n\n*text*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<h1>Title</h1>\n<p><em>text</em></p>\n"; actual != expected {
+		t.Errorf("expected %q, but got %q", expected, actual)
+	}
+
+	markdown := New(WithParserOptions(parser.WithAutoHeadingID()))
+	actual, err = markdown.ConvertString("# Title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<h1 id=\"title\">Title</h1>\n"; actual != expected {
+		t.Errorf("expected %q, but got %q", expected, actual)
+	}
+
+	// plain io.Writers are buffered and flushed by Convert.
+	w := &unbufferedWriter{}
+	if err := Convert([]byte("text"), w); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "<p>text</p>\n"; w.buf.String() != expected {
+		t.Errorf("expected %q, but got %q", expected, w.buf.String())
+	}
+}
+
+func TestWithoutAutoLinks(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithoutURLAutoLinks()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "<https://example.com> <foo@example.com>", "<p>&lt;https://example.com&gt; <a href=\"mailto:foo@example.com\">foo@example.com</a></p>"},
+	}, t)
+
+	markdown = New(WithParserOptions(parser.WithoutEmailAutoLinks()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{2, "<https://example.com> <foo@example.com>", "<p><a href=\"https://example.com\">https://example.com</a> &lt;foo@example.com&gt;</p>"},
+	}, t)
+
+	markdown = New(WithParserOptions(parser.WithoutURLAutoLinks(), parser.WithoutEmailAutoLinks()), WithRendererOptions(html.WithUnsafe()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{3, "<https://example.com> <foo@example.com> <b>bold</b> [a](/a)", "<p>&lt;https://example.com&gt; &lt;foo@example.com&gt; <b>bold</b> <a href=\"/a\">a</a></p>"},
+	}, t)
+}
+
+func TestImagesInLinks(t *testing.T) {
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "[![alt](img.png)](page.html)", "<p><a href=\"page.html\"><img src=\"img.png\" alt=\"alt\"></a></p>"},
+		{2, "[![alt *x*](img.png \"t\")](page.html \"p\") after", "<p><a href=\"page.html\" title=\"p\"><img src=\"img.png\" alt=\"alt x\" title=\"t\"></a> after</p>"},
+		{3, "[![alt](img.png)][ref] [a ![b](c.png) d](/e)\n\n[ref]: /r", "<p><a href=\"/r\"><img src=\"img.png\" alt=\"alt\"></a> <a href=\"/e\">a <img src=\"c.png\" alt=\"b\"> d</a></p>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithFigures(), html.WithImageLoadingLazy()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "[![alt](img.png)](page.html)", "<p><a href=\"page.html\"><img src=\"img.png\" alt=\"alt\" loading=\"lazy\" decoding=\"async\"></a></p>"},
+	}, t)
+}
+
+func TestSoftBreakMode(t *testing.T) {
+	markdown := New(WithRendererOptions(html.WithSoftBreakMode(html.SoftBreakAsSpace)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "a\nb  \nc", "<p>a b<br>\nc</p>"},
+		{2, "a\nb\n===", "<h1>a b</h1>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(html.WithSoftBreakMode(html.SoftBreakAsBR)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{3, "a\nb", "<p>a<br>\nb</p>"},
+	}, t)
+
+	markdown = New(WithRendererOptions(
+		html.WithSoftBreakMode(html.SoftBreakAsSpace),
+		html.WithHardWraps(),
+	))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "a\nb", "<p>a<br>\nb</p>"},
+	}, t)
+
+	markdown = New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{5, "a\nb", "<p>a\nb</p>"},
+	}, t)
+}
+
+func TestStrictHeadings(t *testing.T) {
+	markdown := New()
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "#Heading", "<p>#Heading</p>"},
+		{2, "# Heading", "<h1>Heading</h1>"},
+		{3, "#", "<h1></h1>"},
+	}, t)
+
+	markdown = New(WithParserOptions(parser.WithStrictHeadings(true)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{4, "#Heading", "<p>#Heading</p>"},
+		{5, "# Heading", "<h1>Heading</h1>"},
+	}, t)
+
+	markdown = New(WithParserOptions(parser.WithStrictHeadings(false)))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{6, "#Heading", "<h1>Heading</h1>"},
+		{7, "# Heading", "<h1>Heading</h1>"},
+		{8, "##Heading ##", "<h2>Heading</h2>"},
+		{9, "#######Heading", "<p>#######Heading</p>"},
+	}, t)
+}
+
+func TestATXHeadingClosingSequences(t *testing.T) {
+	cases := []MarkdownTestCase{
+		{1, "## Heading ##", "<h2>Heading</h2>"},
+		{2, "## Heading #with-hash", "<h2>Heading #with-hash</h2>"},
+		{3, "## foo ###", "<h2>foo</h2>"},
+		{4, "# #", "<h1></h1>"},
+		{5, "## foo ## bar ##", "<h2>foo ## bar</h2>"},
+		{6, "# foo#", "<h1>foo#</h1>"},
+		{7, "### foo \\###", "<h3>foo ###</h3>"},
+	}
+	DoTestCases(New(), cases, t)
+	DoTestCases(New(WithParserOptions(parser.WithAttribute())), cases, t)
+
+	markdown := New(WithParserOptions(parser.WithAttribute()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{8, "# # {#id}", "<h1 id=\"id\"></h1>"},
+		{9, "## foo #with ## {.c}", "<h2 class=\"c\">foo #with</h2>"},
+		{10, "## foo ## bar ## {.c}", "<h2 class=\"c\">foo ## bar</h2>"},
+	}, t)
+}
+
+func TestTextLineBreaks(t *testing.T) {
+	text := ast.NewText()
+	text.SetSoftLineBreak(true)
+	text.SetHardLineBreak(true)
+	text.SetSoftLineBreak(false)
+	if text.SoftLineBreak() || !text.HardLineBreak() {
+		t.Errorf("SetSoftLineBreak(false) should clear only a soft line break: soft=%v, hard=%v",
+			text.SoftLineBreak(), text.HardLineBreak())
+	}
+	text.SetSoftLineBreak(true)
+	text.SetHardLineBreak(false)
+	if !text.SoftLineBreak() || text.HardLineBreak() {
+		t.Errorf("SetHardLineBreak(false) should clear only a hard line break: soft=%v, hard=%v",
+			text.SoftLineBreak(), text.HardLineBreak())
+	}
+}
+
+func TestRemoveChildren(t *testing.T) {
+	parent := ast.NewParagraph()
+	children := []ast.Node{ast.NewText(), ast.NewText(), ast.NewText()}
+	for _, c := range children {
+		parent.AppendChild(parent, c)
+	}
+	parent.RemoveChildren(parent)
+	if parent.HasChildren() || parent.ChildCount() != 0 {
+		t.Errorf("expected no children, but got %d", parent.ChildCount())
+	}
+	for i, c := range children {
+		if c.Parent() != nil || c.PreviousSibling() != nil || c.NextSibling() != nil {
+			t.Errorf("child %d should be detached", i)
+		}
+	}
+}
+
+func TestUnclosedAttributes(t *testing.T) {
+	for _, v := range []string{"a {", "a {#id", "a {#id .c"} {
+		if actual := util.FindAttributeIndiciesReverse([]byte(v), true); actual != nil {
+			t.Errorf("%q: expected no attributes, but got %v", v, actual)
+		}
+	}
+	markdown := New(WithParserOptions(parser.WithAttribute()))
+	DoTestCases(markdown, []MarkdownTestCase{
+		{1, "# Heading {", "<h1>Heading {</h1>"},
+		{2, "# Heading {#id", "<h1>Heading {#id</h1>"},
+		{3, "Heading {#id\n===", "<h1>Heading {#id</h1>"},
+		{4, "# Heading {#id}", "<h1 id=\"id\">Heading</h1>"},
+	}, t)
+}
+
+func TestDumpTo(t *testing.T) {
+	markdown := New(WithParserOptions(parser.WithAttribute()))
+	source := []byte("# Title {#t .c}\n\n[link](/url \"T\")\n")
+	doc := markdown.Parser().Parse(text.NewReader(source))
+	var buf bytes.Buffer
+	ast.DumpTo(&buf, doc, source)
+	expected := `Document {
+    Heading {
+        RawText: "Title "
+        HasBlankPreviousLines: false
+        Level: 1
+        Attributes {
+            id: "t"
+            class: "c"
+        }
+        Text {
+            Value: "Title"
+        }
+    }
+    Paragraph {
+        RawText: "[link](/url \"T\")"
+        HasBlankPreviousLines: true
+        Link {
+            Destination: "/url"
+            Title: "T"
+            Text {
+                Value: "link"
+            }
+        }
+    }
+}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected dump:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+type attributeParagraphTransformer struct {
+}
+
+// AttributeParagraphTransformer is a ParagraphTransformer implementation
+// that parses attributes like '{#id .class attr=value}' at the end of
+// paragraphs.
+//
+// Attributes at the end of a paragraph are set to the paragraph.
+// A paragraph that contains only attributes sets the attributes to the
+// previous block(a paragraph, a code block, a list, a blockquote or a
+// thematic break) and is removed.
+//
+// Attributes right after a code span like '`code`{.class}' are not treated
+// as attributes of the paragraph.
+//
+// WithAttribute adds this transformer to the parser.
+var AttributeParagraphTransformer = &attributeParagraphTransformer{}
+
+func (p *attributeParagraphTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc Context) {
+	lines := node.Lines()
+	if lines.Len() == 0 {
+		return
+	}
+	source := reader.Source()
+	lastIndex := lines.Len() - 1
+	lastLine := lines.At(lastIndex)
+	line := lastLine.Value(source)
+	indicies := util.FindAttributeIndiciesReverse(line, true)
+	if indicies == nil {
+		return
+	}
+	start := indicies[0][0]
+	for ; start > 0 && line[start] != '{'; start-- {
+	}
+	if start > 0 && line[start-1] == '`' {
+		// attributes of a code span
+		return
+	}
+	target := ast.Node(node)
+	if start == 0 && lines.Len() == 1 {
+		prev := node.PreviousSibling()
+		if prev == nil || !canHaveBlockAttributes(prev) {
+			return
+		}
+		target = prev
+	}
+	for _, index := range indicies {
+		target.SetAttribute(line[index[0]:index[1]],
+			util.UnescapePunctuations(line[index[2]:index[3]]))
+	}
+	if target != node {
+		node.Parent().RemoveChild(node.Parent(), node)
+		return
+	}
+	if start == 0 {
+		lines.SetSliced(0, lastIndex)
+		lastIndex--
+		lastLine = lines.At(lastIndex)
+	} else {
+		lastLine.Stop = lastLine.Start + start
+	}
+	lines.Set(lastIndex, lastLine.TrimRightSpace(source))
+	node.SetLines(lines)
+}
+
+func canHaveBlockAttributes(node ast.Node) bool {
+	switch node.Kind() {
+	case ast.KindParagraph, ast.KindCodeBlock, ast.KindFencedCodeBlock,
+		ast.KindList, ast.KindBlockquote, ast.KindThemanticBreak:
+		return true
+	}
+	return false
+}
@@ -8,8 +8,9 @@ import (
 
 // A HeadingConfig struct is a data structure that holds configuration of the renderers related to headings.
 type HeadingConfig struct {
-	AutoHeadingID bool
-	Attribute     bool
+	AutoHeadingID  bool
+	Attribute      bool
+	StrictHeadings bool
 }
 
 // SetOption implements SetOptioner.
@@ -19,6 +20,8 @@ func (b *HeadingConfig) SetOption(name OptionName, value interface{}) {
 		b.AutoHeadingID = true
 	case optAttribute:
 		b.Attribute = true
+	case optStrictHeadings:
+		b.StrictHeadings = value.(bool)
 	}
 }
 
@@ -44,6 +47,9 @@ func (o *withAutoHeadingID) SetHeadingOption(p *HeadingConfig) {
 
 // WithAutoHeadingID is a functional option that enables custom heading ids and
 // auto generated heading ids.
+// Ids are generated from texts of headings without inline markups like
+// 'bold-link' for '**Bold** [link](/url)', so ids are generated after inline
+// parsing.
 func WithAutoHeadingID() HeadingOption {
 	return &withAutoHeadingID{}
 }
@@ -61,13 +67,40 @@ func WithHeadingAttribute() HeadingOption {
 	return &withHeadingAttribute{WithAttribute()}
 }
 
+// StrictHeadings is an option name used in WithStrictHeadings.
+const optStrictHeadings OptionName = "StrictHeadings"
+
+type withStrictHeadings struct {
+	value bool
+}
+
+func (o *withStrictHeadings) SetParserOption(c *Config) {
+	c.Options[optStrictHeadings] = o.value
+}
+
+func (o *withStrictHeadings) SetHeadingOption(p *HeadingConfig) {
+	p.StrictHeadings = o.value
+}
+
+// WithStrictHeadings is a functional option that indicates whether ATX
+// headings require a space after the opening '#'s as CommonMark does.
+// If false, lines like '#Heading' are parsed as headings.
+// Defaults to true.
+func WithStrictHeadings(value bool) HeadingOption {
+	return &withStrictHeadings{value}
+}
+
 type atxHeadingParser struct {
 	HeadingConfig
 }
 
 // NewATXHeadingParser return a new BlockParser that can parse ATX headings.
 func NewATXHeadingParser(opts ...HeadingOption) BlockParser {
-	p := &atxHeadingParser{}
+	p := &atxHeadingParser{
+		HeadingConfig: HeadingConfig{
+			StrictHeadings: true,
+		},
+	}
 	for _, o := range opts {
 		o.SetHeadingOption(&p.HeadingConfig)
 	}
@@ -85,7 +118,7 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 		return nil, NoChildren
 	}
 	l := util.TrimLeftSpaceLength(line[i:])
-	if l == 0 {
+	if l == 0 && i < len(line) && b.StrictHeadings {
 		return nil, NoChildren
 	}
 	start := i + l
@@ -103,10 +136,17 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 			if util.IsEscapedPunctuation(line, i) {
 				i += 2
 			} else if util.IsSpace(c) && i < stop-1 && line[i+1] == '#' {
-				closureOpen = i + 1
 				j := i + 1
 				for ; j < stop && line[j] == '#'; j++ {
 				}
+				k := j
+				for ; k < stop && util.IsSpace(line[k]); k++ {
+				}
+				if k < stop && (k == j || line[k] != '{') { // hashes in texts like '#with-hash'
+					i = j
+					continue
+				}
+				closureOpen = i + 1
 				closureClose = j
 				break
 			} else {
@@ -117,7 +157,7 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 			i := closureClose
 			for ; i < stop && util.IsSpace(line[i]); i++ {
 			}
-			if i < stop-1 || line[i] == '{' {
+			if i < stop-1 && line[i] == '{' {
 				as := i + 1
 				for as < stop {
 					ai, skip := util.FindAttributeIndex(line[as:], true)
@@ -130,7 +170,7 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 				}
 				for ; as < stop && util.IsSpace(line[as]); as++ {
 				}
-				if line[as] == '}' && (as > stop-2 || util.IsBlank(line[as:])) {
+				if as < stop && line[as] == '}' && (as > stop-2 || util.IsBlank(line[as:])) {
 					parsed = true
 					node.Lines().Append(text.NewSegment(segment.Start+start+1, segment.Start+closureOpen))
 				} else {
@@ -142,9 +182,7 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 	if !parsed {
 		start = origstart
 		stop := len(line) - util.TrimRightSpaceLength(line)
-		if stop <= start { // empty headings like '##[space]'
-			stop = start + 1
-		} else {
+		if stop > start { // otherwise, empty headings like '##[space]'
 			i = stop - 1
 			for ; line[i] == '#' && i >= start; i-- {
 			}
@@ -153,10 +191,10 @@ func (b *atxHeadingParser) Open(parent ast.Node, reader text.Reader, pc Context)
 			}
 			i++
 			stop = i
-		}
 
-		if len(util.TrimRight(line[start:stop], []byte{'#'})) != 0 { // empty heading like '### ###'
-			node.Lines().Append(text.NewSegment(segment.Start+start, segment.Start+stop))
+			if len(util.TrimRight(line[start:stop], []byte{'#'})) != 0 { // empty heading like '### ###'
+				node.Lines().Append(text.NewSegment(segment.Start+start, segment.Start+stop))
+			}
 		}
 	}
 	return node, NoChildren
@@ -172,12 +210,15 @@ func (b *atxHeadingParser) Close(node ast.Node, reader text.Reader, pc Context)
 		if !ok {
 			parseLastLineAttributes(node, reader, pc)
 		}
+		if id, ok := node.AttributeString("id"); ok {
+			pc.IDs().Put(id)
+		}
 	}
 
 	if b.AutoHeadingID {
 		_, ok := node.AttributeString("id")
 		if !ok {
-			generateAutoHeadingID(node.(*ast.Heading), reader, pc)
+			addAutoHeadingID(node.(*ast.Heading), pc)
 		}
 	}
 }
@@ -190,19 +231,44 @@ func (b *atxHeadingParser) CanAcceptIndentedLine() bool {
 	return false
 }
 
-var attrAutoHeadingIDPrefix = []byte("heading")
 var attrNameID = []byte("#")
 
-func generateAutoHeadingID(node *ast.Heading, reader text.Reader, pc Context) {
-	lastIndex := node.Lines().Len() - 1
-	lastLine := node.Lines().At(lastIndex)
-	line := lastLine.Value(reader.Source())
-	headingID := pc.IDs().Generate(line, attrAutoHeadingIDPrefix)
-	node.SetAttribute(attrNameID, headingID)
+// autoHeadingIDsKey is a key of headings that need auto generated ids.
+var autoHeadingIDsKey = NewContextKey()
+
+// addAutoHeadingID adds the given heading to headings that need auto
+// generated ids. Ids are generated by generateAutoHeadingIDs after inline
+// parsing because ids are generated from texts without inline markups.
+func addAutoHeadingID(node *ast.Heading, pc Context) {
+	var headings []*ast.Heading
+	if v := pc.Get(autoHeadingIDsKey); v != nil {
+		headings = v.([]*ast.Heading)
+	}
+	pc.Set(autoHeadingIDsKey, append(headings, node))
+}
+
+// generateAutoHeadingIDs generates ids of headings added by addAutoHeadingID
+// from their plain texts(see ast.PlainText).
+func generateAutoHeadingIDs(reader text.Reader, pc Context) {
+	v := pc.Get(autoHeadingIDsKey)
+	if v == nil {
+		return
+	}
+	pc.Set(autoHeadingIDsKey, nil)
+	for _, node := range v.([]*ast.Heading) {
+		if _, ok := node.AttributeString("id"); ok || node.Parent() == nil {
+			continue
+		}
+		headingID := pc.IDs().Generate(ast.PlainText(node, reader.Source()), ast.KindHeading)
+		node.SetAttribute(attrNameID, headingID)
+	}
 }
 
 func parseLastLineAttributes(node ast.Node, reader text.Reader, pc Context) {
 	lastIndex := node.Lines().Len() - 1
+	if lastIndex < 0 { // empty headings like '# #'
+		return
+	}
 	lastLine := node.Lines().At(lastIndex)
 	line := lastLine.Value(reader.Source())
 	indicies := util.FindAttributeIndiciesReverse(line, true)
@@ -6,15 +6,57 @@ import (
 	"github.com/yuin/goldmark/util"
 )
 
-type autoLinkParser struct {
+// URLAutoLinksDisabled is an option name used in WithoutURLAutoLinks.
+const optURLAutoLinksDisabled OptionName = "URLAutoLinksDisabled"
+
+// EmailAutoLinksDisabled is an option name used in WithoutEmailAutoLinks.
+const optEmailAutoLinksDisabled OptionName = "EmailAutoLinksDisabled"
+
+type withoutURLAutoLinks struct {
+}
+
+func (o *withoutURLAutoLinks) SetParserOption(c *Config) {
+	c.Options[optURLAutoLinksDisabled] = true
+}
+
+// WithoutURLAutoLinks is a functional option that disables autolinks of
+// URLs like '<https://example.com>'. They are rendered as texts.
+func WithoutURLAutoLinks() Option {
+	return &withoutURLAutoLinks{}
+}
+
+type withoutEmailAutoLinks struct {
+}
+
+func (o *withoutEmailAutoLinks) SetParserOption(c *Config) {
+	c.Options[optEmailAutoLinksDisabled] = true
+}
+
+// WithoutEmailAutoLinks is a functional option that disables autolinks of
+// email addresses like '<foo@example.com>'. They are rendered as texts.
+func WithoutEmailAutoLinks() Option {
+	return &withoutEmailAutoLinks{}
 }
 
-var defaultAutoLinkParser = &autoLinkParser{}
+type autoLinkParser struct {
+	URLAutoLinksDisabled   bool
+	EmailAutoLinksDisabled bool
+}
 
 // NewAutoLinkParser returns a new InlineParser that parses autolinks
 // surrounded by '<' and '>' .
 func NewAutoLinkParser() InlineParser {
-	return defaultAutoLinkParser
+	return &autoLinkParser{}
+}
+
+// SetOption implements SetOptioner.
+func (s *autoLinkParser) SetOption(name OptionName, value interface{}) {
+	switch name {
+	case optURLAutoLinksDisabled:
+		s.URLAutoLinksDisabled = value.(bool)
+	case optEmailAutoLinksDisabled:
+		s.EmailAutoLinksDisabled = value.(bool)
+	}
 }
 
 func (s *autoLinkParser) Trigger() []byte {
@@ -32,6 +74,9 @@ func (s *autoLinkParser) Parse(parent ast.Node, block text.Reader, pc Context) a
 	if stop < 0 {
 		return nil
 	}
+	if (typ == ast.AutoLinkURL && s.URLAutoLinksDisabled) || (typ == ast.AutoLinkEmail && s.EmailAutoLinksDisabled) {
+		return nil
+	}
 	stop++
 	if stop >= len(line) || line[stop] != '>' {
 		return nil
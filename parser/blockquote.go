@@ -3,7 +3,6 @@ package parser
 import (
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
-	"github.com/yuin/goldmark/util"
 )
 
 type blockquoteParser struct {
@@ -19,7 +18,8 @@ func NewBlockquoteParser() BlockParser {
 
 func (b *blockquoteParser) process(reader text.Reader) bool {
 	line, _ := reader.PeekLine()
-	w, pos := util.IndentWidth(line, 0)
+	offset := reader.LineOffset()
+	w, pos := reader.TabStop().IndentWidth(line, 0)
 	if w > 3 || pos >= len(line) || line[pos] != '>' {
 		return false
 	}
@@ -33,7 +33,8 @@ func (b *blockquoteParser) process(reader text.Reader) bool {
 	}
 	reader.Advance(pos)
 	if line[pos-1] == '\t' {
-		reader.SetPadding(2)
+		// the tab after '>' is partially consumed as a space.
+		reader.SetPadding(reader.TabStop().TabWidth(offset+w+1) - 1)
 	}
 	return true
 }
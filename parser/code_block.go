@@ -20,7 +20,7 @@ func NewCodeBlockParser() BlockParser {
 
 func (b *codeBlockParser) Open(parent ast.Node, reader text.Reader, pc Context) (ast.Node, State) {
 	line, segment := reader.PeekLine()
-	pos, padding := util.IndentPosition(line, reader.LineOffset(), 4)
+	pos, padding := reader.TabStop().IndentPosition(line, reader.LineOffset(), 4)
 	if pos < 0 {
 		return nil, NoChildren
 	}
@@ -39,7 +39,7 @@ func (b *codeBlockParser) Continue(node ast.Node, reader text.Reader, pc Context
 		node.Lines().Append(segment.TrimLeftSpaceWidth(4, reader.Source()))
 		return Continue | NoChildren
 	}
-	pos, padding := util.IndentPosition(line, reader.LineOffset(), 4)
+	pos, padding := reader.TabStop().IndentPosition(line, reader.LineOffset(), 4)
 	if pos < 0 {
 		return Close
 	}
@@ -7,14 +7,23 @@ import (
 )
 
 type codeSpanParser struct {
+	Attribute bool
 }
 
-var defaultCodeSpanParser = &codeSpanParser{}
-
 // NewCodeSpanParser return a new InlineParser that parses inline codes
 // surrounded by '`' .
+// If WithAttribute is enabled, attributes like '{.language-go}' right after
+// closing backticks are set to the code span.
 func NewCodeSpanParser() InlineParser {
-	return defaultCodeSpanParser
+	return &codeSpanParser{}
+}
+
+// SetOption implements SetOptioner.
+func (s *codeSpanParser) SetOption(name OptionName, value interface{}) {
+	switch name {
+	case optAttribute:
+		s.Attribute = true
+	}
 }
 
 func (s *codeSpanParser) Trigger() []byte {
@@ -42,7 +51,7 @@ func (s *codeSpanParser) Parse(parent ast.Node, block text.Reader, pc Context) a
 				for ; i < len(line) && line[i] == '`'; i++ {
 				}
 				closure := i - oldi
-				if closure == opener && (i+1 >= len(line) || line[i+1] != '`') {
+				if closure == opener {
 					segment := segment.WithStop(segment.Start + i - closure)
 					if !segment.IsEmpty() {
 						node.AppendChild(node, ast.NewRawTextSegment(segment))
@@ -52,32 +61,80 @@ func (s *codeSpanParser) Parse(parent ast.Node, block text.Reader, pc Context) a
 				}
 			}
 		}
-		if !util.IsBlank(line) {
-			node.AppendChild(node, ast.NewRawTextSegment(segment))
-		}
+		// line endings are a part of the content, they are converted into
+		// spaces by renderers.
+		node.AppendChild(node, ast.NewRawTextSegment(segment))
 		block.AdvanceLine()
 	}
 end:
 	if !node.IsBlank(block.Source()) {
-		// trim first halfspace and last halfspace
+		// trim first halfspace and last halfspace. line endings are treated
+		// as spaces.
 		segment := node.FirstChild().(*ast.Text).Segment
 		shouldTrimmed := true
-		if !(!segment.IsEmpty() && block.Source()[segment.Start] == ' ') {
+		if !(!segment.IsEmpty() && isCodeSpanSpace(block.Source()[segment.Start])) {
 			shouldTrimmed = false
 		}
 		segment = node.LastChild().(*ast.Text).Segment
-		if !(!segment.IsEmpty() && block.Source()[segment.Stop-1] == ' ') {
+		if !(!segment.IsEmpty() && isCodeSpanSpace(block.Source()[segment.Stop-1])) {
 			shouldTrimmed = false
 		}
 		if shouldTrimmed {
+			source := block.Source()
 			t := node.FirstChild().(*ast.Text)
 			segment := t.Segment
-			t.Segment = segment.WithStart(segment.Start + 1)
+			start := segment.Start + 1
+			if source[segment.Start] == '\r' && start < segment.Stop && source[start] == '\n' {
+				start++
+			}
+			t.Segment = segment.WithStart(start)
 			t = node.LastChild().(*ast.Text)
-			segment = node.LastChild().(*ast.Text).Segment
-			t.Segment = segment.WithStop(segment.Stop - 1)
+			segment = t.Segment
+			stop := segment.Stop - 1
+			if l := util.LineEndingLength(segment.Value(source)); l != 0 {
+				stop = segment.Stop - l
+			}
+			t.Segment = segment.WithStop(stop)
 		}
 
 	}
+	if s.Attribute {
+		parseCodeSpanAttributes(node, block)
+	}
 	return node
 }
+
+// parseCodeSpanAttributes parses attributes like '{#id .class}' that
+// immediately follow a code span.
+func parseCodeSpanAttributes(node ast.Node, block text.Reader) {
+	line, _ := block.PeekLine()
+	if len(line) == 0 || line[0] != '{' {
+		return
+	}
+	var attrs []ast.Attribute
+	i := 1
+	for i < len(line) {
+		ai, skip := util.FindAttributeIndex(line[i:], true)
+		if ai[0] < 0 {
+			break
+		}
+		attrs = append(attrs, ast.Attribute{
+			Name:  line[i+ai[0] : i+ai[1]],
+			Value: util.UnescapePunctuations(line[i+ai[2] : i+ai[3]]),
+		})
+		i += ai[3] + skip
+	}
+	for ; i < len(line) && util.IsSpace(line[i]) && line[i] != '\n'; i++ {
+	}
+	if i >= len(line) || line[i] != '}' || len(attrs) == 0 {
+		return
+	}
+	for _, attr := range attrs {
+		node.SetAttribute(attr.Name, attr.Value)
+	}
+	block.Advance(i + 1)
+}
+
+func isCodeSpanSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r'
+}
@@ -46,12 +46,13 @@ func (b *fencedCodeBlockParser) Open(parent ast.Node, reader text.Reader, pc Con
 	if i < len(line)-1 {
 		rest := line[i:]
 		left := util.TrimLeftSpaceLength(rest)
-		right := util.TrimRightSpaceLength(rest)
-		infoStart, infoStop := segment.Start+i+left, segment.Stop-right
-		value := rest[left : len(rest)-right]
-		if fenceChar == '`' && bytes.IndexByte(value, '`') > -1 {
-			return nil, NoChildren
-		} else if infoStart != infoStop {
+		if left < len(rest) {
+			right := util.TrimRightSpaceLength(rest)
+			infoStart, infoStop := segment.Start+i+left, segment.Stop-right
+			value := rest[left : len(rest)-right]
+			if fenceChar == '`' && bytes.IndexByte(value, '`') > -1 {
+				return nil, NoChildren
+			}
 			info = ast.NewTextSegment(text.NewSegment(infoStart, infoStop))
 		}
 	}
@@ -64,14 +65,15 @@ func (b *fencedCodeBlockParser) Open(parent ast.Node, reader text.Reader, pc Con
 func (b *fencedCodeBlockParser) Continue(node ast.Node, reader text.Reader, pc Context) State {
 	line, segment := reader.PeekLine()
 	fdata := pc.Get(fencedCodeBlockInfoKey).(*fenceData)
-	w, pos := util.IndentWidth(line, 0)
+	w, pos := reader.TabStop().IndentWidth(line, 0)
 	if w < 4 {
 		i := pos
 		for ; i < len(line) && line[i] == fdata.char; i++ {
 		}
 		length := i - pos
 		if length >= fdata.length && util.IsBlank(line[i:]) {
-			reader.Advance(segment.Stop - segment.Start - 1 - segment.Padding)
+			newline := util.LineEndingLength(line)
+			reader.Advance(segment.Stop - segment.Start - newline - segment.Padding)
 			return Close
 		}
 	}
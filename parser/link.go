@@ -49,6 +49,13 @@ func (s *linkLabelState) Kind() ast.NodeKind {
 	return kindLinkLabelState
 }
 
+// IsInLinkLabel returns true if the current position seems to be in a link
+// label. Inline parsers that create links should not create links in link
+// labels because links can not contain other links.
+func IsInLinkLabel(pc Context) bool {
+	return pc.Get(linkLabelStateKey) != nil
+}
+
 func pushLinkLabelState(pc Context, v *linkLabelState) {
 	tlist := pc.Get(linkLabelStateKey)
 	var list *linkLabelState
@@ -245,6 +252,11 @@ func (s *linkParser) parseReferenceLink(parent ast.Node, last *linkLabelState, b
 
 	ref, ok := pc.Reference(util.ToLinkReference(maybeReference))
 	if !ok {
+		_, pos := block.Position()
+		pc.AddUnresolvedReference(UnresolvedReference{
+			Label:   maybeReference,
+			Segment: text.NewSegment(last.Segment.Start, pos.Start),
+		})
 		return nil, true
 	}
 
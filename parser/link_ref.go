@@ -43,10 +43,15 @@ func (p *linkReferenceParagraphTransformer) Transform(node *ast.Paragraph, reade
 	if lines.Len() == 0 {
 		t := ast.NewTextBlock()
 		t.SetBlankPreviousLines(node.HasBlankPreviousLines())
+		t.SetPos(node.Pos())
 		node.Parent().ReplaceChild(node.Parent(), node, t)
 		return
 	}
 
+	if len(removes) != 0 {
+		// the paragraph now starts after the removed definitions.
+		node.SetPos(lines.At(0).Start)
+	}
 	node.SetLines(lines)
 }
 
@@ -57,7 +62,7 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 		return -1, -1
 	}
 	startLine, _ := block.Position()
-	width, pos := util.IndentWidth(line, 0)
+	width, pos := block.TabStop().IndentWidth(line, 0)
 	if width > 3 {
 		return -1, -1
 	}
@@ -148,13 +153,13 @@ func parseLinkReferenceDefinition(block text.Reader, pc Context) (int, int) {
 		if !isNewLine {
 			return -1, -1
 		}
-		title := block.Value(text.NewSegment(open, closes))
+		title := util.NormalizeLineEndings(block.Value(text.NewSegment(open, closes)))
 		ref := NewReference(label, destination, title)
 		pc.AddReference(ref)
 		return startLine, endLine
 	}
 
-	title := block.Value(text.NewSegment(open, closes))
+	title := util.NormalizeLineEndings(block.Value(text.NewSegment(open, closes)))
 
 	endLine, _ = block.Position()
 	ref := NewReference(label, destination, title)
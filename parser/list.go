@@ -56,7 +56,7 @@ func parseListItem(line []byte) ([6]int, listItemType) {
 	} else {
 		return ret, notList
 	}
-	if line[i] != '\n' {
+	if line[i] != '\n' && line[i] != '\r' {
 		w, _ := util.IndentWidth(line[i:], 0)
 		if w == 0 {
 			return ret, notList
@@ -64,12 +64,22 @@ func parseListItem(line []byte) ([6]int, listItemType) {
 	}
 	ret[4] = i
 	ret[5] = len(line)
-	if line[ret[5]-1] == '\n' && line[i] != '\n' {
-		ret[5]--
+	if l := util.LineEndingLength(line); l != 0 && ret[5]-l > i {
+		ret[5] -= l
 	}
 	return ret, typ
 }
 
+// isEmptyListItem returns true if the given list marker is followed by
+// only a line ending.
+func isEmptyListItem(line []byte, match [6]int) bool {
+	if match[5]-match[4] == 1 {
+		return true
+	}
+	l := util.LineEndingLength(line)
+	return l != 0 && match[4] == len(line)-l
+}
+
 func matchesListItem(source []byte, strict bool) ([6]int, listItemType) {
 	m, typ := parseListItem(source)
 	if typ != notList && (!strict || strict && m[1] < 4) {
@@ -78,12 +88,12 @@ func matchesListItem(source []byte, strict bool) ([6]int, listItemType) {
 	return m, notList
 }
 
-func calcListOffset(source []byte, match [6]int) int {
+func calcListOffset(source []byte, match [6]int, tabStop util.TabStop) int {
 	offset := 0
 	if util.IsBlank(source[match[4]:]) { // list item starts with a blank line
 		offset = 1
 	} else {
-		offset, _ = util.IndentWidth(source[match[4]:], match[2])
+		offset, _ = tabStop.IndentWidth(source[match[4]:], match[2])
 		if offset > 4 { // offseted codeblock
 			offset = 1
 		}
@@ -134,7 +144,7 @@ func (b *listParser) Open(parent ast.Node, reader text.Reader, pc Context) (ast.
 			return nil, NoChildren
 		}
 		//an empty list item cannot interrupt a paragraph:
-		if match[5]-match[4] == 1 {
+		if isEmptyListItem(line, match) {
 			return nil, NoChildren
 		}
 	}
@@ -158,7 +168,7 @@ func (b *listParser) Continue(node ast.Node, reader text.Reader, pc Context) Sta
 		return Continue | HasChildren
 	}
 	// Themantic Breaks take precedence over lists
-	if isThemanticBreak(line) {
+	if isThemanticBreak(line, reader.TabStop()) {
 		isHeading := false
 		last := pc.LastOpenedBlock().Node
 		if ast.IsParagraph(last) {
@@ -181,7 +191,7 @@ func (b *listParser) Continue(node ast.Node, reader text.Reader, pc Context) Sta
 	//  - b          <--- current line
 	// it maybe a new child of the list.
 	offset := lastOffset(node)
-	indent, _ := util.IndentWidth(line, 0)
+	indent, _ := reader.TabStop().IndentWidth(line, 0)
 
 	if indent < offset {
 		if indent < 4 {
@@ -225,6 +235,7 @@ func (b *listParser) Close(node ast.Node, reader text.Reader, pc Context) {
 				if ok {
 					textBlock := ast.NewTextBlock()
 					textBlock.SetLines(paragraph.Lines())
+					textBlock.SetPos(paragraph.Pos())
 					child.ReplaceChild(child, paragraph, textBlock)
 				}
 			}
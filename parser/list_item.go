@@ -34,13 +34,13 @@ func (b *listItemParser) Open(parent ast.Node, reader text.Reader, pc Context) (
 	if match[1]-offset > 3 {
 		return nil, NoChildren
 	}
-	itemOffset := calcListOffset(line, match)
+	itemOffset := calcListOffset(line, match, reader.TabStop())
 	node := ast.NewListItem(match[3] + itemOffset)
-	if match[5]-match[4] == 1 {
+	if isEmptyListItem(line, match) {
 		return node, NoChildren
 	}
 
-	pos, padding := util.IndentPosition(line[match[4]:], match[4], itemOffset)
+	pos, padding := reader.TabStop().IndentPosition(line[match[4]:], match[4], itemOffset)
 	child := match[3] + pos
 	reader.AdvanceAndSetPadding(child, padding)
 	return node, HasChildren
@@ -52,7 +52,7 @@ func (b *listItemParser) Continue(node ast.Node, reader text.Reader, pc Context)
 		return Continue | HasChildren
 	}
 
-	indent, _ := util.IndentWidth(line, reader.LineOffset())
+	indent, _ := reader.TabStop().IndentWidth(line, reader.LineOffset())
 	offset := lastOffset(node.Parent())
 	if indent < offset && indent < 4 {
 		_, typ := matchesListItem(line, true)
@@ -62,7 +62,7 @@ func (b *listItemParser) Continue(node ast.Node, reader text.Reader, pc Context)
 		}
 		return Close
 	}
-	pos, padding := util.IndentPosition(line, reader.LineOffset(), offset)
+	pos, padding := reader.TabStop().IndentPosition(line, reader.LineOffset(), offset)
 	reader.AdvanceAndSetPadding(pos, padding)
 
 	return Continue | HasChildren
@@ -53,10 +53,21 @@ func (r *reference) String() string {
 	return fmt.Sprintf("Reference{Label:%s, Destination:%s, Title:%s}", r.label, r.destination, r.title)
 }
 
+// An UnresolvedReference struct represents a reference link whose label
+// has no matching link reference definition.
+type UnresolvedReference struct {
+	// Label is a label of the reference link.
+	Label []byte
+
+	// Segment is a position of the reference link in the source.
+	Segment text.Segment
+}
+
 // An IDs interface is a collection of the element ids.
 type IDs interface {
-	// Generate generates a new element id.
-	Generate(value, prefix []byte) []byte
+	// Generate generates a new element id for the node of the given kind.
+	// Generated ids must be unique in the document.
+	Generate(value []byte, kind ast.NodeKind) []byte
 
 	// Put puts a given element id to the used ids table.
 	Put(value []byte)
@@ -66,45 +77,27 @@ type ids struct {
 	values map[string]bool
 }
 
-func newIDs() IDs {
+// NewIDs returns a new IDs that generates GitHub compatible ids:
+// letters are lowercased, spaces are replaced with '-' and punctuations
+// other than '-' and '_' are removed. Duplicated ids are suffixed with
+// '-1', '-2' and so on.
+func NewIDs() IDs {
 	return &ids{
 		values: map[string]bool{},
 	}
 }
 
-func (s *ids) Generate(value, prefix []byte) []byte {
-	value = util.TrimLeftSpace(value)
-	value = util.TrimRightSpace(value)
-	result := []byte{}
-	for i := 0; i < len(value); {
-		v := value[i]
-		l := util.UTF8Len(v)
-		i += int(l)
-		if l != 1 {
-			continue
-		}
-		if util.IsAlphaNumeric(v) {
-			if 'A' <= v && v <= 'Z' {
-				v += 'a' - 'A'
-			}
-			result = append(result, v)
-		} else if util.IsSpace(v) {
-			result = append(result, '-')
-		}
-	}
+func (s *ids) Generate(value []byte, kind ast.NodeKind) []byte {
+	result := util.ToElementID(value)
 	if len(result) == 0 {
-		if prefix != nil {
-			result = append(make([]byte, 0, len(prefix)), prefix...)
-		} else {
-			result = []byte("id")
-		}
+		result = []byte(strings.ToLower(kind.String()))
 	}
 	if _, ok := s.values[util.BytesToReadOnlyString(result)]; !ok {
 		s.values[util.BytesToReadOnlyString(result)] = true
 		return result
 	}
 	for i := 1; ; i++ {
-		newResult := fmt.Sprintf("%s%d", result, i)
+		newResult := fmt.Sprintf("%s-%d", result, i)
 		if _, ok := s.values[newResult]; !ok {
 			s.values[newResult] = true
 			return []byte(newResult)
@@ -151,6 +144,15 @@ type Context interface {
 	// References returns a list of references.
 	References() []Reference
 
+	// AddUnresolvedReference adds the given unresolved reference to this context.
+	AddUnresolvedReference(UnresolvedReference)
+
+	// UnresolvedReferences returns a list of reference links like '[text][ref]'
+	// and '[text][]' that have no matching link reference definitions in
+	// the order they appear. Shortcut reference links like '[text]' are
+	// not included because these are often just texts in brackets.
+	UnresolvedReferences() []UnresolvedReference
+
 	// IDs returns a collection of the element ids.
 	IDs() IDs
 
@@ -192,18 +194,42 @@ type parseContext struct {
 	store         []interface{}
 	ids           IDs
 	refs          map[string]Reference
+	unresolved    []UnresolvedReference
 	blockOffset   int
 	delimiters    *Delimiter
 	lastDelimiter *Delimiter
 	openedBlocks  []Block
 }
 
+// A ContextConfig struct is a data structure that holds configuration of the Context.
+type ContextConfig struct {
+	IDs IDs
+}
+
+// An ContextOption is a functional option type for the Context.
+type ContextOption func(*ContextConfig)
+
+// WithIDs is a functional option for the Context that replaces the default
+// element id generator.
+func WithIDs(ids IDs) ContextOption {
+	return func(c *ContextConfig) {
+		c.IDs = ids
+	}
+}
+
 // NewContext returns a new Context.
-func NewContext() Context {
+func NewContext(options ...ContextOption) Context {
+	cfg := &ContextConfig{
+		IDs: NewIDs(),
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
 	return &parseContext{
 		store:         make([]interface{}, ContextKeyMax+1),
 		refs:          map[string]Reference{},
-		ids:           newIDs(),
+		ids:           cfg.IDs,
 		blockOffset:   0,
 		delimiters:    nil,
 		lastDelimiter: nil,
@@ -312,6 +338,14 @@ func (p *parseContext) References() []Reference {
 	return ret
 }
 
+func (p *parseContext) AddUnresolvedReference(ref UnresolvedReference) {
+	p.unresolved = append(p.unresolved, ref)
+}
+
+func (p *parseContext) UnresolvedReferences() []UnresolvedReference {
+	return p.unresolved
+}
+
 func (p *parseContext) String() string {
 	refs := []string{}
 	for _, r := range p.refs {
@@ -391,20 +425,115 @@ type withAttribute struct {
 }
 
 func (o *withAttribute) SetParserOption(c *Config) {
+	if _, ok := c.Options[optAttribute]; !ok {
+		c.ParagraphTransformers = append(c.ParagraphTransformers,
+			util.Prioritized(AttributeParagraphTransformer, 200))
+	}
 	c.Options[optAttribute] = true
 }
 
 // WithAttribute is a functional option that enables custom attributes.
+// Attributes can be set to headings, paragraphs, code blocks, lists,
+// blockquotes and thematic breaks(see AttributeParagraphTransformer).
 func WithAttribute() Option {
 	return &withAttribute{}
 }
 
+// TabWidth is an option name used in WithTabWidth.
+const optTabWidth OptionName = "TabWidth"
+
+type withTabWidth struct {
+	value int
+}
+
+func (o *withTabWidth) SetParserOption(c *Config) {
+	c.Options[optTabWidth] = o.value
+}
+
+// WithTabWidth is a functional option that specifies a width of tab stops.
+// Tabs in indentation advance to the next tab stop, so the width affects
+// indented code blocks and nested list items.
+// The default width is 4 as CommonMark specifies.
+func WithTabWidth(n int) Option {
+	return &withTabWidth{n}
+}
+
+// IndentedCodeBlocks is an option name used in WithoutIndentedCodeBlocks.
+const optIndentedCodeBlocks OptionName = "IndentedCodeBlocks"
+
+type withoutIndentedCodeBlocks struct {
+}
+
+func (o *withoutIndentedCodeBlocks) SetParserOption(c *Config) {
+	c.Options[optIndentedCodeBlocks] = false
+}
+
+// WithoutIndentedCodeBlocks is a functional option that disables indented
+// code blocks. Lines indented with 4 or more spaces are parsed as
+// paragraphs. Fenced code blocks are still available.
+func WithoutIndentedCodeBlocks() Option {
+	return &withoutIndentedCodeBlocks{}
+}
+
+// MaxNestingDepth is an option name used in WithMaxNestingDepth.
+const optMaxNestingDepth OptionName = "MaxNestingDepth"
+
+type withMaxNestingDepth struct {
+	value int
+}
+
+func (o *withMaxNestingDepth) SetParserOption(c *Config) {
+	c.Options[optMaxNestingDepth] = o.value
+}
+
+// WithMaxNestingDepth is a functional option that limits the nesting depth
+// of blocks like blockquotes and lists. Contents nested deeper than the
+// given depth are parsed as paragraphs.
+// You should set this option when you parse untrusted Markdown texts,
+// because deeply nested blocks consume a lot of time and memory.
+// By default, the depth is unlimited.
+func WithMaxNestingDepth(n int) Option {
+	return &withMaxNestingDepth{n}
+}
+
+// A LinkReference struct is a link reference definition that is provided
+// outside of documents.
+type LinkReference struct {
+	// Destination is a destination(URL) of the reference.
+	Destination string
+
+	// Title is a title of the reference.
+	Title string
+}
+
+// LinkReferences is an option name used in WithLinkReferences.
+const optLinkReferences OptionName = "LinkReferences"
+
+type withLinkReferences struct {
+	value map[string]LinkReference
+}
+
+func (o *withLinkReferences) SetParserOption(c *Config) {
+	c.Options[optLinkReferences] = o.value
+}
+
+// WithLinkReferences is a functional option that provides link reference
+// definitions shared by documents like a glossary. Keys are labels like
+// 'term' for '[term]'. Link reference definitions in documents take
+// precedence over the given definitions.
+func WithLinkReferences(refs map[string]LinkReference) Option {
+	return &withLinkReferences{refs}
+}
+
 // A Parser interface parses Markdown text into AST nodes.
 type Parser interface {
 	// Parse parses the given Markdown text into AST nodes.
 	Parse(reader text.Reader, opts ...ParseOption) ast.Node
 
 	// AddOption adds the given option to thie parser.
+	// Options can be added after parsing documents. The parser is
+	// reconfigured on the next Parse call.
+	// AddOptions must not be called concurrently with Parse.
 	AddOptions(...Option)
 }
 
@@ -486,8 +615,13 @@ type ParagraphTransformer interface {
 }
 
 // ASTTransformer transforms entire Markdown document AST tree.
+// ASTTransformers are called after all blocks and inlines are parsed, in
+// order of their priorities(a smaller value first). So ASTTransformers can
+// add, remove and replace any nodes, for example, to insert a table of
+// contents or to rewrite destinations of images.
 type ASTTransformer interface {
-	// Transform transforms the given AST tree.
+	// Transform transforms the given AST tree. reader.Source() returns the
+	// whole source of the document.
 	Transform(node *ast.Document, reader text.Reader, pc Context)
 }
 
@@ -563,6 +697,10 @@ type parser struct {
 	paragraphTransformers []ParagraphTransformer
 	astTransformers       []ASTTransformer
 	config                *Config
+	tabStop               util.TabStop
+	indentedCodeBlocks    bool
+	maxNestingDepth       int
+	linkReferences        []Reference
 	initSync              sync.Once
 }
 
@@ -622,6 +760,25 @@ func WithASTTransformers(ps ...util.PrioritizedValue) Option {
 	return &withASTTransformers{ps}
 }
 
+type truncateTransformer struct {
+	words int
+}
+
+var ellipsis = []byte("…")
+
+func (t *truncateTransformer) Transform(node *ast.Document, reader text.Reader, pc Context) {
+	ast.Truncate(node, reader.Source(), t.words, ellipsis)
+}
+
+// WithTruncate is a functional option that truncates documents after the
+// given number of words and appends '…' to the last word like
+// '<p>Lorem <em>ipsum…</em></p>'. This is useful for rendering previews.
+// Documents are truncated after ASTTransformers that have priorities
+// less than 10000. See ast.Truncate for details.
+func WithTruncate(words int) Option {
+	return WithASTTransformers(util.Prioritized(&truncateTransformer{words}, 10000))
+}
+
 type withOption struct {
 	name  OptionName
 	value interface{}
@@ -645,8 +802,10 @@ func NewParser(options ...Option) Parser {
 	}
 
 	p := &parser{
-		options: map[OptionName]interface{}{},
-		config:  config,
+		options:            map[OptionName]interface{}{},
+		config:             config,
+		tabStop:            util.DefaultTabStop,
+		indentedCodeBlocks: true,
 	}
 
 	return p
@@ -656,6 +815,7 @@ func (p *parser) AddOptions(opts ...Option) {
 	for _, opt := range opts {
 		opt.SetParserOption(p.config)
 	}
+	p.initSync = sync.Once{}
 }
 
 func (p *parser) addBlockParser(v util.PrioritizedValue, options map[OptionName]interface{}) {
@@ -741,6 +901,19 @@ func WithContext(context Context) ParseOption {
 
 func (p *parser) Parse(reader text.Reader, opts ...ParseOption) ast.Node {
 	p.initSync.Do(func() {
+		p.blockParsers = nil
+		p.inlineParsers = [256][]InlineParser{}
+		p.closeBlockers = nil
+		p.paragraphTransformers = nil
+		p.astTransformers = nil
+		p.tabStop = util.DefaultTabStop
+		p.indentedCodeBlocks = true
+		p.maxNestingDepth = 0
+		p.linkReferences = nil
+		if v, ok := p.config.Options[optIndentedCodeBlocks]; ok && !v.(bool) {
+			p.indentedCodeBlocks = false
+			p.config.BlockParsers = p.config.BlockParsers.Remove(defaultCodeBlockParser)
+		}
 		p.config.BlockParsers.Sort()
 		for _, v := range p.config.BlockParsers {
 			p.addBlockParser(v, p.config.Options)
@@ -757,7 +930,22 @@ func (p *parser) Parse(reader text.Reader, opts ...ParseOption) ast.Node {
 		for _, v := range p.config.ASTTransformers {
 			p.addASTTransformer(v, p.config.Options)
 		}
-		p.config = nil
+		if v, ok := p.config.Options[optTabWidth]; ok && v.(int) > 0 {
+			p.tabStop = util.TabStop(v.(int))
+		}
+		if v, ok := p.config.Options[optMaxNestingDepth]; ok {
+			p.maxNestingDepth = v.(int)
+		}
+		if v, ok := p.config.Options[optLinkReferences]; ok {
+			for label, ref := range v.(map[string]LinkReference) {
+				var title []byte
+				if len(ref.Title) != 0 {
+					title = []byte(ref.Title)
+				}
+				p.linkReferences = append(p.linkReferences,
+					NewReference([]byte(label), []byte(ref.Destination), title))
+			}
+		}
 	})
 	c := &ParseConfig{}
 	for _, opt := range opts {
@@ -768,11 +956,18 @@ func (p *parser) Parse(reader text.Reader, opts ...ParseOption) ast.Node {
 	}
 	pc := c.Context
 	root := ast.NewDocument()
+	reader.SetTabStop(p.tabStop)
 	p.parseBlocks(root, reader, pc)
+	// references in the document are already added and take precedence.
+	for _, ref := range p.linkReferences {
+		pc.AddReference(ref)
+	}
 	blockReader := text.NewBlockReader(reader.Source(), nil)
+	blockReader.SetTabStop(p.tabStop)
 	p.walkBlock(root, func(node ast.Node) {
 		p.parseBlock(blockReader, node, pc)
 	})
+	generateAutoHeadingIDs(reader, pc)
 	for _, at := range p.astTransformers {
 		at.Transform(root, reader, pc)
 	}
@@ -823,26 +1018,39 @@ func (p *parser) openBlocks(parent ast.Node, blankLine bool, reader text.Reader,
 		continuable = ast.IsParagraph(lastBlock.Node)
 	}
 retry:
+	depth := 0
+	if p.maxNestingDepth > 0 {
+		for c := parent; c.Parent() != nil; c = c.Parent() {
+			depth++
+		}
+	}
 	shouldPeek := true
 	//var currentLineNum int
 	var w int
 	var pos int
 	var line []byte
+	var segment text.Segment
 	for _, bp := range p.blockParsers {
 		if shouldPeek {
 			//currentLineNum, _ = reader.Position()
-			line, _ = reader.PeekLine()
-			w, pos = util.IndentWidth(line, 0)
+			line, segment = reader.PeekLine()
+			w, pos = reader.TabStop().IndentWidth(line, reader.LineOffset())
 			pc.SetBlockOffset(pos)
 			shouldPeek = false
-			if line == nil || line[0] == '\n' {
+			if line == nil || line[0] == '\n' || line[0] == '\r' {
 				break
 			}
 		}
 		if continuable && result == noBlocksOpened && !bp.CanInterruptParagraph() {
 			continue
 		}
-		if w > 3 && !bp.CanAcceptIndentedLine() {
+		if w > 3 && !bp.CanAcceptIndentedLine() &&
+			(p.indentedCodeBlocks || bp != defaultParagraphParser) {
+			continue
+		}
+		// lists can not have children other than list items.
+		if p.maxNestingDepth > 0 && depth >= p.maxNestingDepth &&
+			bp != defaultParagraphParser && parent.Kind() != ast.KindList {
 			continue
 		}
 		last := pc.LastOpenedBlock().Node
@@ -853,6 +1061,14 @@ retry:
 		if node != nil {
 			shouldPeek = true
 			node.SetBlankPreviousLines(blankLine)
+			if positioner, ok := node.(ast.Positioner); ok && positioner.Pos() < 0 {
+				// a line may start with padding spaces of a tab.
+				offset := pos - segment.Padding
+				if offset < 0 {
+					offset = 0
+				}
+				positioner.SetPos(segment.Start + offset)
+			}
 			if last != nil && last.Parent() == nil {
 				lastPos := len(pc.OpenedBlocks()) - 1
 				p.closeBlocks(lastPos, lastPos, reader, pc)
@@ -990,6 +1206,7 @@ func (p *parser) parseBlock(block text.BlockReader, parent ast.Node, pc Context)
 	block.Reset(parent.Lines())
 	for {
 	retry:
+		escaped = false
 		line, _ := block.PeekLine()
 		if line == nil {
 			break
@@ -998,9 +1215,10 @@ func (p *parser) parseBlock(block text.BlockReader, parent ast.Node, pc Context)
 		l, startPosition := block.Position()
 		n := 0
 		softLinebreak := false
+		lineEnd := lineLength - util.LineEndingLength(line)
 		for i := 0; i < lineLength; i++ {
 			c := line[i]
-			if c == '\n' {
+			if i == lineEnd {
 				softLinebreak = true
 				break
 			}
@@ -1060,14 +1278,21 @@ func (p *parser) parseBlock(block text.BlockReader, parent ast.Node, pc Context)
 		diff := startPosition.Between(currentPosition)
 		stop := diff.Stop
 		hardlineBreak := false
-		if lineLength > 2 && line[lineLength-2] == '\\' && softLinebreak { // ends with \\n
+		if escaped && softLinebreak { // ends with an unescaped \\n
 			stop--
 			hardlineBreak = true
-		} else if lineLength > 3 && line[lineLength-3] == ' ' && line[lineLength-2] == ' ' && softLinebreak { // ends with [space][space]\n
+		} else if lineEnd > 1 && line[lineEnd-2] == ' ' && line[lineEnd-1] == ' ' && softLinebreak { // ends with [space][space]\n
 			hardlineBreak = true
 		}
 		rest := diff.WithStop(stop)
-		text := ast.NewTextSegment(rest.TrimRightSpace(source))
+		trimmed := rest.TrimRightSpace(source)
+		if trimmed.IsEmpty() {
+			// trailing spaces may be split by inline parsers triggered by spaces.
+			if t, ok := parent.LastChild().(*ast.Text); ok && t.Segment.Stop == rest.Start && !t.IsRaw() {
+				t.Segment = t.Segment.TrimRightSpace(source)
+			}
+		}
+		text := ast.NewTextSegment(trimmed)
 		text.SetSoftLineBreak(softLinebreak)
 		text.SetHardLineBreak(hardlineBreak)
 		parent.AppendChild(parent, text)
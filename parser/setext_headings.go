@@ -84,26 +84,38 @@ func (b *setextHeadingParser) Close(node ast.Node, reader text.Reader, pc Contex
 		segment = segment.TrimLeftSpace(reader.Source())
 		if next == nil || !ast.IsParagraph(next) {
 			para := ast.NewParagraph()
-			para.Lines().Append(segment)
+			para.Lines().Append(segment.TrimRightSpace(reader.Source()))
+			para.SetPos(segment.Start)
 			heading.Parent().InsertAfter(heading.Parent(), heading, para)
 		} else {
 			next.(ast.Node).Lines().Unshift(segment)
 		}
 		heading.Parent().RemoveChild(heading.Parent(), heading)
-	} else {
-		heading.SetLines(tmp.Lines())
-		heading.SetBlankPreviousLines(tmp.HasBlankPreviousLines())
-		tmp.Parent().RemoveChild(tmp.Parent(), tmp)
+		return
 	}
+	heading.SetLines(tmp.Lines())
+	heading.SetBlankPreviousLines(tmp.HasBlankPreviousLines())
+	heading.SetPos(tmp.Pos())
+	// attributes may be already parsed by the AttributeParagraphTransformer
+	for _, attr := range tmp.Attributes() {
+		heading.SetAttribute(attr.Name, attr.Value)
+	}
+	tmp.Parent().RemoveChild(tmp.Parent(), tmp)
 
 	if b.Attribute {
-		parseLastLineAttributes(node, reader, pc)
+		_, ok := node.AttributeString("id")
+		if !ok {
+			parseLastLineAttributes(node, reader, pc)
+		}
+		if id, ok := node.AttributeString("id"); ok {
+			pc.IDs().Put(id)
+		}
 	}
 
 	if b.AutoHeadingID {
 		_, ok := node.AttributeString("id")
 		if !ok {
-			generateAutoHeadingID(heading, reader, pc)
+			addAutoHeadingID(heading, pc)
 		}
 	}
 }
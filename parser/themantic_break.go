@@ -17,8 +17,8 @@ func NewThemanticBreakParser() BlockParser {
 	return defaultThemanticBreakParser
 }
 
-func isThemanticBreak(line []byte) bool {
-	w, pos := util.IndentWidth(line, 0)
+func isThemanticBreak(line []byte, tabStop util.TabStop) bool {
+	w, pos := tabStop.IndentWidth(line, 0)
 	if w > 3 {
 		return false
 	}
@@ -47,7 +47,7 @@ func isThemanticBreak(line []byte) bool {
 
 func (b *themanticBreakParser) Open(parent ast.Node, reader text.Reader, pc Context) (ast.Node, State) {
 	line, segment := reader.PeekLine()
-	if isThemanticBreak(line) {
+	if isThemanticBreak(line, reader.TabStop()) {
 		reader.Advance(segment.Len() - 1)
 		return ast.NewThemanticBreak(), NoChildren
 	}
@@ -3,7 +3,11 @@ package html
 import (
 	"bytes"
 	"fmt"
+	neturl "net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/renderer"
@@ -12,19 +16,77 @@ import (
 
 // A Config struct has configurations for the HTML based renderers.
 type Config struct {
-	Writer    Writer
-	HardWraps bool
-	XHTML     bool
-	Unsafe    bool
+	Writer                  Writer
+	HardWraps               bool
+	XHTML                   bool
+	Unsafe                  bool
+	HeadingAnchors          []byte
+	ExternalLinkTarget      bool
+	ExternalLinkRel         []byte
+	LinkRel                 []byte
+	IsExternalLink          func(destination []byte) bool
+	ImageLoadingLazy        bool
+	ImageDimensions         bool
+	HeadingLevelOffset      int
+	Figures                 bool
+	IgnoreHardLineBreaks    bool
+	URLSanitizer            func(url []byte, isImage bool) []byte
+	CodeBlockWrapper        CodeBlockWrapper
+	CodeLineNumbers         bool
+	CodeLanguagePrefix      string
+	EmailObfuscation        bool
+	CompactOutput           bool
+	CodeSpanNewLines        bool
+	ThematicBreakAttributes []ast.Attribute
+	HeadingNumbers          *HeadingNumbers
+	EmphasisTags            [2]string
+	BaseURL                 *neturl.URL
+	EmptyAltAttributes      []ast.Attribute
+	Comments                CommentHandling
+	CodeBlockMetaAttributes bool
+	LooseLists              bool
+	HeadingHardLineBreaks   bool
+	HTMLAllowlist           map[string][]string
+	AutoIDs                 bool
+	SoftBreakMode           SoftBreakMode
 }
 
 // NewConfig returns a new Config with defaults.
 func NewConfig() Config {
 	return Config{
-		Writer:    DefaultWriter,
-		HardWraps: false,
-		XHTML:     false,
-		Unsafe:    false,
+		Writer:                  DefaultWriter,
+		HardWraps:               false,
+		XHTML:                   false,
+		Unsafe:                  false,
+		HeadingAnchors:          nil,
+		ExternalLinkTarget:      false,
+		ExternalLinkRel:         []byte("noopener noreferrer"),
+		LinkRel:                 nil,
+		IsExternalLink:          IsExternalURL,
+		ImageLoadingLazy:        false,
+		ImageDimensions:         false,
+		HeadingLevelOffset:      0,
+		Figures:                 false,
+		IgnoreHardLineBreaks:    false,
+		URLSanitizer:            nil,
+		CodeBlockWrapper:        CodeBlockWrapper{},
+		CodeLineNumbers:         false,
+		CodeLanguagePrefix:      "language-",
+		EmailObfuscation:        false,
+		CompactOutput:           false,
+		CodeSpanNewLines:        false,
+		ThematicBreakAttributes: nil,
+		HeadingNumbers:          nil,
+		EmphasisTags:            [2]string{"em", "strong"},
+		BaseURL:                 nil,
+		EmptyAltAttributes:      nil,
+		Comments:                CommentsAsRawHTML,
+		CodeBlockMetaAttributes: false,
+		LooseLists:              false,
+		HeadingHardLineBreaks:   false,
+		HTMLAllowlist:           nil,
+		AutoIDs:                 false,
+		SoftBreakMode:           SoftBreakAsNewline,
 	}
 }
 
@@ -39,6 +101,93 @@ func (c *Config) SetOption(name renderer.OptionName, value interface{}) {
 		c.Unsafe = value.(bool)
 	case optTextWriter:
 		c.Writer = value.(Writer)
+	case optHeadingAnchors:
+		c.HeadingAnchors = value.([]byte)
+	case optExternalLinkTarget:
+		c.ExternalLinkTarget = value.(bool)
+	case optExternalLinkRel:
+		c.ExternalLinkRel = value.([]byte)
+	case optLinkRel:
+		c.LinkRel = value.([]byte)
+	case optExternalLinkMatcher:
+		c.IsExternalLink = value.(func([]byte) bool)
+	case optImageLoadingLazy:
+		c.ImageLoadingLazy = value.(bool)
+	case optImageDimensions:
+		c.ImageDimensions = value.(bool)
+	case optHeadingLevelOffset:
+		c.HeadingLevelOffset = value.(int)
+	case optFigures:
+		c.Figures = value.(bool)
+	case optIgnoreHardLineBreaks:
+		c.IgnoreHardLineBreaks = value.(bool)
+	case optURLSanitizer:
+		c.URLSanitizer = value.(func([]byte, bool) []byte)
+	case optCodeBlockWrapper:
+		c.CodeBlockWrapper = value.(CodeBlockWrapper)
+	case optCodeLineNumbers:
+		c.CodeLineNumbers = value.(bool)
+	case optCodeLanguagePrefix:
+		c.CodeLanguagePrefix = value.(string)
+	case optEmailObfuscation:
+		c.EmailObfuscation = value.(bool)
+	case optCompactOutput:
+		c.CompactOutput = value.(bool)
+	case optCodeSpanNewLines:
+		c.CodeSpanNewLines = value.(bool)
+	case optThematicBreakAttributes:
+		c.ThematicBreakAttributes = value.([]ast.Attribute)
+	case optHeadingNumbers:
+		c.HeadingNumbers = value.(*HeadingNumbers)
+	case optEmphasisTags:
+		c.EmphasisTags = value.([2]string)
+	case optBaseURL:
+		c.BaseURL = value.(*neturl.URL)
+	case optEmptyAltAttributes:
+		c.EmptyAltAttributes = value.([]ast.Attribute)
+	case optComments:
+		c.Comments = value.(CommentHandling)
+	case optCodeBlockMetaAttributes:
+		c.CodeBlockMetaAttributes = value.(bool)
+	case optLooseLists:
+		c.LooseLists = value.(bool)
+	case optHeadingHardLineBreaks:
+		c.HeadingHardLineBreaks = value.(bool)
+	case optHTMLAllowlist:
+		c.HTMLAllowlist = value.(map[string][]string)
+	case optAutoIDs:
+		c.AutoIDs = value.(bool)
+	case optSoftBreakMode:
+		c.SoftBreakMode = value.(SoftBreakMode)
+	}
+}
+
+// WriteNewLine writes a newline that only makes the output readable like
+// newlines after block level tags. WriteNewLine writes nothing if
+// CompactOutput is true. Newlines in contents like code blocks must be
+// written directly.
+func (c *Config) WriteNewLine(w util.BufWriter) {
+	if !c.CompactOutput {
+		w.WriteByte('\n')
+	}
+}
+
+// WriteVoidElement writes a void element that has no attributes like '<br>'.
+// The element is self-closed like '<br />' if XHTML is true.
+func (c *Config) WriteVoidElement(w util.BufWriter, name string) {
+	w.WriteByte('<')
+	w.WriteString(name)
+	c.WriteVoidElementEnd(w)
+}
+
+// WriteVoidElementEnd writes an end of a void element whose name and
+// attributes are already written, that is ' />' if XHTML is true,
+// otherwise '>'.
+func (c *Config) WriteVoidElementEnd(w util.BufWriter) {
+	if c.XHTML {
+		w.WriteString(" />")
+	} else {
+		w.WriteByte('>')
 	}
 }
 
@@ -47,97 +196,1046 @@ type Option interface {
 	SetHTMLOption(*Config)
 }
 
-// TextWriter is an option name used in WithWriter.
-const optTextWriter renderer.OptionName = "Writer"
+// TextWriter is an option name used in WithWriter.
+const optTextWriter renderer.OptionName = "Writer"
+
+type withWriter struct {
+	value Writer
+}
+
+func (o *withWriter) SetConfig(c *renderer.Config) {
+	c.Options[optTextWriter] = o.value
+}
+
+func (o *withWriter) SetHTMLOption(c *Config) {
+	c.Writer = o.value
+}
+
+// WithWriter is a functional option that allow you to set the given writer to
+// the renderer.
+func WithWriter(writer Writer) interface {
+	renderer.Option
+	Option
+} {
+	return &withWriter{writer}
+}
+
+// HardWraps is an option name used in WithHardWraps.
+const optHardWraps renderer.OptionName = "HardWraps"
+
+type withHardWraps struct {
+}
+
+func (o *withHardWraps) SetConfig(c *renderer.Config) {
+	c.Options[optHardWraps] = true
+}
+
+func (o *withHardWraps) SetHTMLOption(c *Config) {
+	c.HardWraps = true
+}
+
+// WithHardWraps is a functional option that indicates whether softline breaks
+// should be rendered as '<br>'.
+// WithHardWraps is an alias of WithSoftBreakMode(SoftBreakAsBR) and takes
+// precedence over WithSoftBreakMode.
+func WithHardWraps() interface {
+	renderer.Option
+	Option
+} {
+	return &withHardWraps{}
+}
+
+// A SoftBreakMode represents how soft line breaks are rendered.
+type SoftBreakMode int
+
+const (
+	// SoftBreakAsNewline renders soft line breaks as new lines.
+	SoftBreakAsNewline SoftBreakMode = iota
+
+	// SoftBreakAsSpace renders soft line breaks as spaces like web browsers
+	// display them.
+	SoftBreakAsSpace
+
+	// SoftBreakAsBR renders soft line breaks as '<br>' like WithHardWraps.
+	SoftBreakAsBR
+)
+
+// SoftBreakMode is an option name used in WithSoftBreakMode.
+const optSoftBreakMode renderer.OptionName = "SoftBreakMode"
+
+type withSoftBreakMode struct {
+	value SoftBreakMode
+}
+
+func (o *withSoftBreakMode) SetConfig(c *renderer.Config) {
+	c.Options[optSoftBreakMode] = o.value
+}
+
+func (o *withSoftBreakMode) SetHTMLOption(c *Config) {
+	c.SoftBreakMode = o.value
+}
+
+// WithSoftBreakMode is a functional option that specifies how soft line
+// breaks are rendered. The default is SoftBreakAsNewline.
+func WithSoftBreakMode(mode SoftBreakMode) interface {
+	renderer.Option
+	Option
+} {
+	return &withSoftBreakMode{mode}
+}
+
+// XHTML is an option name used in WithXHTML.
+const optXHTML renderer.OptionName = "XHTML"
+
+type withXHTML struct {
+}
+
+func (o *withXHTML) SetConfig(c *renderer.Config) {
+	c.Options[optXHTML] = true
+}
+
+func (o *withXHTML) SetHTMLOption(c *Config) {
+	c.XHTML = true
+}
+
+// WithXHTML is a functional option indicates that nodes should be rendered in
+// xhtml instead of HTML5.
+func WithXHTML() interface {
+	Option
+	renderer.Option
+} {
+	return &withXHTML{}
+}
+
+// Unsafe is an option name used in WithUnsafe.
+const optUnsafe renderer.OptionName = "Unsafe"
+
+type withUnsafe struct {
+}
+
+func (o *withUnsafe) SetConfig(c *renderer.Config) {
+	c.Options[optUnsafe] = true
+}
+
+func (o *withUnsafe) SetHTMLOption(c *Config) {
+	c.Unsafe = true
+}
+
+// WithUnsafe is a functional option that renders dangerous contents
+// (raw htmls and potentially dangerous links) as it is.
+func WithUnsafe() interface {
+	renderer.Option
+	Option
+} {
+	return &withUnsafe{}
+}
+
+// HeadingAnchors is an option name used in WithHeadingAnchors.
+const optHeadingAnchors renderer.OptionName = "HeadingAnchors"
+
+type withHeadingAnchors struct {
+	value []byte
+}
+
+func (o *withHeadingAnchors) SetConfig(c *renderer.Config) {
+	c.Options[optHeadingAnchors] = o.value
+}
+
+func (o *withHeadingAnchors) SetHTMLOption(c *Config) {
+	c.HeadingAnchors = o.value
+}
+
+// WithHeadingAnchors is a functional option that renders a permalink anchor
+// like '<a class="anchor" href="#id">symbol</a>' inside headings that have
+// an id attribute.
+func WithHeadingAnchors(symbol string) interface {
+	renderer.Option
+	Option
+} {
+	return &withHeadingAnchors{[]byte(symbol)}
+}
+
+// ExternalLinkTarget is an option name used in WithExternalLinkTarget.
+const optExternalLinkTarget renderer.OptionName = "ExternalLinkTarget"
+
+type withExternalLinkTarget struct {
+}
+
+func (o *withExternalLinkTarget) SetConfig(c *renderer.Config) {
+	c.Options[optExternalLinkTarget] = true
+}
+
+func (o *withExternalLinkTarget) SetHTMLOption(c *Config) {
+	c.ExternalLinkTarget = true
+}
+
+// WithExternalLinkTarget is a functional option that adds target="_blank" and
+// rel="noopener noreferrer" to links that point to external sites.
+// The rel value can be changed by WithExternalLinkRel, and what counts as
+// an external link can be changed by WithExternalLinkMatcher.
+func WithExternalLinkTarget() interface {
+	renderer.Option
+	Option
+} {
+	return &withExternalLinkTarget{}
+}
+
+// ExternalLinkRel is an option name used in WithExternalLinkRel.
+const optExternalLinkRel renderer.OptionName = "ExternalLinkRel"
+
+type withExternalLinkRel struct {
+	value []byte
+}
+
+func (o *withExternalLinkRel) SetConfig(c *renderer.Config) {
+	c.Options[optExternalLinkRel] = o.value
+}
+
+func (o *withExternalLinkRel) SetHTMLOption(c *Config) {
+	c.ExternalLinkRel = o.value
+}
+
+// WithExternalLinkRel is a functional option that sets a rel value for
+// external links. An empty string disables rel attributes.
+func WithExternalLinkRel(rel string) interface {
+	renderer.Option
+	Option
+} {
+	return &withExternalLinkRel{[]byte(rel)}
+}
+
+// LinkRel is an option name used in WithLinkRel.
+const optLinkRel renderer.OptionName = "LinkRel"
+
+type withLinkRel struct {
+	value []byte
+}
+
+func (o *withLinkRel) SetConfig(c *renderer.Config) {
+	c.Options[optLinkRel] = o.value
+}
+
+func (o *withLinkRel) SetHTMLOption(c *Config) {
+	c.LinkRel = o.value
+}
+
+// WithLinkRel is a functional option that adds the given rel value like
+// 'nofollow ugc' to all links and autolinks except links to fragments like
+// '#section' and email autolinks. If WithExternalLinkTarget is also enabled,
+// rel values of external links are merged without duplicates like
+// 'noopener noreferrer nofollow ugc'.
+func WithLinkRel(rel string) interface {
+	renderer.Option
+	Option
+} {
+	return &withLinkRel{[]byte(rel)}
+}
+
+// ExternalLinkMatcher is an option name used in WithExternalLinkMatcher.
+const optExternalLinkMatcher renderer.OptionName = "ExternalLinkMatcher"
+
+type withExternalLinkMatcher struct {
+	value func([]byte) bool
+}
+
+func (o *withExternalLinkMatcher) SetConfig(c *renderer.Config) {
+	c.Options[optExternalLinkMatcher] = o.value
+}
+
+func (o *withExternalLinkMatcher) SetHTMLOption(c *Config) {
+	c.IsExternalLink = o.value
+}
+
+// WithExternalLinkMatcher is a functional option that sets a function that
+// decides whether the given link destination is an external link.
+// By default, links that have a scheme and a host(see IsExternalURL) are
+// external links.
+func WithExternalLinkMatcher(f func(destination []byte) bool) interface {
+	renderer.Option
+	Option
+} {
+	return &withExternalLinkMatcher{f}
+}
+
+// ImageLoadingLazy is an option name used in WithImageLoadingLazy.
+const optImageLoadingLazy renderer.OptionName = "ImageLoadingLazy"
+
+type withImageLoadingLazy struct {
+}
+
+func (o *withImageLoadingLazy) SetConfig(c *renderer.Config) {
+	c.Options[optImageLoadingLazy] = true
+}
+
+func (o *withImageLoadingLazy) SetHTMLOption(c *Config) {
+	c.ImageLoadingLazy = true
+}
+
+// WithImageLoadingLazy is a functional option that adds loading="lazy" and
+// decoding="async" to images.
+func WithImageLoadingLazy() interface {
+	renderer.Option
+	Option
+} {
+	return &withImageLoadingLazy{}
+}
+
+// ImageDimensions is an option name used in WithImageDimensions.
+const optImageDimensions renderer.OptionName = "ImageDimensions"
+
+type withImageDimensions struct {
+}
+
+func (o *withImageDimensions) SetConfig(c *renderer.Config) {
+	c.Options[optImageDimensions] = true
+}
+
+func (o *withImageDimensions) SetHTMLOption(c *Config) {
+	c.ImageDimensions = true
+}
+
+// WithImageDimensions is a functional option that renders dimensions like
+// '=200x100' at the end of image titles or destinations as width and height
+// attributes.
+func WithImageDimensions() interface {
+	renderer.Option
+	Option
+} {
+	return &withImageDimensions{}
+}
+
+// HeadingLevelOffset is an option name used in WithHeadingLevelOffset.
+const optHeadingLevelOffset renderer.OptionName = "HeadingLevelOffset"
+
+type withHeadingLevelOffset struct {
+	value int
+}
+
+func (o *withHeadingLevelOffset) SetConfig(c *renderer.Config) {
+	c.Options[optHeadingLevelOffset] = o.value
+}
+
+func (o *withHeadingLevelOffset) SetHTMLOption(c *Config) {
+	c.HeadingLevelOffset = o.value
+}
+
+// WithHeadingLevelOffset is a functional option that adds the given offset
+// to heading levels. For example, '# foo' is rendered as '<h2>foo</h2>' with
+// an offset 1. Offsetted levels are clamped to the range 1-6.
+func WithHeadingLevelOffset(offset int) interface {
+	renderer.Option
+	Option
+} {
+	return &withHeadingLevelOffset{offset}
+}
+
+// Figures is an option name used in WithFigures.
+const optFigures renderer.OptionName = "Figures"
+
+type withFigures struct {
+}
+
+func (o *withFigures) SetConfig(c *renderer.Config) {
+	c.Options[optFigures] = true
+}
+
+func (o *withFigures) SetHTMLOption(c *Config) {
+	c.Figures = true
+}
+
+// WithFigures is a functional option that renders paragraphs that contain
+// only an image as '<figure>' elements. Alt texts(or titles if images do not
+// have alt texts) are rendered as '<figcaption>' elements.
+func WithFigures() interface {
+	renderer.Option
+	Option
+} {
+	return &withFigures{}
+}
+
+// IgnoreHardLineBreaks is an option name used in WithoutHardLineBreaks.
+const optIgnoreHardLineBreaks renderer.OptionName = "IgnoreHardLineBreaks"
+
+type withoutHardLineBreaks struct {
+}
+
+func (o *withoutHardLineBreaks) SetConfig(c *renderer.Config) {
+	c.Options[optIgnoreHardLineBreaks] = true
+}
+
+func (o *withoutHardLineBreaks) SetHTMLOption(c *Config) {
+	c.IgnoreHardLineBreaks = true
+}
+
+// WithoutHardLineBreaks is a functional option that renders hard line
+// breaks(two or more trailing spaces or a trailing backslash) as soft line
+// breaks. This option does not affect soft line breaks, so hard line breaks
+// are still rendered as '<br>' with WithHardWraps.
+func WithoutHardLineBreaks() interface {
+	renderer.Option
+	Option
+} {
+	return &withoutHardLineBreaks{}
+}
+
+// URLSanitizer is an option name used in WithURLSanitizer.
+const optURLSanitizer renderer.OptionName = "URLSanitizer"
+
+type withURLSanitizer struct {
+	value func([]byte, bool) []byte
+}
+
+func (o *withURLSanitizer) SetConfig(c *renderer.Config) {
+	c.Options[optURLSanitizer] = o.value
+}
+
+func (o *withURLSanitizer) SetHTMLOption(c *Config) {
+	c.URLSanitizer = o.value
+}
+
+// WithURLSanitizer is a functional option that sets a function that
+// sanitizes urls of links, images and autolinks. The function receives an
+// unescaped url and returns a url to be rendered. It can reject a url by
+// returning nil, or rewrite it.
+// The given function is called even if WithUnsafe is specified.
+// By default, potentially dangerous urls(see IsDangerousURL) are rendered as
+// empty strings unless WithUnsafe is specified.
+func WithURLSanitizer(f func(url []byte, isImage bool) []byte) interface {
+	renderer.Option
+	Option
+} {
+	return &withURLSanitizer{f}
+}
+
+// A CodeBlockWrapper struct describes how fenced code blocks are rendered.
+// The zero value renders fenced code blocks as
+// '<pre><code class="language-go">'.
+type CodeBlockWrapper struct {
+	// LanguageClassOnPre renders the language class on the 'pre' element
+	// instead of the 'code' element.
+	LanguageClassOnPre bool
+
+	// Tag is a name of an element that wraps the 'pre' element like "div".
+	// If Tag is empty, no wrapper element is rendered.
+	Tag string
+
+	// Class is a class of the wrapper element like "highlight".
+	Class string
+}
+
+// CodeBlockWrapper is an option name used in WithCodeBlockWrapper.
+const optCodeBlockWrapper renderer.OptionName = "CodeBlockWrapper"
+
+type withCodeBlockWrapper struct {
+	value CodeBlockWrapper
+}
+
+func (o *withCodeBlockWrapper) SetConfig(c *renderer.Config) {
+	c.Options[optCodeBlockWrapper] = o.value
+}
+
+func (o *withCodeBlockWrapper) SetHTMLOption(c *Config) {
+	c.CodeBlockWrapper = o.value
+}
+
+// WithCodeBlockWrapper is a functional option that controls where the
+// language class of fenced code blocks is rendered and whether fenced
+// code blocks are wrapped by an element.
+func WithCodeBlockWrapper(wrapper CodeBlockWrapper) interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeBlockWrapper{wrapper}
+}
+
+// CodeLineNumbers is an option name used in WithCodeLineNumbers.
+const optCodeLineNumbers renderer.OptionName = "CodeLineNumbers"
+
+type withCodeLineNumbers struct {
+}
+
+func (o *withCodeLineNumbers) SetConfig(c *renderer.Config) {
+	c.Options[optCodeLineNumbers] = true
+}
+
+func (o *withCodeLineNumbers) SetHTMLOption(c *Config) {
+	c.CodeLineNumbers = true
+}
+
+// WithCodeLineNumbers is a functional option that wraps each line of code
+// blocks in a '<span class="line" data-line="N">' element.
+// Line numbers start at 1, or at the number specified in the info string
+// of fenced code blocks like '```go {start:10}'.
+func WithCodeLineNumbers() interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeLineNumbers{}
+}
+
+// CodeLanguagePrefix is an option name used in WithCodeLanguagePrefix.
+const optCodeLanguagePrefix renderer.OptionName = "CodeLanguagePrefix"
+
+type withCodeLanguagePrefix struct {
+	value string
+}
+
+func (o *withCodeLanguagePrefix) SetConfig(c *renderer.Config) {
+	c.Options[optCodeLanguagePrefix] = o.value
+}
+
+func (o *withCodeLanguagePrefix) SetHTMLOption(c *Config) {
+	c.CodeLanguagePrefix = o.value
+}
+
+// WithCodeLanguagePrefix is a functional option that sets a prefix of
+// language classes of fenced code blocks. The default prefix is 'language-'.
+// An empty prefix renders classes like 'class="go"'.
+func WithCodeLanguagePrefix(prefix string) interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeLanguagePrefix{prefix}
+}
+
+// EmailObfuscation is an option name used in WithEmailObfuscation.
+const optEmailObfuscation renderer.OptionName = "EmailObfuscation"
+
+type withEmailObfuscation struct {
+}
+
+func (o *withEmailObfuscation) SetConfig(c *renderer.Config) {
+	c.Options[optEmailObfuscation] = true
+}
+
+func (o *withEmailObfuscation) SetHTMLOption(c *Config) {
+	c.EmailObfuscation = true
+}
+
+// WithEmailObfuscation is a functional option that renders email autolinks
+// with every character encoded as a numeric character reference like
+// '&#109;'. This makes harvesting email addresses harder.
+func WithEmailObfuscation() interface {
+	renderer.Option
+	Option
+} {
+	return &withEmailObfuscation{}
+}
+
+// CompactOutput is an option name used in WithCompactOutput.
+const optCompactOutput renderer.OptionName = "CompactOutput"
+
+type withCompactOutput struct {
+}
+
+func (o *withCompactOutput) SetConfig(c *renderer.Config) {
+	c.Options[optCompactOutput] = true
+}
+
+func (o *withCompactOutput) SetHTMLOption(c *Config) {
+	c.CompactOutput = true
+}
+
+// WithCompactOutput is a functional option that suppresses newlines that
+// only make the output readable like newlines after '</p>'.
+// Newlines in code blocks, raw HTML and texts are kept as they are.
+func WithCompactOutput() interface {
+	renderer.Option
+	Option
+} {
+	return &withCompactOutput{}
+}
+
+// CodeSpanNewLines is an option name used in WithCodeSpanNewLines.
+const optCodeSpanNewLines renderer.OptionName = "CodeSpanNewLines"
+
+type withCodeSpanNewLines struct {
+}
+
+func (o *withCodeSpanNewLines) SetConfig(c *renderer.Config) {
+	c.Options[optCodeSpanNewLines] = true
+}
+
+func (o *withCodeSpanNewLines) SetHTMLOption(c *Config) {
+	c.CodeSpanNewLines = true
+}
+
+// WithCodeSpanNewLines is a functional option that renders line endings in
+// code spans as they are. By default, line endings in code spans are
+// converted into spaces as CommonMark specifies.
+func WithCodeSpanNewLines() interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeSpanNewLines{}
+}
+
+// ThematicBreakAttributes is an option name used in WithThematicBreakAttributes.
+const optThematicBreakAttributes renderer.OptionName = "ThematicBreakAttributes"
+
+type withThematicBreakAttributes struct {
+	value []ast.Attribute
+}
+
+func (o *withThematicBreakAttributes) SetConfig(c *renderer.Config) {
+	c.Options[optThematicBreakAttributes] = o.value
+}
+
+func (o *withThematicBreakAttributes) SetHTMLOption(c *Config) {
+	c.ThematicBreakAttributes = o.value
+}
+
+// WithThematicBreakAttributes is a functional option that adds the given
+// attributes to thematic breaks like '<hr class="section-break">'.
+// Attributes set to the node take precedence over these attributes.
+func WithThematicBreakAttributes(attributes map[string]string) interface {
+	renderer.Option
+	Option
+} {
+	return &withThematicBreakAttributes{attributesFromMap(attributes)}
+}
+
+// attributesFromMap returns attributes sorted by their names, so that
+// attributes are rendered in a stable order.
+func attributesFromMap(attributes map[string]string) []ast.Attribute {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	value := make([]ast.Attribute, 0, len(names))
+	for _, name := range names {
+		value = append(value, ast.Attribute{
+			Name:  []byte(name),
+			Value: []byte(attributes[name]),
+		})
+	}
+	return value
+}
+
+// EmptyAltAttributes is an option name used in WithEmptyAltAttributes.
+const optEmptyAltAttributes renderer.OptionName = "EmptyAltAttributes"
+
+type withEmptyAltAttributes struct {
+	value []ast.Attribute
+}
+
+func (o *withEmptyAltAttributes) SetConfig(c *renderer.Config) {
+	c.Options[optEmptyAltAttributes] = o.value
+}
+
+func (o *withEmptyAltAttributes) SetHTMLOption(c *Config) {
+	c.EmptyAltAttributes = o.value
+}
+
+// WithEmptyAltAttributes is a functional option that adds the given
+// attributes like 'role="presentation"' to images that have empty alt
+// texts, so that screen readers treat them as decorative images.
+// See ast.ImagesWithoutAlt for finding such images.
+func WithEmptyAltAttributes(attributes map[string]string) interface {
+	renderer.Option
+	Option
+} {
+	return &withEmptyAltAttributes{attributesFromMap(attributes)}
+}
+
+// A HeadingNumbers struct describes how hierarchical section numbers like
+// '1.2' are rendered at the beginning of headings.
+// Only headings that are direct children of the document are numbered.
+// The shallowest heading level in the document is numbered as the
+// first level.
+type HeadingNumbers struct {
+	// Separator is a string between numbers like '.' in '1.2'.
+	// '.' is used if it is empty.
+	Separator string
+
+	// TrailingDot is true if numbers should be followed by a '.' like '1.2.'.
+	TrailingDot bool
+}
+
+// HeadingNumbers is an option name used in WithHeadingNumbers.
+const optHeadingNumbers renderer.OptionName = "HeadingNumbers"
+
+type withHeadingNumbers struct {
+	value *HeadingNumbers
+}
+
+func (o *withHeadingNumbers) SetConfig(c *renderer.Config) {
+	c.Options[optHeadingNumbers] = o.value
+}
+
+func (o *withHeadingNumbers) SetHTMLOption(c *Config) {
+	c.HeadingNumbers = o.value
+}
+
+// WithHeadingNumbers is a functional option that renders hierarchical
+// section numbers like '1', '1.1' and '2' at the beginning of headings.
+func WithHeadingNumbers(numbers HeadingNumbers) interface {
+	renderer.Option
+	Option
+} {
+	return &withHeadingNumbers{&numbers}
+}
+
+// EmphasisTags is an option name used in WithEmphasisTags.
+const optEmphasisTags renderer.OptionName = "EmphasisTags"
+
+type withEmphasisTags struct {
+	value [2]string
+}
+
+func (o *withEmphasisTags) SetConfig(c *renderer.Config) {
+	c.Options[optEmphasisTags] = o.value
+}
+
+func (o *withEmphasisTags) SetHTMLOption(c *Config) {
+	c.EmphasisTags = o.value
+}
+
+// WithEmphasisTags is a functional option that specifies element names of
+// emphases like 'i' for '*text*' and 'b' for '**text**'.
+// The defaults are 'em' and 'strong'. Names must match
+// [A-Za-z][A-Za-z0-9-]*, the defaults are used instead of invalid names.
+func WithEmphasisTags(emphasis, strong string) interface {
+	renderer.Option
+	Option
+} {
+	tags := [2]string{"em", "strong"}
+	if isElementName(emphasis) {
+		tags[0] = emphasis
+	}
+	if isElementName(strong) {
+		tags[1] = strong
+	}
+	return &withEmphasisTags{tags}
+}
+
+// isElementName returns true if the given name matches [A-Za-z][A-Za-z0-9-]*.
+func isElementName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			i != 0 && (c >= '0' && c <= '9' || c == '-') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// BaseURL is an option name used in WithBaseURL.
+const optBaseURL renderer.OptionName = "BaseURL"
+
+type withBaseURL struct {
+	value *neturl.URL
+}
+
+func (o *withBaseURL) SetConfig(c *renderer.Config) {
+	c.Options[optBaseURL] = o.value
+}
+
+func (o *withBaseURL) SetHTMLOption(c *Config) {
+	c.BaseURL = o.value
+}
+
+// WithBaseURL is a functional option that resolves relative destinations
+// of links and images against the given base URL like 'docs/' and
+// 'https://example.com/docs/'. Absolute URLs, protocol-relative URLs and
+// fragments like '#section' are rendered as they are.
+// URLs are resolved by net/url, so a base URL should end with '/' if it is
+// a directory. WithBaseURL panics if the given base URL is invalid.
+func WithBaseURL(base string) interface {
+	renderer.Option
+	Option
+} {
+	u, err := neturl.Parse(base)
+	if err != nil {
+		panic(fmt.Sprintf("html: invalid base URL: %v", err))
+	}
+	return &withBaseURL{u}
+}
+
+// CommentHandling indicates how HTML comments like '<!-- note -->' are
+// rendered.
+type CommentHandling int
+
+const (
+	// CommentsAsRawHTML renders HTML comments like other raw HTMLs, so
+	// comments are rendered only if WithUnsafe is enabled.
+	CommentsAsRawHTML CommentHandling = iota
+
+	// CommentsStripped does not render HTML comments at all.
+	CommentsStripped
+
+	// CommentsPreserved renders HTML comments as they are even if
+	// WithUnsafe is not enabled.
+	CommentsPreserved
+)
+
+// Comments is an option name used in WithStripComments and
+// WithPreserveComments.
+const optComments renderer.OptionName = "Comments"
+
+type withComments struct {
+	value CommentHandling
+}
+
+func (o *withComments) SetConfig(c *renderer.Config) {
+	c.Options[optComments] = o.value
+}
 
-type withWriter struct {
-	value Writer
+func (o *withComments) SetHTMLOption(c *Config) {
+	c.Comments = o.value
 }
 
-func (o *withWriter) SetConfig(c *renderer.Config) {
-	c.Options[optTextWriter] = o.value
+// WithStripComments is a functional option that removes HTML comments like
+// '<!-- note -->' from the output regardless of WithUnsafe.
+// Both inline comments and HTML blocks that consist of only a comment are
+// removed.
+func WithStripComments() interface {
+	renderer.Option
+	Option
+} {
+	return &withComments{CommentsStripped}
 }
 
-func (o *withWriter) SetHTMLOption(c *Config) {
-	c.Writer = o.value
+// WithPreserveComments is a functional option that renders HTML comments
+// like '<!-- note -->' as they are regardless of WithUnsafe.
+// Both inline comments and HTML blocks that consist of only a comment are
+// rendered. Comments that browsers may close early, like '<!-->' and
+// '<!-- a --!>', are handled as raw HTML unless WithUnsafe is enabled.
+func WithPreserveComments() interface {
+	renderer.Option
+	Option
+} {
+	return &withComments{CommentsPreserved}
 }
 
-// WithWriter is a functional option that allow you to set the given writer to
-// the renderer.
-func WithWriter(writer Writer) interface {
+// CodeBlockMetaAttributes is an option name used in
+// WithCodeBlockMetaAttributes.
+const optCodeBlockMetaAttributes renderer.OptionName = "CodeBlockMetaAttributes"
+
+type withCodeBlockMetaAttributes struct {
+}
+
+func (o *withCodeBlockMetaAttributes) SetConfig(c *renderer.Config) {
+	c.Options[optCodeBlockMetaAttributes] = true
+}
+
+func (o *withCodeBlockMetaAttributes) SetHTMLOption(c *Config) {
+	c.CodeBlockMetaAttributes = true
+}
+
+// WithCodeBlockMetaAttributes is a functional option that renders attributes
+// written in the info string meta of fenced code blocks like
+// '```go {data-file="main.go" title="main"}'. The attributes are rendered on
+// the element that has the language class.
+// Only 'title' and 'data-*' attributes are rendered, others are ignored.
+func WithCodeBlockMetaAttributes() interface {
 	renderer.Option
 	Option
 } {
-	return &withWriter{writer}
+	return &withCodeBlockMetaAttributes{}
 }
 
-// HardWraps is an option name used in WithHardWraps.
-const optHardWraps renderer.OptionName = "HardWraps"
+// LooseLists is an option name used in WithLooseLists.
+const optLooseLists renderer.OptionName = "LooseLists"
 
-type withHardWraps struct {
+type withLooseLists struct {
 }
 
-func (o *withHardWraps) SetConfig(c *renderer.Config) {
-	c.Options[optHardWraps] = true
+func (o *withLooseLists) SetConfig(c *renderer.Config) {
+	c.Options[optLooseLists] = true
 }
 
-func (o *withHardWraps) SetHTMLOption(c *Config) {
-	c.HardWraps = true
+func (o *withLooseLists) SetHTMLOption(c *Config) {
+	c.LooseLists = true
 }
 
-// WithHardWraps is a functional option that indicates whether softline breaks
-// should be rendered as '<br>'.
-func WithHardWraps() interface {
+// WithLooseLists is a functional option that renders all lists as loose
+// lists, so texts in list items are always wrapped in '<p>' elements like
+// '<li>\n<p>item</p>\n</li>'.
+// This option deviates from outputs that CommonMark specifies for tight
+// lists. It is for consistent presentation and does not affect parsing.
+func WithLooseLists() interface {
 	renderer.Option
 	Option
 } {
-	return &withHardWraps{}
+	return &withLooseLists{}
 }
 
-// XHTML is an option name used in WithXHTML.
-const optXHTML renderer.OptionName = "XHTML"
+// HeadingHardLineBreaks is an option name used in WithHeadingHardLineBreaks.
+const optHeadingHardLineBreaks renderer.OptionName = "HeadingHardLineBreaks"
 
-type withXHTML struct {
+type withHeadingHardLineBreaks struct {
 }
 
-func (o *withXHTML) SetConfig(c *renderer.Config) {
-	c.Options[optXHTML] = true
+func (o *withHeadingHardLineBreaks) SetConfig(c *renderer.Config) {
+	c.Options[optHeadingHardLineBreaks] = true
 }
 
-func (o *withXHTML) SetHTMLOption(c *Config) {
-	c.XHTML = true
+func (o *withHeadingHardLineBreaks) SetHTMLOption(c *Config) {
+	c.HeadingHardLineBreaks = true
 }
 
-// WithXHTML is a functional option indicates that nodes should be rendered in
-// xhtml instead of HTML5.
-func WithXHTML() interface {
+// WithHeadingHardLineBreaks is a functional option that renders line breaks
+// in headings as '<br>' like line breaks in paragraphs. By default, hard line
+// breaks in multi-line setext headings and soft line breaks with
+// WithHardWraps are rendered as soft line breaks.
+func WithHeadingHardLineBreaks() interface {
+	renderer.Option
 	Option
+} {
+	return &withHeadingHardLineBreaks{}
+}
+
+// HTMLAllowlist is an option name used in WithHTMLAllowlist.
+const optHTMLAllowlist renderer.OptionName = "HTMLAllowlist"
+
+type withHTMLAllowlist struct {
+	value map[string][]string
+}
+
+func (o *withHTMLAllowlist) SetConfig(c *renderer.Config) {
+	c.Options[optHTMLAllowlist] = o.value
+}
+
+func (o *withHTMLAllowlist) SetHTMLOption(c *Config) {
+	c.HTMLAllowlist = o.value
+}
+
+// WithHTMLAllowlist is a functional option that renders raw HTML with only
+// the given tags and attributes. Keys of the map are lower-cased tag names
+// and values are lower-cased attribute names allowed for the tag.
+// Disallowed tags, comments and declarations are rendered as escaped texts
+// and disallowed attributes are dropped. URL attributes like href and src
+// are dropped unless they are relative URLs or URLs with the http, https or
+// mailto scheme.
+// This option takes precedence over WithUnsafe for raw HTML.
+func WithHTMLAllowlist(tags map[string][]string) interface {
 	renderer.Option
+	Option
 } {
-	return &withXHTML{}
+	return &withHTMLAllowlist{tags}
 }
 
-// Unsafe is an option name used in WithUnsafe.
-const optUnsafe renderer.OptionName = "Unsafe"
+// AutoIDs is an option name used in WithAutoIDs.
+const optAutoIDs renderer.OptionName = "AutoIDs"
 
-type withUnsafe struct {
+type withAutoIDs struct {
 }
 
-func (o *withUnsafe) SetConfig(c *renderer.Config) {
-	c.Options[optUnsafe] = true
+func (o *withAutoIDs) SetConfig(c *renderer.Config) {
+	c.Options[optAutoIDs] = true
 }
 
-func (o *withUnsafe) SetHTMLOption(c *Config) {
-	c.Unsafe = true
+func (o *withAutoIDs) SetHTMLOption(c *Config) {
+	c.AutoIDs = true
 }
 
-// WithUnsafe is a functional option that renders dangerous contents
-// (raw htmls and potentially dangerous links) as it is.
-func WithUnsafe() interface {
+// WithAutoIDs is a functional option that renders ids generated from texts
+// of headings that have no ids. Ids are generated in the same way as
+// parser.WithAutoHeadingID and are unique in the document.
+// Ids set by the parser take precedence.
+func WithAutoIDs() interface {
 	renderer.Option
 	Option
 } {
-	return &withUnsafe{}
+	return &withAutoIDs{}
+}
+
+// A StylesheetFeature represents a feature of the bundled extensions that
+// renders classes, like 'task-list-item' for task lists.
+type StylesheetFeature int
+
+const (
+	// StylesheetTaskLists is a StylesheetFeature for the TaskList extension.
+	StylesheetTaskLists StylesheetFeature = iota
+	// StylesheetFootnotes is a StylesheetFeature for the Footnote extension.
+	StylesheetFootnotes
+	// StylesheetAdmonitions is a StylesheetFeature for the Admonition
+	// extension.
+	StylesheetAdmonitions
+	// StylesheetMath is a StylesheetFeature for the Math extension.
+	StylesheetMath
+	// StylesheetEmoji is a StylesheetFeature for the Emoji extension that
+	// renders images.
+	StylesheetEmoji
+	// StylesheetWikiLinks is a StylesheetFeature for the WikiLink extension.
+	StylesheetWikiLinks
+)
+
+var stylesheetFeatureRules = map[StylesheetFeature][]string{
+	StylesheetTaskLists: {
+		".task-list-item { list-style-type: none; }",
+		".task-list-item input[type=\"checkbox\"] { margin: 0 .25em 0 -1.5em; }",
+	},
+	StylesheetFootnotes: {
+		".footnotes { font-size: .9em; }",
+		".footnote-ref, .footnote-backref { text-decoration: none; }",
+	},
+	StylesheetAdmonitions: {
+		".admonition { border-left: .25em solid #999; padding: 0 1em; }",
+		".admonition-title { font-weight: bold; }",
+	},
+	StylesheetMath: {
+		".math.display { display: block; text-align: center; }",
+	},
+	StylesheetEmoji: {
+		"img.emoji { width: 1em; height: 1em; vertical-align: -.1em; }",
+	},
+	StylesheetWikiLinks: {
+		"a.new { color: #ba0000; }",
+	},
+}
+
+// DefaultStylesheet returns a minimal stylesheet for classes rendered by
+// renderers configured with the given options like 'anchor' for
+// WithHeadingAnchors and 'line' for WithCodeLineNumbers. Rules for classes
+// of the bundled extensions are included only for the given features,
+// because this package does not know which extensions are enabled.
+func DefaultStylesheet(features []StylesheetFeature, opts ...Option) string {
+	c := NewConfig()
+	for _, opt := range opts {
+		opt.SetHTMLOption(&c)
+	}
+	var b strings.Builder
+	b.WriteString("pre { overflow-x: auto; }\n")
+	b.WriteString("code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; }\n")
+	if wrapper := c.CodeBlockWrapper; len(wrapper.Tag) != 0 {
+		b.WriteString(wrapper.Tag)
+		if len(wrapper.Class) != 0 {
+			b.WriteByte('.')
+			b.WriteString(strings.Join(strings.Fields(wrapper.Class), "."))
+		}
+		b.WriteString(" > pre { margin: 0; }\n")
+	}
+	if c.CodeLineNumbers {
+		b.WriteString(".line::before { content: attr(data-line); display: inline-block; width: 2.5em; margin-right: 1em; text-align: right; color: #999; user-select: none; }\n")
+	}
+	if c.HeadingAnchors != nil {
+		b.WriteString(".anchor { margin-left: .25em; text-decoration: none; visibility: hidden; }\n")
+		b.WriteString("h1:hover .anchor, h2:hover .anchor, h3:hover .anchor, h4:hover .anchor, h5:hover .anchor, h6:hover .anchor { visibility: visible; }\n")
+	}
+	if c.Figures {
+		b.WriteString("figure { margin: 1em 0; }\n")
+		b.WriteString("figcaption { font-size: .9em; color: #666; }\n")
+	}
+	written := map[StylesheetFeature]bool{}
+	for _, feature := range features {
+		if written[feature] {
+			continue
+		}
+		written[feature] = true
+		for _, rule := range stylesheetFeatureRules[feature] {
+			b.WriteString(rule)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
 }
 
 // A Renderer struct is an implementation of renderer.NodeRenderer that renders
@@ -146,6 +1244,16 @@ type Renderer struct {
 	Config
 }
 
+// A documentState holds values that are computed from a whole document
+// before rendering it, like section numbers of headings.
+type documentState struct {
+	document       ast.Node
+	headingIDs     map[ast.Node][]byte
+	headingNumbers map[ast.Node][]byte
+}
+
+var documentStateKey = renderer.NewContextKey()
+
 // NewRenderer returns a new Renderer with given options.
 func NewRenderer(opts ...Option) renderer.NodeRenderer {
 	r := &Renderer{
@@ -183,14 +1291,125 @@ func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
 	reg.Register(ast.KindLink, r.renderLink)
 	reg.Register(ast.KindRawHTML, r.renderRawHTML)
 	reg.Register(ast.KindText, r.renderText)
+	reg.Register(ast.KindString, r.renderString)
 }
 
 func (r *Renderer) writeLines(w util.BufWriter, source []byte, n ast.Node) {
 	l := n.Lines().Len()
 	for i := 0; i < l; i++ {
 		line := n.Lines().At(i)
-		r.Writer.RawWrite(w, line.Value(source))
+		r.Writer.RawWrite(w, util.NormalizeLineEndings(line.Value(source)))
+	}
+}
+
+func (r *Renderer) writeCodeLines(w util.BufWriter, source []byte, n ast.Node, start int) {
+	if !r.CodeLineNumbers {
+		r.writeLines(w, source, n)
+		return
+	}
+	l := n.Lines().Len()
+	for i := 0; i < l; i++ {
+		line := n.Lines().At(i)
+		value := line.Value(source)
+		newline := util.LineEndingLength(value)
+		hasNewLine := newline != 0
+		value = value[:len(value)-newline]
+		w.WriteString(`<span class="line" data-line="`)
+		w.WriteString(strconv.Itoa(start + i))
+		w.WriteString(`">`)
+		r.Writer.RawWrite(w, value)
+		w.WriteString("</span>")
+		if hasNewLine {
+			w.WriteByte('\n')
+		}
+	}
+}
+
+var codeLineStartKey = []byte("start")
+
+// codeLineStart returns a line number of the first line specified in the
+// given info string meta like '{start:10}'.
+func codeLineStart(meta []byte) int {
+	i := bytes.Index(meta, codeLineStartKey)
+	if i < 0 || (i > 0 && util.IsAlphaNumeric(meta[i-1])) {
+		return 1
+	}
+	rest := meta[i+len(codeLineStartKey):]
+	if len(rest) == 0 || (rest[0] != ':' && rest[0] != '=') {
+		return 1
+	}
+	rest = rest[1:]
+	j := 0
+	for ; j < len(rest) && util.IsNumeric(rest[j]); j++ {
+	}
+	start, err := strconv.Atoi(string(rest[:j]))
+	if err != nil {
+		return 1
+	}
+	return start
+}
+
+// codeBlockMetaAttributes returns attributes written in braces of the given
+// info string meta like '{data-file="main.go" title=main}'.
+// Attributes other than 'title' and 'data-*' are ignored.
+func codeBlockMetaAttributes(meta []byte) []ast.Attribute {
+	start := bytes.IndexByte(meta, '{')
+	if start < 0 {
+		return nil
+	}
+	stop := bytes.LastIndexByte(meta, '}')
+	if stop < start {
+		return nil
+	}
+	meta = meta[start+1 : stop]
+	var attrs []ast.Attribute
+	for i := 0; i < len(meta); {
+		if util.IsSpace(meta[i]) || meta[i] == ',' {
+			i++
+			continue
+		}
+		nameStart := i
+		for ; i < len(meta) && (util.IsAlphaNumeric(meta[i]) || meta[i] == '-' || meta[i] == '_'); i++ {
+		}
+		name := meta[nameStart:i]
+		if i >= len(meta) || meta[i] != '=' || len(name) == 0 {
+			// skips items like 'start:10'.
+			for ; i < len(meta) && !util.IsSpace(meta[i]) && meta[i] != ','; i++ {
+			}
+			continue
+		}
+		i++
+		var value []byte
+		if i < len(meta) && (meta[i] == '"' || meta[i] == '\'') {
+			quote := meta[i]
+			j := bytes.IndexByte(meta[i+1:], quote)
+			if j < 0 {
+				return attrs
+			}
+			value = meta[i+1 : i+1+j]
+			i += j + 2
+		} else {
+			valueStart := i
+			for ; i < len(meta) && !util.IsSpace(meta[i]) && meta[i] != ','; i++ {
+			}
+			value = meta[valueStart:i]
+		}
+		if isCodeBlockMetaAttributeName(name) {
+			attrs = append(attrs, ast.Attribute{Name: bytes.ToLower(name), Value: value})
+		}
 	}
+	return attrs
+}
+
+var attrNameTitle = []byte("title")
+var attrPrefixData = []byte("data-")
+
+func isCodeBlockMetaAttributeName(name []byte) bool {
+	name = bytes.ToLower(name)
+	if bytes.Equal(name, attrNameTitle) {
+		return true
+	}
+	return len(name) > len(attrPrefixData) && bytes.HasPrefix(name, attrPrefixData)
 }
 
 func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
@@ -198,81 +1417,384 @@ func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node
 	return ast.WalkContinue, nil
 }
 
+// documentState returns a state of the document that contains the given
+// node. States are computed once per Render call, so that documents
+// modified between Render calls and documents rendered concurrently do not
+// share states.
+func (r *Renderer) documentState(w util.BufWriter, n ast.Node, source []byte) *documentState {
+	doc := n
+	for doc.Parent() != nil {
+		doc = doc.Parent()
+	}
+	if state, ok := renderer.ContextValue(w, documentStateKey).(*documentState); ok && state.document == doc {
+		return state
+	}
+	state := r.newDocumentState(doc, source)
+	renderer.SetContextValue(w, documentStateKey, state)
+	return state
+}
+
+func (r *Renderer) newDocumentState(doc ast.Node, source []byte) *documentState {
+	state := &documentState{document: doc}
+	if r.AutoIDs {
+		state.headingIDs = headingIDs(doc, source)
+	}
+	if r.HeadingNumbers != nil {
+		state.headingNumbers = r.headingNumbers(doc)
+	}
+	return state
+}
+
 var attrNameID = []byte("id")
 
 func (r *Renderer) renderHeading(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.Heading)
+	level := n.Level + r.HeadingLevelOffset
+	if level < 1 {
+		level = 1
+	} else if level > 6 {
+		level = 6
+	}
 	if entering {
 		w.WriteString("<h")
-		w.WriteByte("0123456"[n.Level])
+		w.WriteByte("0123456"[level])
+		id, hasID := n.AttributeString("id")
+		if !hasID && r.AutoIDs {
+			id = r.documentState(w, n, source).headingIDs[n]
+			if len(id) != 0 {
+				w.WriteString(` id="`)
+				r.Writer.RawWrite(w, id)
+				w.WriteByte('"')
+			}
+		}
 		if n.Attributes() != nil {
 			r.RenderAttributes(w, node)
 		}
 		w.WriteByte('>')
+		if r.HeadingAnchors != nil && len(id) != 0 {
+			w.WriteString(`<a class="anchor" href="#`)
+			r.Writer.RawWrite(w, id)
+			w.WriteString(`">`)
+			r.Writer.Write(w, r.HeadingAnchors)
+			w.WriteString(`</a>`)
+		}
+		if r.HeadingNumbers != nil {
+			if number := r.documentState(w, n, source).headingNumbers[n]; number != nil {
+				w.Write(number)
+				w.WriteByte(' ')
+			}
+		}
 	} else {
 		w.WriteString("</h")
-		w.WriteByte("0123456"[n.Level])
-		w.WriteString(">\n")
+		w.WriteByte("0123456"[level])
+		w.WriteByte('>')
+		r.WriteNewLine(w)
 	}
 	return ast.WalkContinue, nil
 }
 
+// headingIDs returns ids generated from texts of headings that have no ids
+// in the given document. Ids are generated in document order, so duplicated
+// ids are suffixed in the same way as parser.WithAutoHeadingID.
+func headingIDs(doc ast.Node, source []byte) map[ast.Node][]byte {
+	used := map[string]bool{}
+	_ = ast.Walk(doc, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && c.Kind() == ast.KindHeading {
+			if id, ok := c.AttributeString("id"); ok {
+				used[string(id)] = true
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	ids := map[ast.Node][]byte{}
+	suffixes := map[string]int{}
+	_ = ast.Walk(doc, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || c.Kind() != ast.KindHeading {
+			return ast.WalkContinue, nil
+		}
+		if _, ok := c.AttributeString("id"); ok {
+			return ast.WalkSkipChildren, nil
+		}
+		id := util.ToElementID(ast.PlainText(c, source))
+		if len(id) == 0 {
+			id = []byte(strings.ToLower(ast.KindHeading.String()))
+		}
+		if base := string(id); used[base] {
+			// suffixes less than the last one are already used
+			for i := suffixes[base] + 1; ; i++ {
+				newID := fmt.Sprintf("%s-%d", base, i)
+				if !used[newID] {
+					suffixes[base] = i
+					id = []byte(newID)
+					break
+				}
+			}
+		}
+		used[string(id)] = true
+		ids[c] = id
+		return ast.WalkSkipChildren, nil
+	})
+	return ids
+}
+
+// headingNumbers returns section numbers of headings that are direct
+// children of the given document. Counters of deeper levels are reset when
+// a shallower heading appears.
+func (r *Renderer) headingNumbers(doc ast.Node) map[ast.Node][]byte {
+	numbers := map[ast.Node][]byte{}
+	if doc.Kind() != ast.KindDocument {
+		return numbers
+	}
+	top := 7
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		if h, ok := c.(*ast.Heading); ok && h.Level < top {
+			top = h.Level
+		}
+	}
+	separator := r.HeadingNumbers.Separator
+	if len(separator) == 0 {
+		separator = "."
+	}
+	counters := [7]int{}
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		h, ok := c.(*ast.Heading)
+		if !ok {
+			continue
+		}
+		counters[h.Level]++
+		for i := h.Level + 1; i < len(counters); i++ {
+			counters[i] = 0
+		}
+		var number []byte
+		for i := top; i <= h.Level; i++ {
+			if i != top {
+				number = append(number, separator...)
+			}
+			number = strconv.AppendInt(number, int64(counters[i]), 10)
+		}
+		if r.HeadingNumbers.TrailingDot {
+			number = append(number, '.')
+		}
+		numbers[h] = number
+	}
+	return numbers
+}
+
 func (r *Renderer) renderBlockquote(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<blockquote>\n")
+		if n.Attributes() != nil {
+			w.WriteString("<blockquote")
+			r.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<blockquote>")
+		}
+		r.WriteNewLine(w)
 	} else {
-		w.WriteString("</blockquote>\n")
+		w.WriteString("</blockquote>")
+		r.WriteNewLine(w)
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderCodeBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<pre><code>")
-		r.writeLines(w, source, n)
+		w.WriteString("<pre")
+		if n.Attributes() != nil {
+			r.RenderAttributes(w, n)
+		}
+		w.WriteString("><code>")
+		r.writeCodeLines(w, source, n, 1)
 	} else {
-		w.WriteString("</code></pre>\n")
+		w.WriteString("</code></pre>")
+		r.WriteNewLine(w)
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.FencedCodeBlock)
+	wrapper := r.CodeBlockWrapper
 	if entering {
-		w.WriteString("<pre><code")
+		if len(wrapper.Tag) != 0 {
+			w.WriteByte('<')
+			w.WriteString(wrapper.Tag)
+			if len(wrapper.Class) != 0 {
+				w.WriteString(` class="`)
+				w.Write(util.EscapeHTML([]byte(wrapper.Class)))
+				w.WriteByte('"')
+			}
+			w.WriteByte('>')
+			r.WriteNewLine(w)
+		}
 		language := n.Language(source)
-		if language != nil {
-			w.WriteString(" class=\"language-")
+		var metaAttrs []ast.Attribute
+		if r.CodeBlockMetaAttributes {
+			metaAttrs = codeBlockMetaAttributes(n.Meta(source))
+		}
+		w.WriteString("<pre")
+		if wrapper.LanguageClassOnPre && language != nil {
+			r.renderLanguageClass(w, language, n)
+			for _, attr := range metaAttrs {
+				if _, ok := n.Attribute(attr.Name); !ok {
+					r.renderAttribute(w, attr)
+				}
+			}
+		} else if n.Attributes() != nil {
+			r.RenderAttributes(w, n)
+		}
+		w.WriteString("><code")
+		if !wrapper.LanguageClassOnPre && language != nil {
+			w.WriteString(" class=\"")
+			w.Write(util.EscapeHTML([]byte(r.CodeLanguagePrefix)))
 			r.Writer.Write(w, language)
 			w.WriteString("\"")
+			for _, attr := range metaAttrs {
+				r.renderAttribute(w, attr)
+			}
 		}
 		w.WriteByte('>')
-		r.writeLines(w, source, n)
+		r.writeCodeLines(w, source, n, codeLineStart(n.Meta(source)))
 	} else {
-		w.WriteString("</code></pre>\n")
+		w.WriteString("</code></pre>")
+		r.WriteNewLine(w)
+		if len(wrapper.Tag) != 0 {
+			w.WriteString("</")
+			w.WriteString(wrapper.Tag)
+			w.WriteByte('>')
+			r.WriteNewLine(w)
+		}
 	}
 	return ast.WalkContinue, nil
 }
 
+// renderLanguageClass renders the given node's attributes with the language
+// class. Classes of the node follow the language class.
+func (r *Renderer) renderLanguageClass(w util.BufWriter, language []byte, n ast.Node) {
+	if id, ok := n.Attribute(attrNameID); ok {
+		r.renderAttribute(w, ast.Attribute{Name: attrNameID, Value: id})
+	}
+	w.WriteString(` class="`)
+	w.Write(util.EscapeHTML([]byte(r.CodeLanguagePrefix)))
+	r.Writer.Write(w, language)
+	if class, ok := n.Attribute(attrNameClass); ok {
+		w.WriteByte(' ')
+		w.Write(util.EscapeHTML(class))
+	}
+	w.WriteByte('"')
+	for _, attr := range n.Attributes() {
+		if !bytes.Equal(attr.Name, attrNameID) && !bytes.Equal(attr.Name, attrNameClass) {
+			r.renderAttribute(w, attr)
+		}
+	}
+}
+
+// isComment returns true if the given value is a single HTML comment like
+// '<!-- note -->'.
+func isComment(value []byte) bool {
+	value = util.TrimRightSpace(value)
+	if !bytes.HasPrefix(value, []byte("<!--")) {
+		return false
+	}
+	i := bytes.Index(value[4:], []byte("-->"))
+	return i > -1 && i+7 == len(value)
+}
+
+// commentBody returns a text of the given HTML comment and true if the
+// comment can be rendered safely. Comments that browsers close earlier than
+// the last '-->', like '<!-->' and '<!-- a --!>', are not safe.
+func commentBody(value []byte) ([]byte, bool) {
+	value = util.TrimRightSpace(value)
+	if !isComment(value) {
+		return nil, false
+	}
+	body := value[4 : len(value)-3]
+	if bytes.HasPrefix(body, []byte(">")) || bytes.HasPrefix(body, []byte("->")) ||
+		bytes.Contains(body, []byte("--")) || bytes.HasSuffix(body, []byte("-")) {
+		return nil, false
+	}
+	return body, true
+}
+
+func (r *Renderer) writeComment(w util.BufWriter, body []byte) {
+	w.WriteString("<!--")
+	w.Write(body)
+	w.WriteString("-->")
+}
+
+// htmlBlockValue returns the whole text of the given html block.
+func htmlBlockValue(n *ast.HTMLBlock, source []byte) []byte {
+	var value []byte
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		value = append(value, line.Value(source)...)
+	}
+	if n.HasClosure() {
+		value = append(value, n.ClosureLine.Value(source)...)
+	}
+	return util.NormalizeLineEndings(value)
+}
+
+// isCommentBlock returns true if the given html block consists of only a
+// comment.
+func isCommentBlock(n *ast.HTMLBlock, source []byte) bool {
+	if n.HTMLBlockType != ast.HTMLBlockType2 {
+		return false
+	}
+	return isComment(htmlBlockValue(n, source))
+}
+
 func (r *Renderer) renderHTMLBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.HTMLBlock)
+	if r.Comments != CommentsAsRawHTML && isCommentBlock(n, source) {
+		if r.Comments == CommentsStripped {
+			return ast.WalkContinue, nil
+		}
+		if r.Unsafe {
+			if entering {
+				for i := 0; i < n.Lines().Len(); i++ {
+					line := n.Lines().At(i)
+					w.Write(util.NormalizeLineEndings(line.Value(source)))
+				}
+			} else if n.HasClosure() {
+				closure := n.ClosureLine
+				w.Write(util.NormalizeLineEndings(closure.Value(source)))
+			}
+			return ast.WalkContinue, nil
+		}
+		if body, ok := commentBody(htmlBlockValue(n, source)); ok {
+			if entering {
+				r.writeComment(w, body)
+				w.WriteByte('\n')
+			}
+			return ast.WalkContinue, nil
+		}
+	}
+	if r.HTMLAllowlist != nil {
+		if entering {
+			r.writeAllowedHTML(w, htmlBlockValue(n, source))
+		}
+		return ast.WalkContinue, nil
+	}
 	if entering {
 		if r.Unsafe {
 			l := n.Lines().Len()
 			for i := 0; i < l; i++ {
 				line := n.Lines().At(i)
-				w.Write(line.Value(source))
+				w.Write(util.NormalizeLineEndings(line.Value(source)))
 			}
 		} else {
-			w.WriteString("<!-- raw HTML omitted -->\n")
+			w.WriteString("<!-- raw HTML omitted -->")
+			r.WriteNewLine(w)
 		}
 	} else {
 		if n.HasClosure() {
 			if r.Unsafe {
 				closure := n.ClosureLine
-				w.Write(closure.Value(source))
+				w.Write(util.NormalizeLineEndings(closure.Value(source)))
 			} else {
-				w.WriteString("<!-- raw HTML omitted -->\n")
+				w.WriteString("<!-- raw HTML omitted -->")
+				r.WriteNewLine(w)
 			}
 		}
 	}
@@ -289,46 +1811,97 @@ func (r *Renderer) renderList(w util.BufWriter, source []byte, node ast.Node, en
 		w.WriteByte('<')
 		w.WriteString(tag)
 		if n.IsOrdered() && n.Start != 1 {
-			fmt.Fprintf(w, " start=\"%d\">\n", n.Start)
-		} else {
-			w.WriteString(">\n")
+			fmt.Fprintf(w, " start=\"%d\"", n.Start)
+		}
+		if n.Attributes() != nil {
+			r.RenderAttributes(w, n)
 		}
+		w.WriteByte('>')
+		r.WriteNewLine(w)
 	} else {
 		w.WriteString("</")
 		w.WriteString(tag)
-		w.WriteString(">\n")
+		w.WriteByte('>')
+		r.WriteNewLine(w)
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderListItem(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<li>")
+		if n.Attributes() != nil {
+			w.WriteString("<li")
+			r.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<li>")
+		}
 		fc := n.FirstChild()
 		if fc != nil {
-			if _, ok := fc.(*ast.TextBlock); !ok {
-				w.WriteByte('\n')
+			if _, ok := fc.(*ast.TextBlock); r.LooseLists || (!ok && !isTightParagraph(fc)) {
+				r.WriteNewLine(w)
 			}
 		}
 	} else {
-		w.WriteString("</li>\n")
+		w.WriteString("</li>")
+		r.WriteNewLine(w)
 	}
 	return ast.WalkContinue, nil
 }
 
+// isTightParagraph returns true if the given node is a paragraph directly
+// under an item of a tight list. The parser converts such paragraphs into
+// TextBlocks, but ASTs modified by transformers may still have them.
+func isTightParagraph(n ast.Node) bool {
+	if n.Kind() != ast.KindParagraph || n.Parent() == nil || n.Parent().Kind() != ast.KindListItem {
+		return false
+	}
+	list, ok := n.Parent().Parent().(*ast.List)
+	return ok && list.IsTight
+}
+
 func (r *Renderer) renderParagraph(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if isTightParagraph(n) && !r.LooseLists {
+		return r.renderTextBlock(w, source, n, entering)
+	}
+	if r.isFigure(n) {
+		if entering {
+			w.WriteString("<figure")
+			if n.Attributes() != nil {
+				r.RenderAttributes(w, n)
+			}
+			w.WriteByte('>')
+			r.WriteNewLine(w)
+		} else {
+			r.WriteNewLine(w)
+			w.WriteString("</figure>")
+			r.WriteNewLine(w)
+		}
+		return ast.WalkContinue, nil
+	}
 	if entering {
-		w.WriteString("<p>")
+		if n.Attributes() != nil {
+			w.WriteString("<p")
+			r.RenderAttributes(w, n)
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<p>")
+		}
 	} else {
-		w.WriteString("</p>\n")
+		w.WriteString("</p>")
+		r.WriteNewLine(w)
 	}
 	return ast.WalkContinue, nil
 }
 
 func (r *Renderer) renderTextBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if r.LooseLists && n.Parent() != nil && n.Parent().Kind() == ast.KindListItem {
+		// texts in tight list items are rendered as paragraphs.
+		return r.renderParagraph(w, source, n, entering)
+	}
 	if !entering {
 		if _, ok := n.NextSibling().(ast.Node); ok && n.FirstChild() != nil {
-			w.WriteByte('\n')
+			r.WriteNewLine(w)
 		}
 	}
 	return ast.WalkContinue, nil
@@ -338,11 +1911,21 @@ func (r *Renderer) renderThemanticBreak(w util.BufWriter, source []byte, n ast.N
 	if !entering {
 		return ast.WalkContinue, nil
 	}
-	if r.XHTML {
-		w.WriteString("<hr />\n")
-	} else {
-		w.WriteString("<hr>\n")
+	if n.Attributes() == nil && r.ThematicBreakAttributes == nil {
+		r.WriteVoidElement(w, "hr")
+		r.WriteNewLine(w)
+		return ast.WalkContinue, nil
+	}
+	attrs := append([]ast.Attribute{}, n.Attributes()...)
+	for _, attr := range r.ThematicBreakAttributes {
+		if _, ok := n.Attribute(attr.Name); !ok {
+			attrs = append(attrs, attr)
+		}
 	}
+	w.WriteString("<hr")
+	r.renderAttributes(w, attrs)
+	r.WriteVoidElementEnd(w)
+	r.WriteNewLine(w)
 	return ast.WalkContinue, nil
 }
 
@@ -354,29 +1937,63 @@ func (r *Renderer) renderAutoLink(w util.BufWriter, source []byte, node ast.Node
 	w.WriteString(`<a href="`)
 	url := n.URL(source)
 	label := n.Label(source)
-	if n.AutoLinkType == ast.AutoLinkEmail && !bytes.HasPrefix(bytes.ToLower(url), []byte("mailto:")) {
-		w.WriteString("mailto:")
+	if n.AutoLinkType == ast.AutoLinkEmail && r.EmailObfuscation {
+		if !bytes.HasPrefix(bytes.ToLower(url), []byte("mailto:")) {
+			writeObfuscated(w, []byte("mailto:"))
+		}
+		writeObfuscated(w, util.URLEscape(url, false))
+		w.WriteString(`">`)
+		writeObfuscated(w, label)
+		w.WriteString(`</a>`)
+		return ast.WalkContinue, nil
+	}
+	if n.AutoLinkType == ast.AutoLinkEmail {
+		if !bytes.HasPrefix(bytes.ToLower(url), []byte("mailto:")) {
+			w.WriteString("mailto:")
+		}
+		w.Write(util.EscapeHTML(util.URLEscape(url, false)))
+	} else {
+		w.Write(util.EscapeHTML(util.URLEscape(r.sanitizeURL(r.resolveURL(url), false), false)))
 	}
-	w.Write(util.EscapeHTML(util.URLEscape(url, false)))
-	w.WriteString(`">`)
+	w.WriteByte('"')
+	if n.AutoLinkType == ast.AutoLinkURL {
+		r.renderTargetAndRel(w, url)
+	}
+	w.WriteByte('>')
 	w.Write(util.EscapeHTML(label))
 	w.WriteString(`</a>`)
 	return ast.WalkContinue, nil
 }
 
+// writeObfuscated writes the given value with every character encoded as
+// a decimal numeric character reference.
+func writeObfuscated(w util.BufWriter, value []byte) {
+	for len(value) != 0 {
+		r, size := utf8.DecodeRune(value)
+		value = value[size:]
+		w.WriteString("&#")
+		w.WriteString(strconv.Itoa(int(r)))
+		w.WriteByte(';')
+	}
+}
+
 func (r *Renderer) renderCodeSpan(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
 	if entering {
-		w.WriteString("<code>")
+		if n.Attributes() != nil {
+			w.WriteString("<code")
+			r.renderAttributes(w, n.Attributes())
+			w.WriteByte('>')
+		} else {
+			w.WriteString("<code>")
+		}
 		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
 			segment := c.(*ast.Text).Segment
 			value := segment.Value(source)
-			if bytes.HasSuffix(value, []byte("\n")) {
-				r.Writer.RawWrite(w, value[:len(value)-1])
-				if c != n.LastChild() {
-					r.Writer.RawWrite(w, []byte(" "))
-				}
+			if l := util.LineEndingLength(value); l != 0 && !r.CodeSpanNewLines {
+				r.Writer.RawWrite(w, value[:len(value)-l])
+				r.Writer.RawWrite(w, []byte(" "))
 			} else {
-				r.Writer.RawWrite(w, value)
+				r.Writer.RawWrite(w, util.NormalizeLineEndings(value))
 			}
 		}
 		return ast.WalkSkipChildren, nil
@@ -387,9 +2004,9 @@ func (r *Renderer) renderCodeSpan(w util.BufWriter, source []byte, n ast.Node, e
 
 func (r *Renderer) renderEmphasis(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	n := node.(*ast.Emphasis)
-	tag := "em"
+	tag := r.EmphasisTags[0]
 	if n.Level == 2 {
-		tag = "strong"
+		tag = r.EmphasisTags[1]
 	}
 	if entering {
 		w.WriteByte('<')
@@ -407,63 +2024,470 @@ func (r *Renderer) renderLink(w util.BufWriter, source []byte, node ast.Node, en
 	n := node.(*ast.Link)
 	if entering {
 		w.WriteString("<a href=\"")
-		if r.Unsafe || !IsDangerousURL(n.Destination) {
-			w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
-		}
+		r.writeURL(w, n.Destination, false)
 		w.WriteByte('"')
 		if n.Title != nil {
 			w.WriteString(` title="`)
 			r.Writer.Write(w, n.Title)
 			w.WriteByte('"')
 		}
+		if n.Attributes() != nil {
+			r.RenderAttributes(w, n)
+		}
+		r.renderTargetAndRel(w, n.Destination)
 		w.WriteByte('>')
 	} else {
 		w.WriteString("</a>")
 	}
 	return ast.WalkContinue, nil
 }
+
+// writeURL writes the given link destination with resolving references,
+// sanitizing and escaping.
+func (r *Renderer) writeURL(w util.BufWriter, url []byte, isImage bool) {
+	url = util.UnescapePunctuations(url)
+	url = util.ResolveNumericReferences(url)
+	url = util.ResolveEntityNames(url)
+	url = r.resolveURL(url)
+	url = r.sanitizeURL(url, isImage)
+	w.Write(util.EscapeHTML(util.URLEscape(url, false)))
+}
+
+// resolveURL resolves the given relative URL against the BaseURL.
+func (r *Renderer) resolveURL(url []byte) []byte {
+	if r.BaseURL == nil || len(url) == 0 || url[0] == '#' || bytes.HasPrefix(url, []byte("//")) {
+		return url
+	}
+	u, err := neturl.Parse(string(url))
+	if err != nil || u.IsAbs() {
+		return url
+	}
+	resolved := r.BaseURL.ResolveReference(u).String()
+	// net/url resolves paths as absolute paths, so 'sub/' and 'a.png' are
+	// resolved into '/sub/a.png'.
+	base := r.BaseURL
+	if !base.IsAbs() && base.Host == "" && !strings.HasPrefix(base.Path, "/") && !strings.HasPrefix(u.Path, "/") {
+		resolved = strings.TrimPrefix(resolved, "/")
+	}
+	return []byte(resolved)
+}
+
+func (r *Renderer) sanitizeURL(url []byte, isImage bool) []byte {
+	if r.URLSanitizer != nil {
+		return r.URLSanitizer(url, isImage)
+	}
+	if r.Unsafe || !IsDangerousURL(url) {
+		return url
+	}
+	return nil
+}
+
+func (r *Renderer) renderTargetAndRel(w util.BufWriter, destination []byte) {
+	external := r.ExternalLinkTarget && r.IsExternalLink != nil && r.IsExternalLink(destination)
+	if external {
+		w.WriteString(` target="_blank"`)
+	}
+	var rel [][]byte
+	if external {
+		rel = bytes.Fields(r.ExternalLinkRel)
+	}
+	if len(r.LinkRel) != 0 && !bytes.HasPrefix(destination, []byte("#")) {
+		for _, v := range bytes.Fields(r.LinkRel) {
+			if !containsBytes(rel, v) {
+				rel = append(rel, v)
+			}
+		}
+	}
+	if len(rel) != 0 {
+		w.WriteString(` rel="`)
+		w.Write(util.EscapeHTML(bytes.Join(rel, []byte(" "))))
+		w.WriteByte('"')
+	}
+}
+
+func containsBytes(values [][]byte, value []byte) bool {
+	for _, v := range values {
+		if bytes.Equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFigure returns true if the given node is a paragraph that should be
+// rendered as a figure.
+func (r *Renderer) isFigure(n ast.Node) bool {
+	if !r.Figures || n.Kind() != ast.KindParagraph {
+		return false
+	}
+	c := n.FirstChild()
+	return c != nil && c == n.LastChild() && c.Kind() == ast.KindImage
+}
+
 func (r *Renderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Image)
+	figure := r.isFigure(n.Parent())
 	if !entering {
+		if figure && n.HasChildren() {
+			w.WriteString("</figcaption>")
+		}
 		return ast.WalkContinue, nil
 	}
-	n := node.(*ast.Image)
-	w.WriteString("<img src=\"")
-	if r.Unsafe || !IsDangerousURL(n.Destination) {
-		w.Write(util.EscapeHTML(util.URLEscape(n.Destination, true)))
+	destination := n.Destination
+	title := n.Title
+	var width, height []byte
+	if r.ImageDimensions {
+		if title != nil {
+			title, width, height = parseImageDimensions(title)
+			if len(title) == 0 && (width != nil || height != nil) {
+				title = nil
+			}
+		}
+		if width == nil && height == nil {
+			destination, width, height = parseImageDimensions(destination)
+		}
 	}
+	w.WriteString("<img src=\"")
+	r.writeURL(w, destination, true)
+	alt := n.Text(source)
 	w.WriteString(`" alt="`)
-	w.Write(n.Text(source))
+	r.Writer.Write(w, alt)
 	w.WriteByte('"')
-	if n.Title != nil {
+	if r.EmptyAltAttributes != nil && util.IsBlank(alt) {
+		r.renderAttributes(w, r.EmptyAltAttributes)
+	}
+	if title != nil {
 		w.WriteString(` title="`)
-		r.Writer.Write(w, n.Title)
+		r.Writer.Write(w, title)
 		w.WriteByte('"')
 	}
-	if r.XHTML {
-		w.WriteString(" />")
-	} else {
-		w.WriteString(">")
+	if width != nil {
+		w.WriteString(` width="`)
+		w.Write(width)
+		w.WriteByte('"')
+	}
+	if height != nil {
+		w.WriteString(` height="`)
+		w.Write(height)
+		w.WriteByte('"')
+	}
+	if r.ImageLoadingLazy {
+		w.WriteString(` loading="lazy" decoding="async"`)
+	}
+	r.WriteVoidElementEnd(w)
+	if figure {
+		if n.HasChildren() {
+			r.WriteNewLine(w)
+			w.WriteString("<figcaption>")
+			return ast.WalkContinue, nil
+		}
+		if len(title) != 0 {
+			r.WriteNewLine(w)
+			w.WriteString("<figcaption>")
+			r.Writer.Write(w, title)
+			w.WriteString("</figcaption>")
+		}
 	}
 	return ast.WalkSkipChildren, nil
 }
 
+// parseImageDimensions parses a trailing '=WIDTHxHEIGHT' from the given value.
+// Either the width or the height can be omitted like '=200x' and '=x100'.
+// parseImageDimensions returns the value without dimensions, the width and
+// the height. If the value does not end with dimensions, the value is
+// returned as it is and the width and the height are nil.
+func parseImageDimensions(value []byte) ([]byte, []byte, []byte) {
+	i := bytes.LastIndexByte(value, '=')
+	if i < 0 || (i != 0 && !util.IsSpace(value[i-1])) {
+		return value, nil, nil
+	}
+	dims := value[i+1:]
+	x := bytes.IndexByte(dims, 'x')
+	if x < 0 {
+		return value, nil, nil
+	}
+	width, height := dims[:x], dims[x+1:]
+	if len(width) == 0 && len(height) == 0 {
+		return value, nil, nil
+	}
+	for _, part := range [][]byte{width, height} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return value, nil, nil
+			}
+		}
+	}
+	if len(width) == 0 {
+		width = nil
+	}
+	if len(height) == 0 {
+		height = nil
+	}
+	return util.TrimRightSpace(value[:i]), width, height
+}
+
 func (r *Renderer) renderRawHTML(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if !entering {
 		return ast.WalkSkipChildren, nil
 	}
-	if r.Unsafe {
-		n := node.(*ast.RawHTML)
-		l := n.Segments.Len()
-		for i := 0; i < l; i++ {
-			segment := n.Segments.At(i)
-			w.Write(segment.Value(source))
+	n := node.(*ast.RawHTML)
+	var value []byte
+	for i := 0; i < n.Segments.Len(); i++ {
+		segment := n.Segments.At(i)
+		value = append(value, segment.Value(source)...)
+	}
+	value = util.NormalizeLineEndings(value)
+	if r.Comments != CommentsAsRawHTML && bytes.HasPrefix(value, []byte("<!--")) {
+		if r.Comments == CommentsStripped {
+			return ast.WalkSkipChildren, nil
+		}
+		if r.Unsafe {
+			w.Write(value)
+			return ast.WalkSkipChildren, nil
+		}
+		if body, ok := commentBody(value); ok {
+			r.writeComment(w, body)
+			return ast.WalkSkipChildren, nil
 		}
+	}
+	if r.HTMLAllowlist != nil {
+		r.writeAllowedHTML(w, value)
+		return ast.WalkSkipChildren, nil
+	}
+	if r.Unsafe {
+		w.Write(value)
 		return ast.WalkSkipChildren, nil
 	}
 	w.WriteString("<!-- raw HTML omitted -->")
 	return ast.WalkSkipChildren, nil
 }
 
+// writeAllowedHTML writes the given raw HTML with only tags and attributes
+// in the HTMLAllowlist. Other tags are escaped.
+func (r *Renderer) writeAllowedHTML(w util.BufWriter, value []byte) {
+	for len(value) != 0 {
+		i := bytes.IndexByte(value, '<')
+		if i < 0 {
+			w.Write(value)
+			return
+		}
+		w.Write(value[:i])
+		value = value[i:]
+		if len(value) > 1 && (value[1] == '!' || value[1] == '?') {
+			// comments, declarations and processing instructions
+			end := []byte(">")
+			if bytes.HasPrefix(value, []byte("<!--")) {
+				end = []byte("-->")
+			}
+			l := len(value)
+			if j := bytes.Index(value[2:], end); j > -1 {
+				l = j + 2 + len(end)
+			}
+			w.Write(util.EscapeHTML(value[:l]))
+			value = value[l:]
+			continue
+		}
+		tag, ok := parseHTMLTag(value)
+		if !ok {
+			w.WriteString("&lt;")
+			value = value[1:]
+			continue
+		}
+		attrs, allowed := r.HTMLAllowlist[strings.ToLower(string(tag.name))]
+		if !allowed {
+			w.Write(util.EscapeHTML(value[:tag.length]))
+			value = value[tag.length:]
+			continue
+		}
+		w.WriteByte('<')
+		if tag.closing {
+			w.WriteByte('/')
+			w.Write(tag.name)
+			w.WriteByte('>')
+			value = value[tag.length:]
+			continue
+		}
+		w.Write(tag.name)
+		for _, attr := range tag.attributes {
+			name := strings.ToLower(string(attr.Name))
+			if !containsString(attrs, name) {
+				continue
+			}
+			v := attr.Value
+			if isURLAttributeName(name) && !isAllowedURLAttribute(name, v) {
+				continue
+			}
+			w.WriteByte(' ')
+			w.Write(attr.Name)
+			if v != nil {
+				w.WriteString(`="`)
+				w.Write(bytes.ReplaceAll(v, []byte(`"`), []byte("&quot;")))
+				w.WriteByte('"')
+			}
+		}
+		if tag.selfClosing {
+			w.WriteString(" />")
+		} else {
+			w.WriteByte('>')
+		}
+		value = value[tag.length:]
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isURLAttributeName(name string) bool {
+	switch name {
+	case "href", "src", "action", "formaction", "cite", "poster", "background", "srcset", "xlink:href":
+		return true
+	}
+	return false
+}
+
+// isAllowedURLAttribute returns true if the given value of a URL attribute
+// is a relative URL or an URL with the http, https or mailto scheme.
+func isAllowedURLAttribute(name string, value []byte) bool {
+	value = util.ResolveEntityNames(util.ResolveNumericReferences(value))
+	if name != "srcset" {
+		return isAllowedURL(value)
+	}
+	for _, candidate := range bytes.Split(value, []byte{','}) {
+		candidate = util.TrimLeftSpace(candidate)
+		if i := bytes.IndexAny(candidate, " \t\n\f\r"); i > -1 {
+			candidate = candidate[:i]
+		}
+		if !isAllowedURL(candidate) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAllowedURL returns true if the given decoded URL is a relative URL or an
+// URL with the http, https or mailto scheme. The URL is normalized like
+// the WHATWG URL parser does: tabs and new lines are removed and leading and
+// trailing C0 controls and spaces are trimmed.
+func isAllowedURL(url []byte) bool {
+	normalized := make([]byte, 0, len(url))
+	for _, c := range url {
+		if c != '\t' && c != '\n' && c != '\r' {
+			normalized = append(normalized, c)
+		}
+	}
+	normalized = bytes.TrimFunc(normalized, func(r rune) bool {
+		return r <= 0x20
+	})
+	for i, c := range normalized {
+		switch c {
+		case '/', '?', '#':
+			return true
+		case ':':
+			scheme := strings.ToLower(string(normalized[:i]))
+			return scheme == "http" || scheme == "https" || scheme == "mailto"
+		case '&':
+			// character references that are not resolved, like '&#58'
+			return false
+		}
+	}
+	return true
+}
+
+type htmlTag struct {
+	name        []byte
+	attributes  []ast.Attribute
+	closing     bool
+	selfClosing bool
+	length      int
+}
+
+// parseHTMLTag parses an open tag or a closing tag at the start of the given
+// bytes. Attribute values of the returned tag are []byte without quotes, or
+// nil if the attribute has no value.
+func parseHTMLTag(value []byte) (htmlTag, bool) {
+	tag := htmlTag{}
+	i := 1
+	if i < len(value) && value[i] == '/' {
+		tag.closing = true
+		i++
+	}
+	start := i
+	for i < len(value) && (util.IsAlphaNumeric(value[i]) || (i > start && value[i] == '-')) {
+		i++
+	}
+	if i == start || !(value[start] >= 'a' && value[start] <= 'z' || value[start] >= 'A' && value[start] <= 'Z') {
+		return tag, false
+	}
+	tag.name = value[start:i]
+	for {
+		s := i
+		for i < len(value) && util.IsSpace(value[i]) {
+			i++
+		}
+		if i >= len(value) {
+			return tag, false
+		}
+		switch {
+		case value[i] == '>':
+			tag.length = i + 1
+			return tag, true
+		case value[i] == '/' && !tag.closing && i+1 < len(value) && value[i+1] == '>':
+			tag.selfClosing = true
+			tag.length = i + 2
+			return tag, true
+		}
+		if tag.closing || s == i {
+			return tag, false
+		}
+		nstart := i
+		for i < len(value) && (util.IsAlphaNumeric(value[i]) || value[i] == '_' || value[i] == ':' ||
+			(i > nstart && (value[i] == '.' || value[i] == '-'))) {
+			i++
+		}
+		if i == nstart {
+			return tag, false
+		}
+		attr := ast.Attribute{Name: value[nstart:i]}
+		j := i
+		for j < len(value) && util.IsSpace(value[j]) {
+			j++
+		}
+		if j < len(value) && value[j] == '=' {
+			i = j + 1
+			for i < len(value) && util.IsSpace(value[i]) {
+				i++
+			}
+			if i >= len(value) {
+				return tag, false
+			}
+			if q := value[i]; q == '"' || q == '\'' {
+				end := bytes.IndexByte(value[i+1:], q)
+				if end < 0 {
+					return tag, false
+				}
+				attr.Value = value[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				vstart := i
+				for i < len(value) && !util.IsSpace(value[i]) && bytes.IndexByte([]byte("\"'=<>`"), value[i]) < 0 {
+					i++
+				}
+				if i == vstart {
+					return tag, false
+				}
+				attr.Value = value[vstart:i]
+			}
+		}
+		tag.attributes = append(tag.attributes, attr)
+	}
+}
+
 func (r *Renderer) renderText(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
 	if !entering {
 		return ast.WalkContinue, nil
@@ -474,12 +2498,19 @@ func (r *Renderer) renderText(w util.BufWriter, source []byte, node ast.Node, en
 		r.Writer.RawWrite(w, segment.Value(source))
 	} else {
 		r.Writer.Write(w, segment.Value(source))
-		if n.HardLineBreak() || (n.SoftLineBreak() && r.HardWraps) {
-			if r.XHTML {
-				w.WriteString("<br />\n")
-			} else {
-				w.WriteString("<br>\n")
-			}
+		mode := r.SoftBreakMode
+		if r.HardWraps {
+			mode = SoftBreakAsBR
+		}
+		hardLineBreak := n.HardLineBreak() && !r.IgnoreHardLineBreaks
+		softLineBreak := n.SoftLineBreak() && !hardLineBreak
+		if (hardLineBreak || (softLineBreak && mode == SoftBreakAsBR)) && !r.HeadingHardLineBreaks && isInHeading(n) {
+			w.WriteByte('\n')
+		} else if hardLineBreak || (softLineBreak && mode == SoftBreakAsBR) {
+			r.WriteVoidElement(w, "br")
+			r.WriteNewLine(w)
+		} else if softLineBreak && mode == SoftBreakAsSpace {
+			w.WriteByte(' ')
 		} else if n.SoftLineBreak() {
 			w.WriteByte('\n')
 		}
@@ -487,15 +2518,76 @@ func (r *Renderer) renderText(w util.BufWriter, source []byte, node ast.Node, en
 	return ast.WalkContinue, nil
 }
 
+// isInHeading returns true if the given inline node is in a heading.
+func isInHeading(n ast.Node) bool {
+	p := n.Parent()
+	for ; p != nil && p.Type() == ast.TypeInline; p = p.Parent() {
+	}
+	return p != nil && p.Kind() == ast.KindHeading
+}
+
+func (r *Renderer) renderString(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		r.Writer.RawWrite(w, node.(*ast.String).Value)
+	}
+	return ast.WalkContinue, nil
+}
+
 // RenderAttributes renders given node's attributes.
+// Attributes are rendered in a stable order: 'id' first, 'class' second and
+// other attributes in the order they were set.
 func (r *Renderer) RenderAttributes(w util.BufWriter, node ast.Node) {
-	for _, attr := range node.Attributes() {
-		w.WriteString(" ")
-		w.Write(attr.Name)
-		w.WriteString(`="`)
+	r.renderAttributes(w, node.Attributes())
+}
+
+func (r *Renderer) renderAttributes(w util.BufWriter, attrs []ast.Attribute) {
+	renderAttributes(w, r.Writer, attrs)
+}
+
+// RenderAttributes renders given node's attributes like
+// ' id="a" class="b"' in the same order as Renderer.RenderAttributes.
+// Renderers for extensions can use this to render attributes set by
+// ast.Node.SetAttribute on any elements.
+// Values are written without a Writer, so Writer options like
+// WithEscapeNonASCII are not applied to them.
+func RenderAttributes(w util.BufWriter, node ast.Node) {
+	renderAttributes(w, nil, node.Attributes())
+}
+
+// renderAttributes renders the given attributes. Values are written by
+// writer.RawWrite, or just escaped if writer is nil.
+func renderAttributes(w util.BufWriter, writer Writer, attrs []ast.Attribute) {
+	for _, name := range attributeOrder {
+		for _, attr := range attrs {
+			if bytes.Equal(attr.Name, name) {
+				renderAttribute(w, writer, attr)
+			}
+		}
+	}
+	for _, attr := range attrs {
+		if !bytes.Equal(attr.Name, attrNameID) && !bytes.Equal(attr.Name, attrNameClass) {
+			renderAttribute(w, writer, attr)
+		}
+	}
+}
+
+var attrNameClass = []byte("class")
+var attributeOrder = [][]byte{attrNameID, attrNameClass}
+
+func (r *Renderer) renderAttribute(w util.BufWriter, attr ast.Attribute) {
+	renderAttribute(w, r.Writer, attr)
+}
+
+func renderAttribute(w util.BufWriter, writer Writer, attr ast.Attribute) {
+	w.WriteString(" ")
+	w.Write(attr.Name)
+	w.WriteString(`="`)
+	if writer != nil {
+		writer.RawWrite(w, attr.Value)
+	} else {
 		w.Write(util.EscapeHTML(attr.Value))
-		w.WriteByte('"')
 	}
+	w.WriteByte('"')
 }
 
 // A Writer interface wirtes textual contents to a writer.
@@ -509,10 +2601,86 @@ type Writer interface {
 	RawWrite(writer util.BufWriter, source []byte)
 }
 
+// A WriterConfig struct has configurations for the HTML based writers.
+type WriterConfig struct {
+	// ExtraEntities is a map of entity names without '&' and ';' to
+	// characters. Entities in this map take precedence over HTML5 entities.
+	ExtraEntities map[string][]byte
+
+	// KeepBackslashes is true if backslashes of backslash escapes like '\\*'
+	// should be written as they are.
+	KeepBackslashes bool
+
+	// CollapseWhitespace is true if runs of spaces and tabs should be
+	// written as a single space.
+	CollapseWhitespace bool
+
+	// EscapeNonASCII is true if non-ASCII characters should be written as
+	// numeric character references like '&#x65E5;'.
+	EscapeNonASCII bool
+}
+
+// A WriterOption is a functional option type for the Writer.
+type WriterOption func(*WriterConfig)
+
+// WithExtraEntities is a functional option for the Writer that adds named
+// entities like 'foo' for '&foo;'. Unknown entities are written as they are.
+func WithExtraEntities(entities map[string][]byte) WriterOption {
+	return func(c *WriterConfig) {
+		if c.ExtraEntities == nil {
+			c.ExtraEntities = map[string][]byte{}
+		}
+		for name, characters := range entities {
+			c.ExtraEntities[name] = characters
+		}
+	}
+}
+
+// WithoutUnescaping is a functional option for the Writer that writes
+// backslash escapes like '\\*' as they are.
+func WithoutUnescaping() WriterOption {
+	return func(c *WriterConfig) {
+		c.KeepBackslashes = true
+	}
+}
+
+// WithCollapseWhitespace is a functional option for the Writer that writes
+// runs of spaces and tabs as a single space like web browsers do.
+// Texts written by RawWrite like code spans and code blocks are not
+// collapsed.
+func WithCollapseWhitespace() WriterOption {
+	return func(c *WriterConfig) {
+		c.CollapseWhitespace = true
+	}
+}
+
+// WithEscapeNonASCII is a functional option for the Writer that writes
+// non-ASCII characters as numeric character references like '&#x65E5;' for
+// systems that accept only ASCII. Entities like '&copy;' are resolved and
+// written as numeric character references, and invalid characters are
+// written as '&#xFFFD;'.
+// Contents written without the Writer like URLs, raw HTML and attributes
+// rendered by RenderAttributes are not affected.
+func WithEscapeNonASCII() WriterOption {
+	return func(c *WriterConfig) {
+		c.EscapeNonASCII = true
+	}
+}
+
 type defaultWriter struct {
+	WriterConfig
+}
+
+// NewWriter returns a new Writer configured by the given options.
+func NewWriter(opts ...WriterOption) Writer {
+	w := &defaultWriter{}
+	for _, opt := range opts {
+		opt(&w.WriterConfig)
+	}
+	return w
 }
 
-func escapeRune(writer util.BufWriter, r rune) {
+func (d *defaultWriter) escapeRune(writer util.BufWriter, r rune) {
 	if r < 256 {
 		v := util.EscapeHTMLByte(byte(r))
 		if v != nil {
@@ -520,28 +2688,112 @@ func escapeRune(writer util.BufWriter, r rune) {
 			return
 		}
 	}
-	writer.WriteRune(util.ToValidRune(r))
+	r = util.ToValidRune(r)
+	if d.EscapeNonASCII && r >= utf8.RuneSelf {
+		writeNumericReference(writer, r)
+		return
+	}
+	writer.WriteRune(r)
+}
+
+func writeNumericReference(writer util.BufWriter, r rune) {
+	_, _ = fmt.Fprintf(writer, "&#x%X;", r)
+}
+
+// indexNonASCII returns an index of the first non-ASCII byte in the given
+// bytes, or -1 if the bytes consist of only ASCII characters.
+func indexNonASCII(source []byte) int {
+	for i, c := range source {
+		if c >= utf8.RuneSelf {
+			return i
+		}
+	}
+	return -1
+}
+
+// writerSpecialChars is a set of characters that need references to be
+// resolved, backslashes to be unescaped or characters to be escaped.
+const writerSpecialChars = "&\\<>\""
+
+func hasWriterSpecialBytes(source []byte) bool {
+	return bytes.IndexAny(source, writerSpecialChars) > -1
 }
 
 func (d *defaultWriter) RawWrite(writer util.BufWriter, source []byte) {
-	n := 0
-	l := len(source)
-	for i := 0; i < l; i++ {
-		v := util.EscapeHTMLByte(source[i])
-		if v != nil {
-			writer.Write(source[i-n : i])
-			n = 0
-			writer.Write(v)
-			continue
+	if d.EscapeNonASCII {
+		for {
+			i := indexNonASCII(source)
+			if i < 0 {
+				break
+			}
+			d.rawWrite(writer, source[:i])
+			r, l := utf8.DecodeRune(source[i:])
+			writeNumericReference(writer, util.ToValidRune(r))
+			source = source[i+l:]
+		}
+	}
+	d.rawWrite(writer, source)
+}
+
+func (d *defaultWriter) rawWrite(writer util.BufWriter, source []byte) {
+	for {
+		i := util.IndexHTMLEscape(source)
+		if i < 0 {
+			break
+		}
+		writer.Write(source[:i])
+		writer.Write(util.EscapeHTMLByte(source[i]))
+		source = source[i+1:]
+	}
+	if len(source) != 0 {
+		writer.Write(source)
+	}
+}
+
+// collapseWhitespace returns the given source with runs of spaces and tabs
+// replaced with a single space. The source itself is returned if it has
+// nothing to be collapsed.
+func collapseWhitespace(source []byte) []byte {
+	i := 0
+	for ; i < len(source); i++ {
+		c := source[i]
+		if c == '\t' || (c == ' ' && i+1 < len(source) && (source[i+1] == ' ' || source[i+1] == '\t')) {
+			break
 		}
-		n++
 	}
-	if n != 0 {
-		writer.Write(source[l-n:])
+	if i == len(source) {
+		return source
+	}
+	result := make([]byte, 0, len(source))
+	result = append(result, source[:i]...)
+	space := false
+	for ; i < len(source); i++ {
+		c := source[i]
+		if c == ' ' || c == '\t' {
+			if !space {
+				result = append(result, ' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		result = append(result, c)
 	}
+	return result
 }
 
 func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
+	if d.CollapseWhitespace {
+		source = collapseWhitespace(source)
+	}
+	if !hasWriterSpecialBytes(source) {
+		if d.EscapeNonASCII {
+			d.RawWrite(writer, source)
+			return
+		}
+		writer.Write(source)
+		return
+	}
 	escaped := false
 	ok := false
 	limit := len(source)
@@ -550,8 +2802,10 @@ func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
 		c := source[i]
 		if escaped {
 			if util.IsPunct(c) {
-				d.RawWrite(writer, source[n:i-1])
-				n = i
+				if !d.KeepBackslashes {
+					d.RawWrite(writer, source[n:i-1])
+					n = i
+				}
 				escaped = false
 				continue
 			}
@@ -559,18 +2813,18 @@ func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
 		if c == '&' {
 			pos := i
 			next := i + 1
-			if next < limit && source[next] == '#' {
+			if next < limit-1 && source[next] == '#' {
 				nnext := next + 1
 				nc := source[nnext]
 				// code point like #x22;
-				if nnext < limit && nc == 'x' || nc == 'X' {
+				if nc == 'x' || nc == 'X' {
 					start := nnext + 1
 					i, ok = util.ReadWhile(source, [2]int{start, limit}, util.IsHexDecimal)
 					if ok && i < limit && source[i] == ';' {
 						v, _ := strconv.ParseUint(util.BytesToReadOnlyString(source[start:i]), 16, 32)
 						d.RawWrite(writer, source[n:pos])
 						n = i + 1
-						escapeRune(writer, rune(v))
+						d.escapeRune(writer, rune(v))
 						continue
 					}
 					// code point like #1234;
@@ -581,7 +2835,7 @@ func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
 						v, _ := strconv.ParseUint(util.BytesToReadOnlyString(source[start:i]), 0, 32)
 						d.RawWrite(writer, source[n:pos])
 						n = i + 1
-						escapeRune(writer, rune(v))
+						d.escapeRune(writer, rune(v))
 						continue
 					}
 				}
@@ -591,6 +2845,12 @@ func (d *defaultWriter) Write(writer util.BufWriter, source []byte) {
 				// entity reference
 				if ok && i < limit && source[i] == ';' {
 					name := util.BytesToReadOnlyString(source[start:i])
+					if characters, ok := d.ExtraEntities[name]; ok {
+						d.RawWrite(writer, source[n:pos])
+						n = i + 1
+						d.RawWrite(writer, characters)
+						continue
+					}
 					entity, ok := util.LookUpHTML5EntityByName(name)
 					if ok {
 						d.RawWrite(writer, source[n:pos])
@@ -624,9 +2884,32 @@ var bVb = []byte("vbscript:")
 var bFile = []byte("file:")
 var bData = []byte("data:")
 
-// IsDangerousURL returns true if the given url seems a potentially dangerous url,
+// IsExternalURL returns true if the given url has a scheme and a host like
+// 'https://example.com/' or is a protocol-relative url like '//example.com/',
 // otherwise false.
+func IsExternalURL(url []byte) bool {
+	if bytes.HasPrefix(url, []byte("//")) {
+		return true
+	}
+	for i, c := range url {
+		if c == ':' {
+			return i > 0 && bytes.HasPrefix(url[i+1:], []byte("//"))
+		}
+		if !(util.IsAlphaNumeric(c) || (i > 0 && (c == '+' || c == '-' || c == '.'))) {
+			return false
+		}
+	}
+	return false
+}
+
+// IsDangerousURL returns true if the given url seems a potentially dangerous url,
+// otherwise false. Schemes are compared case-insensitively.
 func IsDangerousURL(url []byte) bool {
+	url = util.TrimLeftSpace(url)
+	if len(url) > 16 {
+		url = url[:16]
+	}
+	url = bytes.ToLower(url)
 	if bytes.HasPrefix(url, bDataImage) && len(url) >= 11 {
 		v := url[11:]
 		if bytes.HasPrefix(v, bPng) || bytes.HasPrefix(v, bGif) ||
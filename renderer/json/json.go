@@ -0,0 +1,220 @@
+// Package json implements a renderer that serializes an AST to JSON.
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// A Config struct has configurations for the JSON renderer.
+type Config struct {
+	// Indent is an indentation string. If Indent is empty, the renderer
+	// writes compact JSON.
+	Indent string
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		Indent: "",
+	}
+}
+
+// SetOption implements renderer.NodeRenderer.SetOption.
+func (c *Config) SetOption(name renderer.OptionName, value interface{}) {
+	switch name {
+	case optIndent:
+		c.Indent = value.(string)
+	}
+}
+
+// An Option interface sets options for the JSON renderer.
+type Option interface {
+	SetJSONOption(*Config)
+}
+
+// Indent is an option name used in WithIndent.
+const optIndent renderer.OptionName = "JSONIndent"
+
+type withIndent struct {
+	value string
+}
+
+func (o *withIndent) SetConfig(c *renderer.Config) {
+	c.Options[optIndent] = o.value
+}
+
+func (o *withIndent) SetJSONOption(c *Config) {
+	c.Indent = o.value
+}
+
+// WithIndent is a functional option that indents JSON with the given string.
+func WithIndent(indent string) interface {
+	renderer.Option
+	Option
+} {
+	return &withIndent{indent}
+}
+
+// A Node struct is a JSON representation of an ast.Node.
+type Node struct {
+	// Kind is a kind name of the node.
+	Kind string `json:"kind"`
+
+	// Type is "block", "inline" or "document".
+	Type string `json:"type"`
+
+	// Attributes is attributes of the node.
+	Attributes map[string]string `json:"attributes,omitempty"`
+
+	// Properties is kind specific properties like heading levels and
+	// link destinations.
+	Properties map[string]interface{} `json:"properties,omitempty"`
+
+	// Text is text contents of the node. Only text nodes and raw
+	// blocks(like code blocks) have text contents.
+	Text string `json:"text,omitempty"`
+
+	// Children is child nodes of the node.
+	Children []*Node `json:"children,omitempty"`
+}
+
+// NewNode returns a new Node that represents the given ast.Node and
+// its descendants.
+func NewNode(n ast.Node, source []byte) *Node {
+	ret := &Node{
+		Kind: n.Kind().String(),
+	}
+	switch n.Type() {
+	case ast.TypeBlock:
+		ret.Type = "block"
+	case ast.TypeInline:
+		ret.Type = "inline"
+	case ast.TypeDocument:
+		ret.Type = "document"
+	}
+	if attrs := n.Attributes(); len(attrs) != 0 {
+		ret.Attributes = map[string]string{}
+		for _, attr := range attrs {
+			ret.Attributes[string(attr.Name)] = string(attr.Value)
+		}
+	}
+	ret.Properties = properties(n, source)
+	if t, ok := n.(*ast.Text); ok {
+		ret.Text = string(t.Segment.Value(source))
+	} else if v, ok := n.(*ast.String); ok {
+		ret.Text = string(v.Value)
+	} else if n.Type() == ast.TypeBlock && n.IsRaw() {
+		var buf bytes.Buffer
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			buf.Write(util.NormalizeLineEndings(line.Value(source)))
+		}
+		ret.Text = buf.String()
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		ret.Children = append(ret.Children, NewNode(c, source))
+	}
+	return ret
+}
+
+func properties(n ast.Node, source []byte) map[string]interface{} {
+	m := map[string]interface{}{}
+	switch v := n.(type) {
+	case *ast.Heading:
+		m["level"] = v.Level
+	case *ast.FencedCodeBlock:
+		if v.Info != nil {
+			m["info"] = string(v.Info.Text(source))
+			m["language"] = string(v.Language(source))
+			if meta := v.Meta(source); meta != nil {
+				m["meta"] = string(meta)
+			}
+		}
+	case *ast.List:
+		m["ordered"] = v.IsOrdered()
+		m["marker"] = string(v.Marker)
+		m["tight"] = v.IsTight
+		if v.IsOrdered() {
+			m["start"] = v.Start
+		}
+	case *ast.Text:
+		if v.SoftLineBreak() {
+			m["softLineBreak"] = true
+		}
+		if v.HardLineBreak() {
+			m["hardLineBreak"] = true
+		}
+	case *ast.Emphasis:
+		m["level"] = v.Level
+	case *ast.Link:
+		m["destination"] = string(v.Destination)
+		if v.Title != nil {
+			m["title"] = string(v.Title)
+		}
+	case *ast.Image:
+		m["destination"] = string(v.Destination)
+		if v.Title != nil {
+			m["title"] = string(v.Title)
+		}
+	case *ast.AutoLink:
+		m["url"] = string(v.URL(source))
+		if v.AutoLinkType == ast.AutoLinkEmail {
+			m["email"] = true
+		}
+	case *ast.RawHTML:
+		var buf bytes.Buffer
+		for i := 0; i < v.Segments.Len(); i++ {
+			segment := v.Segments.At(i)
+			buf.Write(util.NormalizeLineEndings(segment.Value(source)))
+		}
+		m["html"] = buf.String()
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// A Renderer struct is an implementation of renderer.NodeRenderer that
+// serializes the whole document as JSON.
+type Renderer struct {
+	Config
+}
+
+// NewRenderer returns a new Renderer with given options.
+func NewRenderer(opts ...Option) renderer.NodeRenderer {
+	r := &Renderer{
+		Config: NewConfig(),
+	}
+
+	for _, opt := range opts {
+		opt.SetJSONOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements NodeRenderer.RegisterFuncs .
+func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindDocument, r.renderDocument)
+}
+
+func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	encoder := stdjson.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if len(r.Indent) != 0 {
+		encoder.SetIndent("", r.Indent)
+	}
+	if err := encoder.Encode(NewNode(node, source)); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,599 @@
+// Package markdown implements a renderer that renders an AST as normalized
+// Markdown text.
+package markdown
+
+import (
+	"bytes"
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// A Config struct has configurations for the Markdown renderer.
+type Config struct {
+	// EmphasisMarker is a character used for emphasis, '*' or '_'.
+	EmphasisMarker byte
+
+	// BulletMarker is a character used for bullet lists, '-', '*' or '+'.
+	BulletMarker byte
+}
+
+// NewConfig returns a new Config with defaults.
+func NewConfig() Config {
+	return Config{
+		EmphasisMarker: '*',
+		BulletMarker:   '-',
+	}
+}
+
+// SetOption implements renderer.NodeRenderer.SetOption.
+func (c *Config) SetOption(name renderer.OptionName, value interface{}) {
+	switch name {
+	case optEmphasisMarker:
+		c.EmphasisMarker = value.(byte)
+	case optBulletMarker:
+		c.BulletMarker = value.(byte)
+	}
+}
+
+// An Option interface sets options for the Markdown renderer.
+type Option interface {
+	SetMarkdownOption(*Config)
+}
+
+// EmphasisMarker is an option name used in WithEmphasisMarker.
+const optEmphasisMarker renderer.OptionName = "MarkdownEmphasisMarker"
+
+type withEmphasisMarker struct {
+	value byte
+}
+
+func (o *withEmphasisMarker) SetConfig(c *renderer.Config) {
+	c.Options[optEmphasisMarker] = o.value
+}
+
+func (o *withEmphasisMarker) SetMarkdownOption(c *Config) {
+	c.EmphasisMarker = o.value
+}
+
+// WithEmphasisMarker is a functional option that sets a character used for
+// emphasis. marker must be '*' or '_'.
+func WithEmphasisMarker(marker byte) interface {
+	renderer.Option
+	Option
+} {
+	return &withEmphasisMarker{marker}
+}
+
+// BulletMarker is an option name used in WithBulletMarker.
+const optBulletMarker renderer.OptionName = "MarkdownBulletMarker"
+
+type withBulletMarker struct {
+	value byte
+}
+
+func (o *withBulletMarker) SetConfig(c *renderer.Config) {
+	c.Options[optBulletMarker] = o.value
+}
+
+func (o *withBulletMarker) SetMarkdownOption(c *Config) {
+	c.BulletMarker = o.value
+}
+
+// WithBulletMarker is a functional option that sets a character used for
+// bullet lists. marker must be '-', '*' or '+'.
+func WithBulletMarker(marker byte) interface {
+	renderer.Option
+	Option
+} {
+	return &withBulletMarker{marker}
+}
+
+// A Renderer struct is an implementation of renderer.NodeRenderer that
+// renders nodes as Markdown.
+//
+// Block structures are reconstructed from the AST, so link reference
+// definitions are resolved into inline links, indented code blocks are
+// rendered as fenced code blocks and single line setext headings are
+// rendered as ATX headings. Inline texts are written as they are in the source, so
+// backslash escapes and entity references are preserved.
+type Renderer struct {
+	Config
+}
+
+// NewRenderer returns a new Renderer with given options.
+func NewRenderer(opts ...Option) renderer.NodeRenderer {
+	r := &Renderer{
+		Config: NewConfig(),
+	}
+
+	for _, opt := range opts {
+		opt.SetMarkdownOption(&r.Config)
+	}
+	return r
+}
+
+// RegisterFuncs implements NodeRenderer.RegisterFuncs .
+func (r *Renderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindDocument, r.renderDocument)
+}
+
+func (r *Renderer) renderDocument(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	b := r.renderBlocks(source, node, false)
+	if len(b) != 0 {
+		w.Write(b)
+		w.WriteByte('\n')
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// renderBlocks renders child blocks of the given node. Blocks are separated
+// by a blank line unless tight is true.
+func (r *Renderer) renderBlocks(source []byte, node ast.Node, tight bool) []byte {
+	var buf bytes.Buffer
+	var prevList *ast.List
+	var prevMarker byte
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		var b []byte
+		if list, ok := c.(*ast.List); ok {
+			// Adjacent lists must use different markers, otherwise they
+			// will be merged into a single list.
+			marker := r.listMarker(list)
+			if prevList != nil && prevList == c.PreviousSibling() &&
+				prevList.IsOrdered() == list.IsOrdered() && prevMarker == marker {
+				marker = alternateListMarker(marker)
+			}
+			b = r.renderList(source, list, marker)
+			prevList = list
+			prevMarker = marker
+		} else {
+			b = r.renderBlock(source, c)
+		}
+		if buf.Len() != 0 {
+			if tight {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteString("\n\n")
+			}
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes()
+}
+
+func (r *Renderer) renderBlock(source []byte, node ast.Node) []byte {
+	switch n := node.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return r.renderInlines(source, n, true)
+	case *ast.Heading:
+		return r.renderHeading(source, n)
+	case *ast.ThemanticBreak:
+		return []byte("***")
+	case *ast.CodeBlock:
+		return r.renderCodeBlock(source, nil, n)
+	case *ast.FencedCodeBlock:
+		var info []byte
+		if n.Info != nil {
+			info = n.Info.Segment.Value(source)
+		}
+		return r.renderCodeBlock(source, info, n)
+	case *ast.Blockquote:
+		return prefixLines(r.renderBlocks(source, n, false), "> ", "> ")
+	case *ast.List:
+		return r.renderList(source, n, r.listMarker(n))
+	case *ast.HTMLBlock:
+		var buf bytes.Buffer
+		writeLines(&buf, source, n)
+		if n.HasClosure() {
+			buf.Write(util.NormalizeLineEndings(n.ClosureLine.Value(source)))
+		}
+		return bytes.TrimRight(buf.Bytes(), "\n")
+	}
+	// Unknown blocks: raw blocks are written as they are, others are
+	// written as their children.
+	if node.IsRaw() || !node.HasChildren() {
+		var buf bytes.Buffer
+		writeLines(&buf, source, node)
+		return bytes.TrimRight(buf.Bytes(), "\n")
+	}
+	if node.FirstChild().Type() == ast.TypeInline {
+		return r.renderInlines(source, node, true)
+	}
+	return r.renderBlocks(source, node, false)
+}
+
+func (r *Renderer) renderHeading(source []byte, n *ast.Heading) []byte {
+	var buf bytes.Buffer
+	if n.Level < 3 {
+		// Multi-line headings can only be written as setext headings.
+		content := r.renderInlines(source, n, true)
+		if bytes.IndexByte(content, '\n') > -1 {
+			buf.Write(content)
+			if n.Level == 1 {
+				buf.WriteString("\n===")
+			} else {
+				buf.WriteString("\n---")
+			}
+			return buf.Bytes()
+		}
+	}
+	for i := 0; i < n.Level; i++ {
+		buf.WriteByte('#')
+	}
+	content := r.renderInlines(source, n, false)
+	if len(content) != 0 {
+		buf.WriteByte(' ')
+		buf.Write(content)
+		// Trailing '#'s would be treated as a closing sequence.
+		i := len(content) - 1
+		for ; i >= 0 && content[i] == '#'; i-- {
+		}
+		if i < 0 || content[i] == ' ' {
+			buf.WriteString(" #")
+		}
+	}
+	return buf.Bytes()
+}
+
+func (r *Renderer) renderCodeBlock(source []byte, info []byte, n ast.Node) []byte {
+	var content bytes.Buffer
+	writeLines(&content, source, n)
+	if content.Len() != 0 && content.Bytes()[content.Len()-1] != '\n' {
+		content.WriteByte('\n')
+	}
+	fenceChar := byte('`')
+	if bytes.IndexByte(info, '`') > -1 {
+		fenceChar = '~'
+	}
+	fenceLength := 3
+	lines := bytes.Split(content.Bytes(), []byte{'\n'})
+	for _, line := range lines {
+		line = util.TrimLeftSpace(line)
+		i := 0
+		for ; i < len(line) && line[i] == fenceChar; i++ {
+		}
+		if i >= fenceLength {
+			fenceLength = i + 1
+		}
+	}
+	fence := bytes.Repeat([]byte{fenceChar}, fenceLength)
+	var buf bytes.Buffer
+	buf.Write(fence)
+	buf.Write(info)
+	buf.WriteByte('\n')
+	buf.Write(content.Bytes())
+	buf.Write(fence)
+	return buf.Bytes()
+}
+
+func (r *Renderer) listMarker(n *ast.List) byte {
+	if n.IsOrdered() {
+		return n.Marker
+	}
+	return r.BulletMarker
+}
+
+func alternateListMarker(marker byte) byte {
+	switch marker {
+	case '.':
+		return ')'
+	case ')':
+		return '.'
+	case '-':
+		return '*'
+	}
+	return '-'
+}
+
+func (r *Renderer) renderList(source []byte, n *ast.List, marker byte) []byte {
+	var buf bytes.Buffer
+	number := n.Start
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if buf.Len() != 0 {
+			if n.IsTight {
+				buf.WriteByte('\n')
+			} else {
+				buf.WriteString("\n\n")
+			}
+		}
+		var prefix []byte
+		if n.IsOrdered() {
+			prefix = strconv.AppendInt(prefix, int64(number), 10)
+			number++
+		}
+		prefix = append(prefix, marker)
+		content := r.renderBlocks(source, c, n.IsTight)
+		if len(content) == 0 {
+			buf.Write(prefix)
+			continue
+		}
+		indent := string(bytes.Repeat([]byte{' '}, len(prefix)+1))
+		buf.Write(prefixLines(content, string(prefix)+" ", indent))
+	}
+	return buf.Bytes()
+}
+
+// renderInlines renders inline children of the given node.
+// If multiline is false, line breaks are rendered as spaces.
+func (r *Renderer) renderInlines(source []byte, n ast.Node, multiline bool) []byte {
+	w := &inlineWriter{
+		multiline: multiline,
+		lineStart: multiline,
+	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		r.renderInline(w, source, c)
+	}
+	return w.buf.Bytes()
+}
+
+type inlineWriter struct {
+	buf       bytes.Buffer
+	multiline bool
+	lineStart bool
+}
+
+func (w *inlineWriter) Write(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	w.buf.Write(b)
+	w.lineStart = false
+}
+
+func (w *inlineWriter) WriteString(s string) {
+	w.Write([]byte(s))
+}
+
+func (w *inlineWriter) LineBreak(hard bool) {
+	if !w.multiline {
+		w.buf.WriteByte(' ')
+		return
+	}
+	if hard {
+		w.buf.WriteByte('\\')
+	}
+	w.buf.WriteByte('\n')
+	w.lineStart = true
+}
+
+// WriteText writes the given text with escaping characters that would be
+// interpreted as markdown syntax. Texts are raw source texts, so characters
+// that have been escaped in the source are written as they are.
+func (w *inlineWriter) WriteText(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if w.lineStart {
+		i := 0
+		for ; i < len(b) && b[i] >= '0' && b[i] <= '9'; i++ {
+		}
+		if i > 0 && i < len(b) && (b[i] == '.' || b[i] == ')') {
+			w.buf.Write(b[:i])
+			w.buf.WriteByte('\\')
+			w.buf.WriteByte(b[i])
+			b = b[i+1:]
+		} else if i == 0 {
+			switch b[0] {
+			case '#', '>', '-', '+', '=', '~', '<', '|':
+				w.buf.WriteByte('\\')
+			}
+		}
+	}
+	// Literal '*', '_' and '`' might be paired with delimiters written by
+	// this renderer.
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		if c == '\\' && i < len(b)-1 {
+			w.buf.Write(b[i : i+2])
+			i++
+			continue
+		}
+		if c == '*' || c == '`' ||
+			(c == '_' && (i == 0 || i == len(b)-1 || !isAlphaNumeric(b[i-1]) || !isAlphaNumeric(b[i+1]))) {
+			w.buf.WriteByte('\\')
+		}
+		w.buf.WriteByte(c)
+	}
+	w.lineStart = false
+}
+
+func (r *Renderer) renderInline(w *inlineWriter, source []byte, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Text:
+		w.WriteText(n.Segment.Value(source))
+		if n.HardLineBreak() {
+			w.LineBreak(true)
+		} else if n.SoftLineBreak() {
+			w.LineBreak(false)
+		}
+	case *ast.String:
+		w.WriteText(n.Value)
+	case *ast.CodeSpan:
+		r.renderCodeSpan(w, source, n)
+	case *ast.Emphasis:
+		marker := r.emphasisMarker(source, n)
+		delim := bytes.Repeat([]byte{marker}, n.Level)
+		w.Write(delim)
+		r.renderInlineChildren(w, source, n)
+		w.Write(delim)
+	case *ast.Link:
+		w.WriteString("[")
+		r.renderInlineChildren(w, source, n)
+		w.WriteString("](")
+		writeLinkDestination(w, n.Destination, n.Title)
+		w.WriteString(")")
+	case *ast.Image:
+		w.WriteString("![")
+		r.renderInlineChildren(w, source, n)
+		w.WriteString("](")
+		writeLinkDestination(w, n.Destination, n.Title)
+		w.WriteString(")")
+	case *ast.AutoLink:
+		w.WriteString("<")
+		if n.AutoLinkType == ast.AutoLinkEmail {
+			w.Write(n.Label(source))
+		} else {
+			w.Write(n.URL(source))
+		}
+		w.WriteString(">")
+	case *ast.RawHTML:
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			w.Write(util.NormalizeLineEndings(segment.Value(source)))
+		}
+	default:
+		r.renderInlineChildren(w, source, node)
+	}
+}
+
+// emphasisMarker returns a marker for the given emphasis.
+func (r *Renderer) emphasisMarker(source []byte, n *ast.Emphasis) byte {
+	marker := r.EmphasisMarker
+	if parent, ok := n.Parent().(*ast.Emphasis); ok && n.Level == 1 &&
+		(parent.FirstChild() == n || parent.LastChild() == n) &&
+		r.emphasisMarker(source, parent) == marker {
+		// Nested emphasis like '*_foo_*' must use a different marker,
+		// otherwise it would be a strong emphasis or a wrong nesting.
+		marker = alternateEmphasisMarker(marker)
+	}
+	if marker == '_' && isIntraword(source, n) {
+		marker = '*'
+	}
+	return marker
+}
+
+func alternateEmphasisMarker(marker byte) byte {
+	if marker == '*' {
+		return '_'
+	}
+	return '*'
+}
+
+func (r *Renderer) renderInlineChildren(w *inlineWriter, source []byte, n ast.Node) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		r.renderInline(w, source, c)
+	}
+}
+
+func (r *Renderer) renderCodeSpan(w *inlineWriter, source []byte, n *ast.CodeSpan) {
+	var content bytes.Buffer
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			content.Write(util.NormalizeLineEndings(t.Segment.Value(source)))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				content.WriteByte(' ')
+			}
+		}
+	}
+	value := content.Bytes()
+	longest, run := 0, 0
+	for _, c := range value {
+		if c == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := bytes.Repeat([]byte{'`'}, longest+1)
+	w.Write(fence)
+	if len(value) != 0 && (value[0] == '`' || value[len(value)-1] == '`' ||
+		(value[0] == ' ' && value[len(value)-1] == ' ' && !util.IsBlank(value))) {
+		w.WriteString(" ")
+		w.Write(value)
+		w.WriteString(" ")
+	} else {
+		w.Write(value)
+	}
+	w.Write(fence)
+}
+
+func writeLinkDestination(w *inlineWriter, destination, title []byte) {
+	if len(destination) == 0 || bytes.ContainsAny(destination, " \t()<>") {
+		w.WriteString("<")
+		w.Write(destination)
+		w.WriteString(">")
+	} else {
+		w.Write(destination)
+	}
+	if title == nil {
+		return
+	}
+	w.WriteString(` "`)
+	for i := 0; i < len(title); i++ {
+		c := title[i]
+		if c == '\\' && i < len(title)-1 {
+			w.Write(title[i : i+2])
+			i++
+			continue
+		}
+		if c == '"' {
+			w.WriteString(`\"`)
+			continue
+		}
+		w.Write(title[i : i+1])
+	}
+	w.WriteString(`"`)
+}
+
+// isIntraword returns true if the given emphasis is adjacent to
+// alphanumeric characters. '_' can not be used for such emphasis.
+func isIntraword(source []byte, n ast.Node) bool {
+	if prev, ok := n.PreviousSibling().(*ast.Text); ok {
+		value := prev.Segment.Value(source)
+		if len(value) != 0 && !prev.SoftLineBreak() && !prev.HardLineBreak() &&
+			isAlphaNumeric(value[len(value)-1]) {
+			return true
+		}
+	}
+	if next, ok := n.NextSibling().(*ast.Text); ok {
+		value := next.Segment.Value(source)
+		if len(value) != 0 && isAlphaNumeric(value[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlphaNumeric(c byte) bool {
+	return c >= 0x80 || util.IsAlphaNumeric(c)
+}
+
+func writeLines(buf *bytes.Buffer, source []byte, n ast.Node) {
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		buf.Write(util.NormalizeLineEndings(line.Value(source)))
+	}
+}
+
+// prefixLines prefixes the first line of the given text with first and
+// following lines with rest. Empty lines are prefixed with right trimmed
+// prefixes.
+func prefixLines(b []byte, first, rest string) []byte {
+	var buf bytes.Buffer
+	lines := bytes.Split(b, []byte{'\n'})
+	for i, line := range lines {
+		if i != 0 {
+			buf.WriteByte('\n')
+		}
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if len(line) == 0 {
+			buf.Write(util.TrimRightSpace([]byte(prefix)))
+		} else {
+			buf.WriteString(prefix)
+		}
+		buf.Write(line)
+	}
+	return buf.Bytes()
+}
@@ -3,6 +3,7 @@ package renderer
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 
 	"github.com/yuin/goldmark/ast"
@@ -25,6 +26,47 @@ func NewConfig() *Config {
 	}
 }
 
+// ContextKey is a key that is used to set arbitrary values to a Render call.
+type ContextKey int
+
+// ContextKeyMax is a maximum value of the ContextKey.
+var ContextKeyMax ContextKey
+
+// NewContextKey return a new ContextKey value.
+func NewContextKey() ContextKey {
+	ContextKeyMax++
+	return ContextKeyMax
+}
+
+// A contextWriter is a util.BufWriter that holds values of a Render call.
+type contextWriter struct {
+	util.BufWriter
+	values map[ContextKey]interface{}
+}
+
+// ContextValue returns a value associated with the given key in the Render
+// call that passes the given writer to NodeRendererFuncs. ContextValue
+// returns nil if the writer is not passed by Render.
+func ContextValue(w util.BufWriter, key ContextKey) interface{} {
+	if cw, ok := w.(*contextWriter); ok {
+		return cw.values[key]
+	}
+	return nil
+}
+
+// SetContextValue sets the given value to the Render call that passes the
+// given writer to NodeRendererFuncs. Values are available until the Render
+// call returns. SetContextValue does nothing if the writer is not passed
+// by Render.
+func SetContextValue(w util.BufWriter, key ContextKey, value interface{}) {
+	if cw, ok := w.(*contextWriter); ok {
+		if cw.values == nil {
+			cw.values = map[ContextKey]interface{}{}
+		}
+		cw.values[key] = value
+	}
+}
+
 // An OptionName is a name of the option.
 type OptionName string
 
@@ -62,6 +104,69 @@ func WithOption(name OptionName, value interface{}) Option {
 	return &withOption{name, value}
 }
 
+const optFlushEachBlock OptionName = "FlushEachBlock"
+
+type withFlushEachBlock struct {
+}
+
+func (o *withFlushEachBlock) SetConfig(c *Config) {
+	c.Options[optFlushEachBlock] = true
+}
+
+// WithFlushEachBlock is a functional option that flushes the writer after
+// each top-level block is rendered. Rendered contents of each top-level
+// block are written to the underlying writer before the next block is
+// rendered, which is useful for streaming outputs.
+// This option does not bound memory usage for large inputs. Rendering
+// allocates a constant amount of memory regardless of the document size, but
+// the whole source and the AST of the whole document are kept in memory
+// because the document is parsed before rendering.
+func WithFlushEachBlock() Option {
+	return &withFlushEachBlock{}
+}
+
+const optUnknownNodeRenderer OptionName = "UnknownNodeRenderer"
+
+type withUnknownNodeRenderer struct {
+	value NodeRendererFunc
+}
+
+func (o *withUnknownNodeRenderer) SetConfig(c *Config) {
+	c.Options[optUnknownNodeRenderer] = o.value
+}
+
+// WithUnknownNodeRenderer is a functional option that specifies a
+// NodeRendererFunc called for nodes that have no NodeRendererFuncs.
+// By default, unknown nodes are not rendered but their children are.
+// SkipUnknownNodes and FailOnUnknownNodes can be used as the function.
+func WithUnknownNodeRenderer(f NodeRendererFunc) Option {
+	return &withUnknownNodeRenderer{f}
+}
+
+// SkipUnknownNodes is a NodeRendererFunc that skips unknown nodes and their
+// children.
+func SkipUnknownNodes(writer util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkSkipChildren, nil
+}
+
+// FailOnUnknownNodes is a NodeRendererFunc that stops rendering with an
+// UnknownNodeError.
+func FailOnUnknownNodes(writer util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkStop, &UnknownNodeError{Kind: n.Kind()}
+}
+
+// An UnknownNodeError is an error returned when a node that has no
+// NodeRendererFuncs is rendered with FailOnUnknownNodes.
+type UnknownNodeError struct {
+	// Kind is a kind of the node.
+	Kind ast.NodeKind
+}
+
+// Error implements error.Error.
+func (e *UnknownNodeError) Error() string {
+	return fmt.Sprintf("renderer: no NodeRendererFunc for %s", e.Kind)
+}
+
 // A SetOptioner interface sets given option to the object.
 type SetOptioner interface {
 	// SetOption sets given option to the object.
@@ -88,9 +193,15 @@ type NodeRendererFuncRegisterer interface {
 // A Renderer interface renders given AST node to given
 // writer with given Renderer.
 type Renderer interface {
+	// Render renders the given AST node to the given writer.
+	// Render can be called concurrently, even for the same AST, as long as
+	// node renderers do not modify nodes and their own states.
 	Render(w io.Writer, source []byte, n ast.Node) error
 
 	// AddOptions adds given option to thie parser.
+	// Options can be added after rendering documents. The renderer is
+	// reconfigured on the next Render call.
+	// AddOptions must not be called concurrently with Render.
 	AddOptions(...Option)
 }
 
@@ -100,6 +211,8 @@ type renderer struct {
 	nodeRendererFuncsTmp map[ast.NodeKind]NodeRendererFunc
 	maxKind              int
 	nodeRendererFuncs    []NodeRendererFunc
+	flushEachBlock       bool
+	unknownNodeRenderer  NodeRendererFunc
 	initSync             sync.Once
 }
 
@@ -123,6 +236,7 @@ func (r *renderer) AddOptions(opts ...Option) {
 	for _, opt := range opts {
 		opt.SetConfig(r.config)
 	}
+	r.initSync = sync.Once{}
 }
 
 func (r *renderer) Register(kind ast.NodeKind, v NodeRendererFunc) {
@@ -135,7 +249,14 @@ func (r *renderer) Register(kind ast.NodeKind, v NodeRendererFunc) {
 // Render renders the given AST node to the given writer with the given Renderer.
 func (r *renderer) Render(w io.Writer, source []byte, n ast.Node) error {
 	r.initSync.Do(func() {
+		r.nodeRendererFuncsTmp = map[ast.NodeKind]NodeRendererFunc{}
+		r.maxKind = 0
 		r.options = r.config.Options
+		_, r.flushEachBlock = r.options[optFlushEachBlock]
+		r.unknownNodeRenderer = nil
+		if v, ok := r.options[optUnknownNodeRenderer]; ok {
+			r.unknownNodeRenderer = v.(NodeRendererFunc)
+		}
 		r.config.NodeRenderers.Sort()
 		l := len(r.config.NodeRenderers)
 		for i := l - 1; i >= 0; i-- {
@@ -152,20 +273,32 @@ func (r *renderer) Render(w io.Writer, source []byte, n ast.Node) error {
 		for kind, nr := range r.nodeRendererFuncsTmp {
 			r.nodeRendererFuncs[kind] = nr
 		}
-		r.config = nil
 		r.nodeRendererFuncsTmp = nil
 	})
-	writer, ok := w.(util.BufWriter)
+	bw, ok := w.(util.BufWriter)
 	if !ok {
-		writer = bufio.NewWriter(w)
+		bw = bufio.NewWriter(w)
 	}
+	// values of this call are released when this call returns, even if
+	// rendering fails.
+	writer := &contextWriter{BufWriter: bw}
 	err := ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		s := ast.WalkStatus(ast.WalkContinue)
 		var err error
-		f := r.nodeRendererFuncs[n.Kind()]
+		var f NodeRendererFunc
+		if kind := int(n.Kind()); kind < len(r.nodeRendererFuncs) {
+			f = r.nodeRendererFuncs[kind]
+		}
+		if f == nil {
+			f = r.unknownNodeRenderer
+		}
 		if f != nil {
 			s, err = f(writer, source, n, entering)
 		}
+		if err == nil && !entering && r.flushEachBlock && n.Type() == ast.TypeBlock &&
+			n.Parent() != nil && n.Parent().Type() == ast.TypeDocument {
+			err = writer.Flush()
+		}
 		return s, err
 	})
 	if err != nil {
@@ -0,0 +1,70 @@
+package text
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// A Position struct represents a human readable position in a source text.
+type Position struct {
+	// Line is a 1-based line number.
+	Line int
+
+	// Column is a 1-based column number counted in characters(runes).
+	Column int
+}
+
+// String implements Stringer.
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// A LineTable struct converts byte offsets in a source text into
+// Positions. LineTable holds start offsets of all lines, so that
+// converting many offsets in the same source is fast.
+type LineTable struct {
+	source []byte
+	starts []int
+}
+
+// NewLineTable returns a new LineTable for the given source.
+func NewLineTable(source []byte) *LineTable {
+	// a leading BOM is not counted as a column.
+	starts := []int{len(source) - len(util.TrimBOM(source))}
+	for i, c := range source {
+		if c == '\n' || c == '\r' && (i+1 == len(source) || source[i+1] != '\n') {
+			starts = append(starts, i+1)
+		}
+	}
+	return &LineTable{
+		source: source,
+		starts: starts,
+	}
+}
+
+// Position returns a Position of the given byte offset.
+// Offsets out of the source are clamped to the source.
+func (t *LineTable) Position(offset int) Position {
+	if offset < t.starts[0] {
+		offset = t.starts[0]
+	}
+	if offset > len(t.source) {
+		offset = len(t.source)
+	}
+	line := sort.Search(len(t.starts), func(i int) bool {
+		return t.starts[i] > offset
+	}) - 1
+	return Position{
+		Line:   line + 1,
+		Column: utf8.RuneCount(t.source[t.starts[line]:offset]) + 1,
+	}
+}
+
+// PositionOf returns a Position of the given byte offset in the source.
+// Use LineTable if you convert many offsets in the same source.
+func PositionOf(source []byte, offset int) Position {
+	return NewLineTable(source).Position(offset)
+}
@@ -37,6 +37,13 @@ type Reader interface {
 	// LineOffset returns a distance from the line head to current position.
 	LineOffset() int
 
+	// TabStop returns a width of tab stops. The default width is
+	// util.DefaultTabStop.
+	TabStop() util.TabStop
+
+	// SetTabStop sets a width of tab stops.
+	SetTabStop(util.TabStop)
+
 	// Position returns current line number and position.
 	Position() (int, Segment)
 
@@ -78,6 +85,7 @@ type reader struct {
 	peekedLine   []byte
 	pos          Segment
 	head         int
+	tabStop      util.TabStop
 }
 
 // NewReader return a new Reader that can read UTF-8 bytes .
@@ -85,6 +93,7 @@ func NewReader(source []byte) Reader {
 	r := &reader{
 		source:       source,
 		sourceLength: len(source),
+		tabStop:      util.DefaultTabStop,
 	}
 	r.ResetPosition()
 	return r
@@ -93,6 +102,8 @@ func NewReader(source []byte) Reader {
 func (r *reader) ResetPosition() {
 	r.line = -1
 	r.head = 0
+	// a leading BOM is not a part of the first line.
+	r.pos.Stop = len(r.source) - len(util.TrimBOM(r.source))
 	r.AdvanceLine()
 }
 
@@ -132,11 +143,19 @@ func (r *reader) ReadRune() (rune, int, error) {
 func (r *reader) LineOffset() int {
 	v := r.pos.Start - r.head
 	if r.pos.Padding > 0 {
-		v += util.TabWidth(v) - r.pos.Padding
+		v += r.tabStop.TabWidth(v) - r.pos.Padding
 	}
 	return v
 }
 
+func (r *reader) TabStop() util.TabStop {
+	return r.tabStop
+}
+
+func (r *reader) SetTabStop(v util.TabStop) {
+	r.tabStop = v
+}
+
 func (r *reader) PrecendingCharacter() rune {
 	if r.pos.Start <= 0 {
 		if r.pos.Padding != 0 {
@@ -151,6 +170,9 @@ func (r *reader) PrecendingCharacter() rune {
 		}
 	}
 	rn, _ := utf8.DecodeRune(r.source[i:])
+	if i == 0 && rn == '\uFEFF' { // a leading BOM
+		return rune('\n')
+	}
 	return rn
 }
 
@@ -167,7 +189,7 @@ func (r *reader) Advance(n int) {
 			r.pos.Padding--
 			continue
 		}
-		if r.source[r.pos.Start] == '\n' {
+		if c := r.source[r.pos.Start]; c == '\n' || c == '\r' && r.pos.Start == r.pos.Stop-1 {
 			r.AdvanceLine()
 			continue
 		}
@@ -190,9 +212,10 @@ func (r *reader) AdvanceLine() {
 		return
 	}
 	r.pos.Stop = r.sourceLength
+	// lines end with '\n', '\r\n' or '\r'.
 	for i := r.pos.Start; i < r.sourceLength; i++ {
 		c := r.source[i]
-		if c == '\n' {
+		if c == '\n' || c == '\r' && (i+1 == r.sourceLength || r.source[i+1] != '\n') {
 			r.pos.Stop = i + 1
 			break
 		}
@@ -245,12 +268,14 @@ type blockReader struct {
 	pos            Segment
 	head           int
 	last           int
+	tabStop        util.TabStop
 }
 
 // NewBlockReader returns a new BlockReader.
 func NewBlockReader(source []byte, segments *Segments) BlockReader {
 	r := &blockReader{
-		source: source,
+		source:  source,
+		tabStop: util.DefaultTabStop,
 	}
 	if segments != nil {
 		r.Reset(segments)
@@ -327,17 +352,28 @@ func (r *blockReader) PrecendingCharacter() rune {
 		}
 	}
 	rn, _ := utf8.DecodeRune(r.source[i:])
+	if i == 0 && rn == '\uFEFF' { // a leading BOM
+		return rune('\n')
+	}
 	return rn
 }
 
 func (r *blockReader) LineOffset() int {
 	v := r.pos.Start - r.head
 	if r.pos.Padding > 0 {
-		v += util.TabWidth(v) - r.pos.Padding
+		v += r.tabStop.TabWidth(v) - r.pos.Padding
 	}
 	return v
 }
 
+func (r *blockReader) TabStop() util.TabStop {
+	return r.tabStop
+}
+
+func (r *blockReader) SetTabStop(v util.TabStop) {
+	r.tabStop = v
+}
+
 func (r *blockReader) Peek() byte {
 	if r.line < r.segmentsLength && r.pos.Start >= 0 && r.pos.Start < r.last {
 		if r.pos.Padding != 0 {
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -120,22 +121,24 @@ func VisualizeSpaces(bs []byte) []byte {
 	return bs
 }
 
+// A TabStop is a width of tab stops. A tab character advances to the next
+// tab stop.
+type TabStop int
+
+// DefaultTabStop is a width of tab stops defined in CommonMark.
+const DefaultTabStop TabStop = 4
+
 // TabWidth calculates actual width of a tab at the given position.
-func TabWidth(currentPos int) int {
-	return 4 - currentPos%4
+func (t TabStop) TabWidth(currentPos int) int {
+	if t < 1 {
+		t = DefaultTabStop
+	}
+	return int(t) - currentPos%int(t)
 }
 
-// IndentPosition searches an indent position with the given width for the given line.
-// If the line contains tab characters, paddings may be not zero.
-// currentPos==0 and width==2:
-//
-//     position: 0    1
-//               [TAB]aaaa
-//     width:    1234 5678
-//
-// width=2 is in the tab character. In this case, IndentPosition returns
-// (pos=1, padding=2)
-func IndentPosition(bs []byte, currentPos, width int) (pos, padding int) {
+// IndentPosition is same as the IndentPosition function except that tabs
+// advance to the tab stops of this width.
+func (t TabStop) IndentPosition(bs []byte, currentPos, width int) (pos, padding int) {
 	w := 0
 	l := len(bs)
 	for i := 0; i < l; i++ {
@@ -143,7 +146,7 @@ func IndentPosition(bs []byte, currentPos, width int) (pos, padding int) {
 		if b == ' ' {
 			w++
 		} else if b == '\t' {
-			w += TabWidth(currentPos + w)
+			w += t.TabWidth(currentPos + w)
 		} else {
 			break
 		}
@@ -154,8 +157,9 @@ func IndentPosition(bs []byte, currentPos, width int) (pos, padding int) {
 	return -1, -1
 }
 
-// IndentWidth calculate an indent width for the given line.
-func IndentWidth(bs []byte, currentPos int) (width, pos int) {
+// IndentWidth is same as the IndentWidth function except that tabs
+// advance to the tab stops of this width.
+func (t TabStop) IndentWidth(bs []byte, currentPos int) (width, pos int) {
 	l := len(bs)
 	for i := 0; i < l; i++ {
 		b := bs[i]
@@ -163,7 +167,7 @@ func IndentWidth(bs []byte, currentPos int) (width, pos int) {
 			width++
 			pos++
 		} else if b == '\t' {
-			width += TabWidth(currentPos + width)
+			width += t.TabWidth(currentPos + width)
 			pos++
 		} else {
 			break
@@ -172,6 +176,30 @@ func IndentWidth(bs []byte, currentPos int) (width, pos int) {
 	return
 }
 
+// TabWidth calculates actual width of a tab at the given position.
+func TabWidth(currentPos int) int {
+	return DefaultTabStop.TabWidth(currentPos)
+}
+
+// IndentPosition searches an indent position with the given width for the given line.
+// If the line contains tab characters, paddings may be not zero.
+// currentPos==0 and width==2:
+//
+//     position: 0    1
+//               [TAB]aaaa
+//     width:    1234 5678
+//
+// width=2 is in the tab character. In this case, IndentPosition returns
+// (pos=1, padding=2)
+func IndentPosition(bs []byte, currentPos, width int) (pos, padding int) {
+	return DefaultTabStop.IndentPosition(bs, currentPos, width)
+}
+
+// IndentWidth calculate an indent width for the given line.
+func IndentWidth(bs []byte, currentPos int) (width, pos int) {
+	return DefaultTabStop.IndentWidth(bs, currentPos)
+}
+
 // FirstNonSpacePosition returns a potisoin line that is a first nonspace
 // character.
 func FirstNonSpacePosition(bs []byte) int {
@@ -322,6 +350,54 @@ func TrimLeftSpace(source []byte) []byte {
 	return TrimLeft(source, spaces)
 }
 
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// TrimBOM returns a subslice of the given bytes by slicing off a leading
+// UTF-8 byte order mark.
+func TrimBOM(source []byte) []byte {
+	return bytes.TrimPrefix(source, utf8BOM)
+}
+
+// NormalizeLineEndings converts '\r\n' and '\r' in the given bytes into
+// '\n'. NormalizeLineEndings returns the given bytes as it is if it has no
+// '\r'.
+func NormalizeLineEndings(source []byte) []byte {
+	i := bytes.IndexByte(source, '\r')
+	if i < 0 {
+		return source
+	}
+	result := make([]byte, 0, len(source))
+	for i > -1 {
+		result = append(result, source[:i]...)
+		result = append(result, '\n')
+		source = source[i+1:]
+		if len(source) != 0 && source[0] == '\n' {
+			source = source[1:]
+		}
+		i = bytes.IndexByte(source, '\r')
+	}
+	return append(result, source...)
+}
+
+// LineEndingLength returns a length of a line ending('\n', '\r\n' or '\r')
+// at the end of the given line, or 0 if the line does not end with it.
+func LineEndingLength(line []byte) int {
+	l := len(line)
+	if l == 0 {
+		return 0
+	}
+	switch line[l-1] {
+	case '\n':
+		if l > 1 && line[l-2] == '\r' {
+			return 2
+		}
+		return 1
+	case '\r':
+		return 1
+	}
+	return 0
+}
+
 // TrimRightSpace returns a subslice of the given string by slicing off all trailing
 // space characters.
 func TrimRightSpace(source []byte) []byte {
@@ -389,6 +465,30 @@ func ToLinkReference(v []byte) string {
 	return strings.ToLower(string(ReplaceSpaces(v, ' ')))
 }
 
+// ToElementID converts given bytes into a GitHub compatible element id:
+// letters are lowercased, spaces are replaced with '-' and punctuations
+// other than '-' and '_' are removed. ToElementID returns an empty slice if
+// no characters remain.
+func ToElementID(value []byte) []byte {
+	value = TrimLeftSpace(value)
+	value = TrimRightSpace(value)
+	result := []byte{}
+	for i := 0; i < len(value); {
+		r, l := utf8.DecodeRune(value[i:])
+		i += l
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_' {
+			if r < utf8.RuneSelf {
+				result = append(result, byte(unicode.ToLower(r)))
+			} else {
+				result = append(result, string(unicode.ToLower(r))...)
+			}
+		} else if unicode.IsSpace(r) {
+			result = append(result, '-')
+		}
+	}
+	return result
+}
+
 var htmlEscapeTable = [256][]byte{nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []byte("&quot;"), nil, nil, nil, []byte("&amp;"), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, []byte("&lt;"), nil, []byte("&gt;"), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}
 
 // EscapeHTMLByte returns HTML escaped bytes if the given byte should be escaped,
@@ -397,22 +497,34 @@ func EscapeHTMLByte(b byte) []byte {
 	return htmlEscapeTable[b]
 }
 
+// htmlEscapeChars is a set of characters that have non-nil values in
+// htmlEscapeTable.
+const htmlEscapeChars = "\"&<>"
+
+// IndexHTMLEscape returns an index of the first byte that should be escaped
+// in HTML text, or -1 if the given bytes have no such bytes.
+func IndexHTMLEscape(v []byte) int {
+	return bytes.IndexAny(v, htmlEscapeChars)
+}
+
 // EscapeHTML escapes characters that should be escaped in HTML text.
+// EscapeHTML returns the given bytes as they are if these have nothing to
+// be escaped.
 func EscapeHTML(v []byte) []byte {
+	i := IndexHTMLEscape(v)
+	if i < 0 {
+		return v
+	}
 	cob := NewCopyOnWriteBuffer(v)
 	n := 0
-	for i := 0; i < len(v); i++ {
-		c := v[i]
-		escaped := htmlEscapeTable[c]
-		if escaped != nil {
-			cob.Write(v[n:i])
-			cob.Write(escaped)
-			n = i + 1
-		}
-	}
-	if cob.IsCopied() {
-		cob.Write(v[n:])
+	for i > -1 {
+		i += n
+		cob.Write(v[n:i])
+		cob.Write(htmlEscapeTable[v[i]])
+		n = i + 1
+		i = IndexHTMLEscape(v[n:])
 	}
+	cob.Write(v[n:])
 	return cob.Bytes()
 }
 
@@ -522,13 +634,20 @@ func ResolveEntityNames(source []byte) []byte {
 
 var htmlSpace = []byte("%20")
 
+const upperHexDigits = "0123456789ABCDEF"
+
 // URLEscape escape the given URL.
 // If resolveReference is set true:
 //   1. unescape punctuations
 //   2. resolve numeric references
 //   3. resolve entity references
 //
-// URL encoded values (%xx) are keeped as is.
+// Valid URL encoded values (%xx) are keeped as is, so URLs are never encoded
+// twice. Other characters like spaces, non-ASCII characters and '%' that
+// is not followed by two hex digits are URL encoded. Bytes that are not
+// valid UTF-8 are encoded byte by byte.
+// Internationalized hosts like 'https://例え.jp/' are kept as they are,
+// because browsers convert them into punycode.
 func URLEscape(v []byte, resolveReference bool) []byte {
 	if resolveReference {
 		v = UnescapePunctuations(v)
@@ -538,6 +657,7 @@ func URLEscape(v []byte, resolveReference bool) []byte {
 	cob := NewCopyOnWriteBuffer(v)
 	limit := len(v)
 	n := 0
+	hostStart, hostStop := urlHostIndex(v)
 
 	for i := 0; i < limit; {
 		c := v[i]
@@ -545,13 +665,15 @@ func URLEscape(v []byte, resolveReference bool) []byte {
 			i++
 			continue
 		}
-		if c == '%' && i+2 < limit && IsHexDecimal(v[i+1]) && IsHexDecimal(v[i+1]) {
-			i += 3
-			continue
+		if c >= 0x80 && i >= hostStart && i < hostStop {
+			// internationalized domain names are kept as they are.
+			if r, size := utf8.DecodeRune(v[i:]); r != utf8.RuneError || size > 1 {
+				i += size
+				continue
+			}
 		}
-		u8len := utf8lenTable[c]
-		if u8len == 99 { // invalid utf8 leading byte, skip it
-			i++
+		if c == '%' && i+2 < limit && IsHexDecimal(v[i+1]) && IsHexDecimal(v[i+2]) {
+			i += 3
 			continue
 		}
 		if c == ' ' {
@@ -562,8 +684,15 @@ func URLEscape(v []byte, resolveReference bool) []byte {
 			continue
 		}
 		cob.Write(v[n:i])
-		cob.Write(StringToReadOnlyBytes(url.QueryEscape(string(v[i : i+int(u8len)]))))
-		i += int(u8len)
+		r, size := utf8.DecodeRune(v[i:])
+		if r == utf8.RuneError && size < 2 {
+			// invalid utf8 bytes are encoded byte by byte.
+			cob.Write([]byte{'%', upperHexDigits[c>>4], upperHexDigits[c&0xf]})
+			i++
+		} else {
+			cob.Write(StringToReadOnlyBytes(url.QueryEscape(string(v[i : i+size]))))
+			i += size
+		}
 		n = i
 	}
 	if cob.IsCopied() {
@@ -572,6 +701,23 @@ func URLEscape(v []byte, resolveReference bool) []byte {
 	return cob.Bytes()
 }
 
+// urlHostIndex returns a range of the host of the given URL like
+// 'example.com' in 'https://example.com/path'. urlHostIndex returns
+// (-1, -1) if the URL does not have a host.
+func urlHostIndex(v []byte) (int, int) {
+	i := 0
+	for ; i < len(v) && (IsAlphaNumeric(v[i]) || (i > 0 && (v[i] == '+' || v[i] == '-' || v[i] == '.'))); i++ {
+	}
+	if i == 0 || !bytes.HasPrefix(v[i:], []byte("://")) {
+		return -1, -1
+	}
+	start := i + 3
+	stop := start
+	for ; stop < len(v) && v[stop] != '/' && v[stop] != '?' && v[stop] != '#'; stop++ {
+	}
+	return start, stop
+}
+
 // FindAttributeIndiciesReverse searches attribute indicies from tail of the given
 // bytes and returns indicies.
 func FindAttributeIndiciesReverse(b []byte, canEscapeQuotes bool) [][4]int {
@@ -606,7 +752,7 @@ retry:
 		result = append(result, [4]int{as + ai[0], as + ai[1], as + ai[2], as + ai[3]})
 		as += ai[3] + skip
 	}
-	if b[as] == '}' && (as > len(b)-2 || IsBlank(b[as:])) {
+	if as < len(b) && b[as] == '}' && (as > len(b)-2 || IsBlank(b[as:])) {
 		return result
 	}
 	goto retry